@@ -0,0 +1,139 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+
+	pb "blog-auth/genproto/userservice"
+	logger "blog-auth/internal/logger"
+	"blog-auth/internal/service"
+
+	"go.uber.org/zap"
+)
+
+type deviceView struct {
+	UserCode string
+	Client   *pb.OAuthClient
+	Scopes   []string
+	Done     string
+	signInForm
+}
+
+// deviceAuthorization serves the device authorization endpoint, where a
+// device gets the codes it shows its user and then polls with.
+func deviceAuthorization(oauth *service.OAuthService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logs, err := logger.NewLogger()
+		if err != nil {
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Cache-Control", "no-store")
+		if err := r.ParseForm(); err != nil {
+			writeOAuthError(w, &service.OAuthError{Code: "invalid_request", Description: "malformed request"}, false)
+			return
+		}
+		req := service.DeviceAuthorizationRequest{
+			ClientID:     r.PostForm.Get("client_id"),
+			ClientSecret: r.PostForm.Get("client_secret"),
+			Scope:        r.PostForm.Get("scope"),
+		}
+		basic := clientAuth(r, &req.ClientID, &req.ClientSecret)
+		resp, err := oauth.DeviceAuthorization(incomingContext(r), req)
+		if err != nil {
+			var oerr *service.OAuthError
+			if !errors.As(err, &oerr) {
+				logs.Error("Error while starting device authorization", zap.Error(err))
+				oerr = &service.OAuthError{Code: "server_error", Description: "device authorization failed"}
+			}
+			writeOAuthError(w, oerr, basic)
+			return
+		}
+		writeJSON(w, http.StatusOK, resp)
+	}
+}
+
+// device serves the verification page. The user enters the code the device
+// shows, signs in unless their session is still open, and approves or denies
+// the device.
+func device(users *service.UserServiceImpl, oauth *service.OAuthService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logs, err := logger.NewLogger()
+		if err != nil {
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+		if err := r.ParseForm(); err != nil {
+			renderDevice(w, http.StatusBadRequest, deviceView{})
+			return
+		}
+		ctx := incomingContext(r)
+		view := deviceView{UserCode: r.Form.Get("user_code")}
+		if view.UserCode == "" {
+			renderDevice(w, http.StatusOK, view)
+			return
+		}
+		view.Client, view.Scopes, err = oauth.DeviceRequest(ctx, view.UserCode)
+		var oerr *service.OAuthError
+		if errors.As(err, &oerr) {
+			view.Error = oerr.Description
+			renderDevice(w, http.StatusBadRequest, view)
+			return
+		}
+		if err != nil {
+			logs.Error("Error while getting device request", zap.Error(err))
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+		view.OtherSignIn = otherSignIn(oauth, "/oauth/device?"+url.Values{"user_code": {view.UserCode}}.Encode())
+		if r.Method == http.MethodGet {
+			user, _, err := sessionUser(ctx, r, users)
+			if err != nil {
+				logs.Error("Error while getting browser session", zap.Error(err))
+				http.Error(w, "internal error", http.StatusInternalServerError)
+				return
+			}
+			if user != nil {
+				view.SignedIn = user.Email
+			}
+			renderDevice(w, http.StatusOK, view)
+			return
+		}
+
+		// Denying needs a signed in user too, or anyone who saw the code
+		// could cancel the device's login.
+		user, _, err := authenticate(ctx, w, r, users, &view.signInForm, true)
+		if err != nil {
+			logs.Error("Error while authenticating device user", zap.Error(err))
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+		if user == nil {
+			renderDevice(w, view.status(), view)
+			return
+		}
+		approved := r.PostForm.Get("action") != "deny"
+		err = oauth.ResolveDevice(ctx, user, view.UserCode, approved)
+		if errors.As(err, &oerr) {
+			view = deviceView{UserCode: view.UserCode}
+			view.Error = oerr.Description
+			renderDevice(w, http.StatusBadRequest, view)
+			return
+		}
+		if err != nil {
+			logs.Error("Error while resolving device authorization", zap.Error(err))
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+		view.Done = "Device connected"
+		if !approved {
+			view.Done = "Device denied"
+		}
+		renderDevice(w, http.StatusOK, view)
+	}
+}
+
+func renderDevice(w http.ResponseWriter, code int, view deviceView) {
+	render(w, code, devicePage, view)
+}
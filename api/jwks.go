@@ -0,0 +1,16 @@
+package api
+
+import (
+	"net/http"
+
+	"blog-auth/internal/token"
+)
+
+// jwks serves the public signing keys. Verifiers may cache the document for a
+// few minutes; new keys are published long before they sign anything.
+func jwks(tokens *token.Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "public, max-age=300")
+		writeJSON(w, http.StatusOK, tokens.JWKS())
+	}
+}
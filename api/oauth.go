@@ -0,0 +1,282 @@
+package api
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"html/template"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	pb "blog-auth/genproto/userservice"
+	logger "blog-auth/internal/logger"
+	"blog-auth/internal/service"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/metadata"
+)
+
+//go:embed templates/*.html
+var templates embed.FS
+
+var (
+	authorizePage = template.Must(template.ParseFS(templates, "templates/authorize.html", "templates/signin.html"))
+	devicePage    = template.Must(template.ParseFS(templates, "templates/device.html", "templates/signin.html"))
+	errorPage     = template.Must(template.ParseFS(templates, "templates/error.html"))
+	sessionPage   = template.Must(template.ParseFS(templates, "templates/session.html"))
+)
+
+type authorizeView struct {
+	Client  *pb.OAuthClient
+	Request service.AuthorizationRequest
+	Scopes  []string
+	signInForm
+}
+
+// authorize serves the authorization endpoint. GET shows the consent page,
+// with a sign-in form unless the user's session is still open; POST checks
+// the credentials, then the second factor if the user has one, and sends the
+// user back to the client with a code. prompt=login asks for the credentials
+// even with a session; consent is asked for every time, so prompt=none fails
+// either way.
+func authorize(users *service.UserServiceImpl, oauth *service.OAuthService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logs, err := logger.NewLogger()
+		if err != nil {
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+		if err := r.ParseForm(); err != nil {
+			renderError(w, http.StatusBadRequest, &service.OAuthError{Code: "invalid_request", Description: "malformed request"})
+			return
+		}
+		ctx := incomingContext(r)
+		req := service.AuthorizationRequest{
+			ClientID:            r.Form.Get("client_id"),
+			RedirectURI:         r.Form.Get("redirect_uri"),
+			ResponseType:        r.Form.Get("response_type"),
+			Scope:               r.Form.Get("scope"),
+			State:               r.Form.Get("state"),
+			CodeChallenge:       r.Form.Get("code_challenge"),
+			CodeChallengeMethod: r.Form.Get("code_challenge_method"),
+			Nonce:               r.Form.Get("nonce"),
+			Prompt:              r.Form.Get("prompt"),
+		}
+		client, err := oauth.Client(ctx, req)
+		if err != nil {
+			var oerr *service.OAuthError
+			if !errors.As(err, &oerr) {
+				logs.Error("Error while getting oauth client", zap.Error(err))
+				http.Error(w, "internal error", http.StatusInternalServerError)
+				return
+			}
+			renderError(w, http.StatusBadRequest, oerr)
+			return
+		}
+		scopes, err := oauth.CheckAuthorization(client, req)
+		if err != nil {
+			redirectError(w, r, req, err)
+			return
+		}
+		prompt := strings.Fields(req.Prompt)
+		reuse := !slices.Contains(prompt, "login")
+		if slices.Contains(prompt, "none") {
+			user, _, err := sessionUser(ctx, r, users)
+			if err != nil {
+				logs.Error("Error while getting browser session", zap.Error(err))
+				http.Error(w, "internal error", http.StatusInternalServerError)
+				return
+			}
+			if user == nil || !reuse {
+				redirectError(w, r, req, &service.OAuthError{Code: "login_required", Description: "the user must sign in"})
+				return
+			}
+			redirectError(w, r, req, &service.OAuthError{Code: "consent_required", Description: "the user must approve the request"})
+			return
+		}
+		view := authorizeView{Client: client, Request: req, Scopes: scopes}
+		view.OtherSignIn = otherSignIn(oauth, "/oauth/authorize?"+authorizeQuery(req).Encode())
+		if r.Method == http.MethodGet {
+			if reuse {
+				user, _, err := sessionUser(ctx, r, users)
+				if err != nil {
+					logs.Error("Error while getting browser session", zap.Error(err))
+					http.Error(w, "internal error", http.StatusInternalServerError)
+					return
+				}
+				if user != nil {
+					view.SignedIn = user.Email
+				}
+			}
+			renderAuthorize(w, http.StatusOK, view)
+			return
+		}
+
+		if r.PostForm.Get("action") == "deny" {
+			redirectError(w, r, req, &service.OAuthError{Code: "access_denied", Description: "the user denied the request"})
+			return
+		}
+		user, authTime, err := authenticate(ctx, w, r, users, &view.signInForm, reuse)
+		if err != nil {
+			logs.Error("Error while authenticating oauth user", zap.Error(err))
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+		if user == nil {
+			renderAuthorize(w, view.status(), view)
+			return
+		}
+
+		code, err := oauth.Authorize(ctx, user, req, scopes, authTime)
+		if err != nil {
+			logs.Error("Error while authorizing oauth client", zap.Error(err))
+			redirectError(w, r, req, &service.OAuthError{Code: "server_error", Description: "authorization failed"})
+			return
+		}
+		redirect(w, r, req, url.Values{"code": {code}})
+	}
+}
+
+// oauthToken serves the token endpoint. Clients authenticate with HTTP Basic,
+// with client_id and client_secret in the form, or with a client assertion.
+func oauthToken(oauth *service.OAuthService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logs, err := logger.NewLogger()
+		if err != nil {
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Cache-Control", "no-store")
+		w.Header().Set("Pragma", "no-cache")
+		if err := r.ParseForm(); err != nil {
+			writeOAuthError(w, &service.OAuthError{Code: "invalid_request", Description: "malformed request"}, false)
+			return
+		}
+		req := service.TokenRequest{
+			GrantType:           r.PostForm.Get("grant_type"),
+			Code:                r.PostForm.Get("code"),
+			RedirectURI:         r.PostForm.Get("redirect_uri"),
+			CodeVerifier:        r.PostForm.Get("code_verifier"),
+			RefreshToken:        r.PostForm.Get("refresh_token"),
+			DeviceCode:          r.PostForm.Get("device_code"),
+			ClientID:            r.PostForm.Get("client_id"),
+			ClientSecret:        r.PostForm.Get("client_secret"),
+			Scope:               r.PostForm.Get("scope"),
+			ClientAssertionType: r.PostForm.Get("client_assertion_type"),
+			ClientAssertion:     r.PostForm.Get("client_assertion"),
+		}
+		basic := clientAuth(r, &req.ClientID, &req.ClientSecret)
+		resp, err := oauth.Token(incomingContext(r), req)
+		if err != nil {
+			var oerr *service.OAuthError
+			if !errors.As(err, &oerr) {
+				logs.Error("Error while issuing oauth token", zap.Error(err))
+				oerr = &service.OAuthError{Code: "server_error", Description: "token request failed"}
+			}
+			writeOAuthError(w, oerr, basic)
+			return
+		}
+		writeJSON(w, http.StatusOK, resp)
+	}
+}
+
+// clientAuth overrides the client credentials of the form with those of an
+// HTTP Basic header, form encoded as RFC 6749 asks, and reports whether there
+// was one.
+func clientAuth(r *http.Request, clientID, clientSecret *string) bool {
+	id, secret, ok := r.BasicAuth()
+	if ok {
+		*clientID, _ = url.QueryUnescape(id)
+		*clientSecret, _ = url.QueryUnescape(secret)
+	}
+	return ok
+}
+
+func writeOAuthError(w http.ResponseWriter, err *service.OAuthError, basic bool) {
+	code := http.StatusBadRequest
+	switch err.Code {
+	case "invalid_client":
+		code = http.StatusUnauthorized
+		if basic {
+			w.Header().Set("WWW-Authenticate", `Basic realm="oauth"`)
+		}
+	case "server_error":
+		code = http.StatusInternalServerError
+	}
+	writeJSON(w, code, map[string]string{"error": err.Code, "error_description": err.Description})
+}
+
+// authorizeQuery returns the parameters of req, to come back to the same
+// request.
+func authorizeQuery(req service.AuthorizationRequest) url.Values {
+	query := url.Values{}
+	for key, value := range map[string]string{
+		"client_id":             req.ClientID,
+		"redirect_uri":          req.RedirectURI,
+		"response_type":         req.ResponseType,
+		"scope":                 req.Scope,
+		"state":                 req.State,
+		"code_challenge":        req.CodeChallenge,
+		"code_challenge_method": req.CodeChallengeMethod,
+		"nonce":                 req.Nonce,
+		"prompt":                req.Prompt,
+	} {
+		if value != "" {
+			query.Set(key, value)
+		}
+	}
+	return query
+}
+
+// redirectError reports err to the client at its redirect URI, which Client
+// has already checked.
+func redirectError(w http.ResponseWriter, r *http.Request, req service.AuthorizationRequest, err error) {
+	oerr := &service.OAuthError{Code: "server_error", Description: "authorization failed"}
+	errors.As(err, &oerr)
+	redirect(w, r, req, url.Values{"error": {oerr.Code}, "error_description": {oerr.Description}})
+}
+
+func redirect(w http.ResponseWriter, r *http.Request, req service.AuthorizationRequest, params url.Values) {
+	u, err := url.Parse(req.RedirectURI)
+	if err != nil {
+		renderError(w, http.StatusBadRequest, &service.OAuthError{Code: "invalid_request", Description: "invalid redirect_uri"})
+		return
+	}
+	if req.State != "" {
+		params.Set("state", req.State)
+	}
+	query := u.Query()
+	for key, values := range params {
+		query[key] = values
+	}
+	u.RawQuery = query.Encode()
+	http.Redirect(w, r, u.String(), http.StatusFound)
+}
+
+func renderAuthorize(w http.ResponseWriter, code int, view authorizeView) {
+	render(w, code, authorizePage, view)
+}
+
+func renderError(w http.ResponseWriter, code int, err *service.OAuthError) {
+	render(w, code, errorPage, err)
+}
+
+// render writes an HTML page that must not be cached or framed, since it
+// takes credentials.
+func render(w http.ResponseWriter, code int, page *template.Template, data interface{}) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("X-Frame-Options", "DENY")
+	w.Header().Set("Content-Security-Policy", "default-src 'none'; style-src 'unsafe-inline'; frame-ancestors 'none'")
+	w.WriteHeader(code)
+	page.Execute(w, data)
+}
+
+// incomingContext carries the user agent of the request the way the gateway
+// forwards it. The client address is already in the request context.
+func incomingContext(r *http.Request) context.Context {
+	md := metadata.Pairs("user-agent", r.UserAgent())
+	return metadata.NewIncomingContext(r.Context(), md)
+}
@@ -0,0 +1,102 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	logger "blog-auth/internal/logger"
+	"blog-auth/internal/service"
+	"blog-auth/internal/token"
+
+	"go.uber.org/zap"
+)
+
+// discoveryDocument is the OpenID Connect provider metadata.
+type discoveryDocument struct {
+	Issuer                            string   `json:"issuer"`
+	AuthorizationEndpoint             string   `json:"authorization_endpoint"`
+	TokenEndpoint                     string   `json:"token_endpoint"`
+	DeviceAuthorizationEndpoint       string   `json:"device_authorization_endpoint"`
+	UserinfoEndpoint                  string   `json:"userinfo_endpoint"`
+	JWKSURI                           string   `json:"jwks_uri"`
+	ScopesSupported                   []string `json:"scopes_supported"`
+	ResponseTypesSupported            []string `json:"response_types_supported"`
+	GrantTypesSupported               []string `json:"grant_types_supported"`
+	SubjectTypesSupported             []string `json:"subject_types_supported"`
+	IDTokenSigningAlgValuesSupported  []string `json:"id_token_signing_alg_values_supported"`
+	TokenEndpointAuthMethodsSupported []string `json:"token_endpoint_auth_methods_supported"`
+	TokenEndpointAuthSigningAlgValues []string `json:"token_endpoint_auth_signing_alg_values_supported"`
+	CodeChallengeMethodsSupported     []string `json:"code_challenge_methods_supported"`
+	ClaimsSupported                   []string `json:"claims_supported"`
+}
+
+// discovery serves /.well-known/openid-configuration so OIDC client libraries
+// can configure themselves from the issuer URL.
+func discovery(oauth *service.OAuthService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		issuer := oauth.Issuer()
+		w.Header().Set("Cache-Control", "public, max-age=3600")
+		writeJSON(w, http.StatusOK, discoveryDocument{
+			Issuer:                            issuer,
+			AuthorizationEndpoint:             issuer + "/oauth/authorize",
+			TokenEndpoint:                     issuer + "/oauth/token",
+			DeviceAuthorizationEndpoint:       issuer + "/oauth/device_authorization",
+			UserinfoEndpoint:                  issuer + "/oauth/userinfo",
+			JWKSURI:                           issuer + "/.well-known/jwks.json",
+			ScopesSupported:                   []string{service.ScopeOpenID, service.ScopeProfile, service.ScopeEmail, service.ScopeOfflineAccess},
+			ResponseTypesSupported:            []string{"code"},
+			GrantTypesSupported:               []string{"authorization_code", "refresh_token", "client_credentials", "urn:ietf:params:oauth:grant-type:device_code"},
+			SubjectTypesSupported:             []string{"public"},
+			IDTokenSigningAlgValuesSupported:  []string{oauth.SigningAlgorithm()},
+			TokenEndpointAuthMethodsSupported: []string{"client_secret_basic", "client_secret_post", "private_key_jwt", "none"},
+			TokenEndpointAuthSigningAlgValues: []string{token.AlgRS256, token.AlgEdDSA},
+			CodeChallengeMethodsSupported:     []string{"S256"},
+			ClaimsSupported:                   []string{"sub", "iss", "aud", "exp", "iat", "auth_time", "nonce", "name", "given_name", "family_name", "picture", "email", "email_verified"},
+		})
+	}
+}
+
+// userinfo serves the claims of the user behind a bearer access token.
+func userinfo(oauth *service.OAuthService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logs, err := logger.NewLogger()
+		if err != nil {
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Cache-Control", "no-store")
+		raw, ok := bearerToken(r)
+		if !ok {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="userinfo"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		resp, err := oauth.UserInfo(incomingContext(r), raw)
+		if err != nil {
+			var oerr *service.OAuthError
+			if !errors.As(err, &oerr) {
+				logs.Error("Error while getting userinfo", zap.Error(err))
+				http.Error(w, "internal error", http.StatusInternalServerError)
+				return
+			}
+			code := http.StatusUnauthorized
+			if oerr.Code == "insufficient_scope" {
+				code = http.StatusForbidden
+			}
+			w.Header().Set("WWW-Authenticate", `Bearer error="`+oerr.Code+`", error_description="`+oerr.Description+`"`)
+			writeJSON(w, code, map[string]string{"error": oerr.Code, "error_description": oerr.Description})
+			return
+		}
+		writeJSON(w, http.StatusOK, resp)
+	}
+}
+
+// bearerToken returns the token of an "Authorization: Bearer" header.
+func bearerToken(r *http.Request) (string, bool) {
+	v := r.Header.Get("Authorization")
+	if len(v) > 7 && strings.EqualFold(v[:7], "bearer ") {
+		return strings.TrimSpace(v[7:]), true
+	}
+	return "", false
+}
@@ -0,0 +1,83 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"blog-auth/internal/interceptor"
+	"blog-auth/internal/ratelimit"
+	"blog-auth/internal/service"
+	"blog-auth/internal/token"
+)
+
+// NewRouter returns the HTTP API served next to the gRPC server, for clients
+// that speak plain HTTP such as JWT libraries fetching the JWKS and OAuth
+// clients.
+func NewRouter(tokens *token.Manager, users *service.UserServiceImpl, oauth *service.OAuthService, proxies interceptor.TrustedProxies, limiter ratelimit.Limiter, rules ratelimit.Rules) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /oauth/signin", signInElsewhere(oauth))
+	mux.HandleFunc("GET /oauth/session", session(users, oauth))
+	mux.HandleFunc("POST /oauth/session", rateLimit(limiter, rules, "/oauth/session", session(users, oauth)))
+	mux.HandleFunc("GET /.well-known/jwks.json", jwks(tokens))
+	mux.HandleFunc("GET /oauth/authorize", authorize(users, oauth))
+	mux.HandleFunc("POST /oauth/authorize", rateLimit(limiter, rules, "/oauth/authorize", authorize(users, oauth)))
+	mux.HandleFunc("POST /oauth/token", rateLimit(limiter, rules, "/oauth/token", oauthToken(oauth)))
+	mux.HandleFunc("POST /oauth/device_authorization", rateLimit(limiter, rules, "/oauth/device_authorization", deviceAuthorization(oauth)))
+	mux.HandleFunc("GET /oauth/device", device(users, oauth))
+	mux.HandleFunc("POST /oauth/device", rateLimit(limiter, rules, "/oauth/device", device(users, oauth)))
+	mux.HandleFunc("GET /.well-known/openid-configuration", discovery(oauth))
+	mux.HandleFunc("GET /oauth/userinfo", userinfo(oauth))
+	mux.HandleFunc("POST /oauth/userinfo", userinfo(oauth))
+	return clientAddress(proxies, mux)
+}
+
+// clientAddress resolves the end user's address the way the gRPC server does,
+// so throttling sees the same address on both.
+func clientAddress(proxies interceptor.TrustedProxies, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		remote, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			remote = r.RemoteAddr
+		}
+		ip := proxies.ClientAddr(remote, r.Header.Values("X-Forwarded-For"))
+		next.ServeHTTP(w, r.WithContext(interceptor.WithClientIP(r.Context(), ip)))
+	})
+}
+
+// rateLimit applies the limits RATELIMITS sets for route, e.g.
+// "oauth/token=60/1m", with the same buckets as the gRPC server. Limits per
+// email count the posted email; all others count the client address.
+func rateLimit(limiter ratelimit.Limiter, rules ratelimit.Rules, route string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		limits := rules.For(route)
+		if len(limits) == 0 {
+			next(w, r)
+			return
+		}
+		r.ParseForm()
+		wait := interceptor.Throttle(r.Context(), limiter, route, limits, func(key string) string {
+			if email := strings.ToLower(strings.TrimSpace(r.PostForm.Get("email"))); key == ratelimit.KeyEmail && email != "" {
+				return "email:" + email
+			}
+			return "ip:" + interceptor.ClientIP(r.Context())
+		})
+		if wait == 0 {
+			next(w, r)
+			return
+		}
+		seconds := int((wait + time.Second - 1) / time.Second)
+		w.Header().Set("Retry-After", strconv.Itoa(seconds))
+		http.Error(w, fmt.Sprintf("rate limit exceeded, retry in %ds", seconds), http.StatusTooManyRequests)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
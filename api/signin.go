@@ -0,0 +1,254 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	pb "blog-auth/genproto/userservice"
+	logger "blog-auth/internal/logger"
+	"blog-auth/internal/service"
+	"blog-auth/internal/token"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// sessionCookie resumes the session of a user who signed in on a page, so
+// they are not asked again, whichever way they signed in.
+const sessionCookie = "blog_auth_session"
+
+// handoffCookie ties a sign-in on the app's page to the browser that was sent
+// there, for handoffTTL.
+const (
+	handoffCookie = "blog_auth_handoff"
+	handoffTTL    = 10 * time.Minute
+)
+
+// signInForm is the state of the sign-in part of a page: who is signed in
+// already, or the email as typed, the MFA challenge once the password was
+// accepted, and what went wrong. OtherSignIn links to the app's sign-in page
+// for users without a password.
+type signInForm struct {
+	SignedIn    string
+	Email       string
+	MFAToken    string
+	Error       string
+	OtherSignIn string
+}
+
+func (f signInForm) status() int {
+	if f.Error != "" {
+		return http.StatusUnauthorized
+	}
+	return http.StatusOK
+}
+
+// otherSignIn links to the app's sign-in page, through signInElsewhere, for
+// coming back to returnTo. It is empty when SIGNINURL is not set.
+func otherSignIn(oauth *service.OAuthService, returnTo string) string {
+	if !oauth.HasSignInURL() {
+		return ""
+	}
+	return "/oauth/signin?" + url.Values{"return_to": {returnTo}}.Encode()
+}
+
+// sessionUser returns the user the session cookie of r signs in and when they
+// signed in, or nil. The cookie is ignored on cross-site posts, so other sites
+// cannot act with it; SameSite keeps it from being sent on them too.
+func sessionUser(ctx context.Context, r *http.Request, users *service.UserServiceImpl) (*pb.UserModel, time.Time, error) {
+	cookie, err := r.Cookie(sessionCookie)
+	if err != nil || (r.Method != http.MethodGet && r.Header.Get("Sec-Fetch-Site") == "cross-site") {
+		return nil, time.Time{}, nil
+	}
+	return users.BrowserSession(ctx, cookie.Value)
+}
+
+// setSessionCookie hands the browser the cookie of a session.
+func setSessionCookie(w http.ResponseWriter, value string, expires time.Time) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookie,
+		Value:    value,
+		Path:     "/oauth",
+		Expires:  expires,
+		MaxAge:   int(time.Until(expires).Seconds()),
+		Secure:   true,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// authenticate returns the user of the session cookie, unless reuse is false,
+// or signs the user in with the form posted in r and starts a session. It also
+// returns when the user signed in. Like signIn, a nil user and nil error mean
+// form must be shown again.
+func authenticate(ctx context.Context, w http.ResponseWriter, r *http.Request, users *service.UserServiceImpl, form *signInForm, reuse bool) (*pb.UserModel, time.Time, error) {
+	if reuse {
+		user, authTime, err := sessionUser(ctx, r, users)
+		if err != nil || user != nil {
+			return user, authTime, err
+		}
+	}
+	user, err := signIn(ctx, r, users, form)
+	if err != nil || user == nil {
+		return nil, time.Time{}, err
+	}
+	value, expires, err := users.StartBrowserSession(ctx, user)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	setSessionCookie(w, value, expires)
+	return user, time.Now(), nil
+}
+
+// signInElsewhere serves the link to the app's sign-in page. It gives the
+// browser a handoff cookie and sends it to SIGNINURL with the handoff, so the
+// sign-in code the app gets for it only works in this browser: nobody can
+// sign another browser in to their own account.
+func signInElsewhere(oauth *service.OAuthService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		returnTo := r.URL.Query().Get("return_to")
+		if !oauth.HasSignInURL() || !localPath(returnTo) {
+			renderError(w, http.StatusBadRequest, &service.OAuthError{Code: "invalid_request", Description: "invalid return_to"})
+			return
+		}
+		handoff, _, err := token.NewOpaqueToken()
+		if err != nil {
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+		http.SetCookie(w, &http.Cookie{
+			Name:     handoffCookie,
+			Value:    handoff,
+			Path:     "/oauth",
+			MaxAge:   int(handoffTTL.Seconds()),
+			Secure:   true,
+			HttpOnly: true,
+			SameSite: http.SameSiteLaxMode,
+		})
+		back := "/oauth/session?" + url.Values{"return_to": {returnTo}}.Encode()
+		http.Redirect(w, r, oauth.SignInURL(back, handoff), http.StatusSeeOther)
+	}
+}
+
+type sessionView struct {
+	Code     string
+	ReturnTo string
+	Error    string
+}
+
+// session serves the page the app sends the browser back to with a sign-in
+// code. GET asks the user to continue; POST, from the page itself only,
+// redeems the code with the browser's handoff cookie and sends the user on to
+// return_to.
+func session(users *service.UserServiceImpl, oauth *service.OAuthService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logs, err := logger.NewLogger()
+		if err != nil {
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+		if err := r.ParseForm(); err != nil {
+			renderSession(w, http.StatusBadRequest, sessionView{Error: "malformed request"})
+			return
+		}
+		view := sessionView{Code: r.Form.Get("code"), ReturnTo: r.Form.Get("return_to")}
+		if !localPath(view.ReturnTo) {
+			view.ReturnTo = ""
+		}
+		if r.Method == http.MethodGet {
+			renderSession(w, http.StatusOK, view)
+			return
+		}
+		if crossSite(r, oauth.Issuer()) {
+			renderSession(w, http.StatusForbidden, sessionView{Error: "cross-site request refused"})
+			return
+		}
+		var handoff string
+		if cookie, err := r.Cookie(handoffCookie); err == nil {
+			handoff = cookie.Value
+		}
+		value, expires, err := users.RedeemSignInCode(incomingContext(r), view.Code, handoff)
+		if status.Code(err) == codes.Unauthenticated {
+			renderSession(w, http.StatusUnauthorized, sessionView{Error: "the sign-in has expired, please try again"})
+			return
+		}
+		if err != nil {
+			logs.Error("Error while redeeming sign-in code", zap.Error(err))
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+		setSessionCookie(w, value, expires)
+		http.SetCookie(w, &http.Cookie{Name: handoffCookie, Path: "/oauth", MaxAge: -1, Secure: true, HttpOnly: true, SameSite: http.SameSiteLaxMode})
+		if view.ReturnTo == "" {
+			renderSession(w, http.StatusOK, sessionView{})
+			return
+		}
+		http.Redirect(w, r, view.ReturnTo, http.StatusSeeOther)
+	}
+}
+
+func renderSession(w http.ResponseWriter, code int, view sessionView) {
+	render(w, code, sessionPage, view)
+}
+
+// localPath reports whether returnTo is one of the pages, so redirects to it
+// cannot leave the server.
+func localPath(returnTo string) bool {
+	return strings.HasPrefix(returnTo, "/oauth/") && !strings.ContainsAny(returnTo, "\\\r\n")
+}
+
+// crossSite reports whether r was sent from another site than issuer. Browsers
+// that send neither header are not told apart from same-origin ones.
+func crossSite(r *http.Request, issuer string) bool {
+	if site := r.Header.Get("Sec-Fetch-Site"); site != "" && site != "same-origin" && site != "none" {
+		return true
+	}
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return false
+	}
+	u, err := url.Parse(issuer)
+	return err != nil || origin != u.Scheme+"://"+u.Host
+}
+
+// signIn checks the password or, once there is an MFA challenge, the second
+// factor posted in r, with the same checks and throttling as Login. A nil
+// user and nil error mean form must be shown again: with the MFA step or
+// with an error the user can fix.
+func signIn(ctx context.Context, r *http.Request, users *service.UserServiceImpl, form *signInForm) (*pb.UserModel, error) {
+	var (
+		user *pb.UserModel
+		err  error
+	)
+	if mfaToken := r.PostForm.Get("mfa_token"); mfaToken != "" {
+		form.MFAToken = mfaToken
+		user, err = users.AuthenticateMFA(ctx, &pb.VerifyMFAReq{
+			MfaToken:     mfaToken,
+			Code:         r.PostForm.Get("code"),
+			RecoveryCode: r.PostForm.Get("recovery_code"),
+		})
+	} else {
+		form.Email = r.PostForm.Get("email")
+		var resp *pb.LoginRes
+		resp, err = users.Authenticate(ctx, &pb.LoginReq{Email: form.Email, Password: r.PostForm.Get("password")})
+		if err == nil && resp.MfaRequired {
+			form.MFAToken = resp.MfaToken
+			return nil, nil
+		}
+		if err == nil {
+			user = resp.UserRes
+		}
+	}
+	switch status.Code(err) {
+	case codes.OK:
+		return user, nil
+	case codes.Unauthenticated, codes.PermissionDenied, codes.ResourceExhausted, codes.InvalidArgument:
+		form.Error = status.Convert(err).Message()
+		return nil, nil
+	}
+	return nil, err
+}
@@ -27,6 +27,26 @@ type AdminServiceClient interface {
 	ForgetPassword(ctx context.Context, in *ForgetPasswordReq, opts ...grpc.CallOption) (*ForgetPasswordRes, error)
 	GetAllUsers(ctx context.Context, in *GetAllUsersReq, opts ...grpc.CallOption) (*GetAllUsersRes, error)
 	DeleteUser(ctx context.Context, in *DeleteUserReq, opts ...grpc.CallOption) (*DeleteUserRes, error)
+	ListFailedEmails(ctx context.Context, in *ListFailedEmailsReq, opts ...grpc.CallOption) (*ListFailedEmailsRes, error)
+	ResendEmail(ctx context.Context, in *ResendEmailReq, opts ...grpc.CallOption) (*ResendEmailRes, error)
+	UnlockUser(ctx context.Context, in *UnlockUserReq, opts ...grpc.CallOption) (*UnlockUserRes, error)
+	ForceLogout(ctx context.Context, in *ForceLogoutReq, opts ...grpc.CallOption) (*ForceLogoutRes, error)
+	RotateSigningKey(ctx context.Context, in *RotateSigningKeyReq, opts ...grpc.CallOption) (*RotateSigningKeyRes, error)
+	CreateOAuthClient(ctx context.Context, in *CreateOAuthClientReq, opts ...grpc.CallOption) (*CreateOAuthClientRes, error)
+	ListOAuthClients(ctx context.Context, in *ListOAuthClientsReq, opts ...grpc.CallOption) (*ListOAuthClientsRes, error)
+	DeleteOAuthClient(ctx context.Context, in *DeleteOAuthClientReq, opts ...grpc.CallOption) (*DeleteOAuthClientRes, error)
+	CreateServiceAccount(ctx context.Context, in *CreateServiceAccountReq, opts ...grpc.CallOption) (*CreateServiceAccountRes, error)
+	ListServiceAccounts(ctx context.Context, in *ListServiceAccountsReq, opts ...grpc.CallOption) (*ListServiceAccountsRes, error)
+	DeleteServiceAccount(ctx context.Context, in *DeleteServiceAccountReq, opts ...grpc.CallOption) (*DeleteServiceAccountRes, error)
+	CreateRole(ctx context.Context, in *CreateRoleReq, opts ...grpc.CallOption) (*CreateRoleRes, error)
+	DeleteRole(ctx context.Context, in *DeleteRoleReq, opts ...grpc.CallOption) (*DeleteRoleRes, error)
+	ListRoles(ctx context.Context, in *ListRolesReq, opts ...grpc.CallOption) (*ListRolesRes, error)
+	CreatePermission(ctx context.Context, in *CreatePermissionReq, opts ...grpc.CallOption) (*CreatePermissionRes, error)
+	ListPermissions(ctx context.Context, in *ListPermissionsReq, opts ...grpc.CallOption) (*ListPermissionsRes, error)
+	GrantPermission(ctx context.Context, in *GrantPermissionReq, opts ...grpc.CallOption) (*GrantPermissionRes, error)
+	RevokePermission(ctx context.Context, in *RevokePermissionReq, opts ...grpc.CallOption) (*RevokePermissionRes, error)
+	AssignRole(ctx context.Context, in *AssignRoleReq, opts ...grpc.CallOption) (*AssignRoleRes, error)
+	UnassignRole(ctx context.Context, in *UnassignRoleReq, opts ...grpc.CallOption) (*UnassignRoleRes, error)
 }
 
 type adminServiceClient struct {
@@ -82,6 +102,186 @@ func (c *adminServiceClient) DeleteUser(ctx context.Context, in *DeleteUserReq,
 	return out, nil
 }
 
+func (c *adminServiceClient) ListFailedEmails(ctx context.Context, in *ListFailedEmailsReq, opts ...grpc.CallOption) (*ListFailedEmailsRes, error) {
+	out := new(ListFailedEmailsRes)
+	err := c.cc.Invoke(ctx, "/userservice.AdminService/ListFailedEmails", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminServiceClient) ResendEmail(ctx context.Context, in *ResendEmailReq, opts ...grpc.CallOption) (*ResendEmailRes, error) {
+	out := new(ResendEmailRes)
+	err := c.cc.Invoke(ctx, "/userservice.AdminService/ResendEmail", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminServiceClient) UnlockUser(ctx context.Context, in *UnlockUserReq, opts ...grpc.CallOption) (*UnlockUserRes, error) {
+	out := new(UnlockUserRes)
+	err := c.cc.Invoke(ctx, "/userservice.AdminService/UnlockUser", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminServiceClient) ForceLogout(ctx context.Context, in *ForceLogoutReq, opts ...grpc.CallOption) (*ForceLogoutRes, error) {
+	out := new(ForceLogoutRes)
+	err := c.cc.Invoke(ctx, "/userservice.AdminService/ForceLogout", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminServiceClient) RotateSigningKey(ctx context.Context, in *RotateSigningKeyReq, opts ...grpc.CallOption) (*RotateSigningKeyRes, error) {
+	out := new(RotateSigningKeyRes)
+	err := c.cc.Invoke(ctx, "/userservice.AdminService/RotateSigningKey", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminServiceClient) CreateOAuthClient(ctx context.Context, in *CreateOAuthClientReq, opts ...grpc.CallOption) (*CreateOAuthClientRes, error) {
+	out := new(CreateOAuthClientRes)
+	err := c.cc.Invoke(ctx, "/userservice.AdminService/CreateOAuthClient", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminServiceClient) ListOAuthClients(ctx context.Context, in *ListOAuthClientsReq, opts ...grpc.CallOption) (*ListOAuthClientsRes, error) {
+	out := new(ListOAuthClientsRes)
+	err := c.cc.Invoke(ctx, "/userservice.AdminService/ListOAuthClients", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminServiceClient) DeleteOAuthClient(ctx context.Context, in *DeleteOAuthClientReq, opts ...grpc.CallOption) (*DeleteOAuthClientRes, error) {
+	out := new(DeleteOAuthClientRes)
+	err := c.cc.Invoke(ctx, "/userservice.AdminService/DeleteOAuthClient", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminServiceClient) CreateServiceAccount(ctx context.Context, in *CreateServiceAccountReq, opts ...grpc.CallOption) (*CreateServiceAccountRes, error) {
+	out := new(CreateServiceAccountRes)
+	err := c.cc.Invoke(ctx, "/userservice.AdminService/CreateServiceAccount", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminServiceClient) ListServiceAccounts(ctx context.Context, in *ListServiceAccountsReq, opts ...grpc.CallOption) (*ListServiceAccountsRes, error) {
+	out := new(ListServiceAccountsRes)
+	err := c.cc.Invoke(ctx, "/userservice.AdminService/ListServiceAccounts", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminServiceClient) DeleteServiceAccount(ctx context.Context, in *DeleteServiceAccountReq, opts ...grpc.CallOption) (*DeleteServiceAccountRes, error) {
+	out := new(DeleteServiceAccountRes)
+	err := c.cc.Invoke(ctx, "/userservice.AdminService/DeleteServiceAccount", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminServiceClient) CreateRole(ctx context.Context, in *CreateRoleReq, opts ...grpc.CallOption) (*CreateRoleRes, error) {
+	out := new(CreateRoleRes)
+	err := c.cc.Invoke(ctx, "/userservice.AdminService/CreateRole", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminServiceClient) DeleteRole(ctx context.Context, in *DeleteRoleReq, opts ...grpc.CallOption) (*DeleteRoleRes, error) {
+	out := new(DeleteRoleRes)
+	err := c.cc.Invoke(ctx, "/userservice.AdminService/DeleteRole", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminServiceClient) ListRoles(ctx context.Context, in *ListRolesReq, opts ...grpc.CallOption) (*ListRolesRes, error) {
+	out := new(ListRolesRes)
+	err := c.cc.Invoke(ctx, "/userservice.AdminService/ListRoles", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminServiceClient) CreatePermission(ctx context.Context, in *CreatePermissionReq, opts ...grpc.CallOption) (*CreatePermissionRes, error) {
+	out := new(CreatePermissionRes)
+	err := c.cc.Invoke(ctx, "/userservice.AdminService/CreatePermission", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminServiceClient) ListPermissions(ctx context.Context, in *ListPermissionsReq, opts ...grpc.CallOption) (*ListPermissionsRes, error) {
+	out := new(ListPermissionsRes)
+	err := c.cc.Invoke(ctx, "/userservice.AdminService/ListPermissions", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminServiceClient) GrantPermission(ctx context.Context, in *GrantPermissionReq, opts ...grpc.CallOption) (*GrantPermissionRes, error) {
+	out := new(GrantPermissionRes)
+	err := c.cc.Invoke(ctx, "/userservice.AdminService/GrantPermission", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminServiceClient) RevokePermission(ctx context.Context, in *RevokePermissionReq, opts ...grpc.CallOption) (*RevokePermissionRes, error) {
+	out := new(RevokePermissionRes)
+	err := c.cc.Invoke(ctx, "/userservice.AdminService/RevokePermission", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminServiceClient) AssignRole(ctx context.Context, in *AssignRoleReq, opts ...grpc.CallOption) (*AssignRoleRes, error) {
+	out := new(AssignRoleRes)
+	err := c.cc.Invoke(ctx, "/userservice.AdminService/AssignRole", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminServiceClient) UnassignRole(ctx context.Context, in *UnassignRoleReq, opts ...grpc.CallOption) (*UnassignRoleRes, error) {
+	out := new(UnassignRoleRes)
+	err := c.cc.Invoke(ctx, "/userservice.AdminService/UnassignRole", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // AdminServiceServer is the server API for AdminService service.
 // All implementations must embed UnimplementedAdminServiceServer
 // for forward compatibility
@@ -91,6 +291,26 @@ type AdminServiceServer interface {
 	ForgetPassword(context.Context, *ForgetPasswordReq) (*ForgetPasswordRes, error)
 	GetAllUsers(context.Context, *GetAllUsersReq) (*GetAllUsersRes, error)
 	DeleteUser(context.Context, *DeleteUserReq) (*DeleteUserRes, error)
+	ListFailedEmails(context.Context, *ListFailedEmailsReq) (*ListFailedEmailsRes, error)
+	ResendEmail(context.Context, *ResendEmailReq) (*ResendEmailRes, error)
+	UnlockUser(context.Context, *UnlockUserReq) (*UnlockUserRes, error)
+	ForceLogout(context.Context, *ForceLogoutReq) (*ForceLogoutRes, error)
+	RotateSigningKey(context.Context, *RotateSigningKeyReq) (*RotateSigningKeyRes, error)
+	CreateOAuthClient(context.Context, *CreateOAuthClientReq) (*CreateOAuthClientRes, error)
+	ListOAuthClients(context.Context, *ListOAuthClientsReq) (*ListOAuthClientsRes, error)
+	DeleteOAuthClient(context.Context, *DeleteOAuthClientReq) (*DeleteOAuthClientRes, error)
+	CreateServiceAccount(context.Context, *CreateServiceAccountReq) (*CreateServiceAccountRes, error)
+	ListServiceAccounts(context.Context, *ListServiceAccountsReq) (*ListServiceAccountsRes, error)
+	DeleteServiceAccount(context.Context, *DeleteServiceAccountReq) (*DeleteServiceAccountRes, error)
+	CreateRole(context.Context, *CreateRoleReq) (*CreateRoleRes, error)
+	DeleteRole(context.Context, *DeleteRoleReq) (*DeleteRoleRes, error)
+	ListRoles(context.Context, *ListRolesReq) (*ListRolesRes, error)
+	CreatePermission(context.Context, *CreatePermissionReq) (*CreatePermissionRes, error)
+	ListPermissions(context.Context, *ListPermissionsReq) (*ListPermissionsRes, error)
+	GrantPermission(context.Context, *GrantPermissionReq) (*GrantPermissionRes, error)
+	RevokePermission(context.Context, *RevokePermissionReq) (*RevokePermissionRes, error)
+	AssignRole(context.Context, *AssignRoleReq) (*AssignRoleRes, error)
+	UnassignRole(context.Context, *UnassignRoleReq) (*UnassignRoleRes, error)
 	mustEmbedUnimplementedAdminServiceServer()
 }
 
@@ -113,6 +333,66 @@ func (UnimplementedAdminServiceServer) GetAllUsers(context.Context, *GetAllUsers
 func (UnimplementedAdminServiceServer) DeleteUser(context.Context, *DeleteUserReq) (*DeleteUserRes, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method DeleteUser not implemented")
 }
+func (UnimplementedAdminServiceServer) ListFailedEmails(context.Context, *ListFailedEmailsReq) (*ListFailedEmailsRes, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListFailedEmails not implemented")
+}
+func (UnimplementedAdminServiceServer) ResendEmail(context.Context, *ResendEmailReq) (*ResendEmailRes, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ResendEmail not implemented")
+}
+func (UnimplementedAdminServiceServer) UnlockUser(context.Context, *UnlockUserReq) (*UnlockUserRes, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UnlockUser not implemented")
+}
+func (UnimplementedAdminServiceServer) ForceLogout(context.Context, *ForceLogoutReq) (*ForceLogoutRes, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ForceLogout not implemented")
+}
+func (UnimplementedAdminServiceServer) RotateSigningKey(context.Context, *RotateSigningKeyReq) (*RotateSigningKeyRes, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RotateSigningKey not implemented")
+}
+func (UnimplementedAdminServiceServer) CreateOAuthClient(context.Context, *CreateOAuthClientReq) (*CreateOAuthClientRes, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateOAuthClient not implemented")
+}
+func (UnimplementedAdminServiceServer) ListOAuthClients(context.Context, *ListOAuthClientsReq) (*ListOAuthClientsRes, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListOAuthClients not implemented")
+}
+func (UnimplementedAdminServiceServer) DeleteOAuthClient(context.Context, *DeleteOAuthClientReq) (*DeleteOAuthClientRes, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteOAuthClient not implemented")
+}
+func (UnimplementedAdminServiceServer) CreateServiceAccount(context.Context, *CreateServiceAccountReq) (*CreateServiceAccountRes, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateServiceAccount not implemented")
+}
+func (UnimplementedAdminServiceServer) ListServiceAccounts(context.Context, *ListServiceAccountsReq) (*ListServiceAccountsRes, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListServiceAccounts not implemented")
+}
+func (UnimplementedAdminServiceServer) DeleteServiceAccount(context.Context, *DeleteServiceAccountReq) (*DeleteServiceAccountRes, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteServiceAccount not implemented")
+}
+func (UnimplementedAdminServiceServer) CreateRole(context.Context, *CreateRoleReq) (*CreateRoleRes, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateRole not implemented")
+}
+func (UnimplementedAdminServiceServer) DeleteRole(context.Context, *DeleteRoleReq) (*DeleteRoleRes, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteRole not implemented")
+}
+func (UnimplementedAdminServiceServer) ListRoles(context.Context, *ListRolesReq) (*ListRolesRes, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListRoles not implemented")
+}
+func (UnimplementedAdminServiceServer) CreatePermission(context.Context, *CreatePermissionReq) (*CreatePermissionRes, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreatePermission not implemented")
+}
+func (UnimplementedAdminServiceServer) ListPermissions(context.Context, *ListPermissionsReq) (*ListPermissionsRes, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListPermissions not implemented")
+}
+func (UnimplementedAdminServiceServer) GrantPermission(context.Context, *GrantPermissionReq) (*GrantPermissionRes, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GrantPermission not implemented")
+}
+func (UnimplementedAdminServiceServer) RevokePermission(context.Context, *RevokePermissionReq) (*RevokePermissionRes, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RevokePermission not implemented")
+}
+func (UnimplementedAdminServiceServer) AssignRole(context.Context, *AssignRoleReq) (*AssignRoleRes, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AssignRole not implemented")
+}
+func (UnimplementedAdminServiceServer) UnassignRole(context.Context, *UnassignRoleReq) (*UnassignRoleRes, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UnassignRole not implemented")
+}
 func (UnimplementedAdminServiceServer) mustEmbedUnimplementedAdminServiceServer() {}
 
 // UnsafeAdminServiceServer may be embedded to opt out of forward compatibility for this service.
@@ -216,6 +496,366 @@ func _AdminService_DeleteUser_Handler(srv interface{}, ctx context.Context, dec
 	return interceptor(ctx, in, info, handler)
 }
 
+func _AdminService_ListFailedEmails_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListFailedEmailsReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServiceServer).ListFailedEmails(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/userservice.AdminService/ListFailedEmails",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServiceServer).ListFailedEmails(ctx, req.(*ListFailedEmailsReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdminService_ResendEmail_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ResendEmailReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServiceServer).ResendEmail(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/userservice.AdminService/ResendEmail",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServiceServer).ResendEmail(ctx, req.(*ResendEmailReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdminService_UnlockUser_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UnlockUserReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServiceServer).UnlockUser(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/userservice.AdminService/UnlockUser",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServiceServer).UnlockUser(ctx, req.(*UnlockUserReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdminService_ForceLogout_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ForceLogoutReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServiceServer).ForceLogout(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/userservice.AdminService/ForceLogout",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServiceServer).ForceLogout(ctx, req.(*ForceLogoutReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdminService_RotateSigningKey_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RotateSigningKeyReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServiceServer).RotateSigningKey(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/userservice.AdminService/RotateSigningKey",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServiceServer).RotateSigningKey(ctx, req.(*RotateSigningKeyReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdminService_CreateOAuthClient_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateOAuthClientReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServiceServer).CreateOAuthClient(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/userservice.AdminService/CreateOAuthClient",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServiceServer).CreateOAuthClient(ctx, req.(*CreateOAuthClientReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdminService_ListOAuthClients_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListOAuthClientsReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServiceServer).ListOAuthClients(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/userservice.AdminService/ListOAuthClients",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServiceServer).ListOAuthClients(ctx, req.(*ListOAuthClientsReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdminService_DeleteOAuthClient_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteOAuthClientReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServiceServer).DeleteOAuthClient(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/userservice.AdminService/DeleteOAuthClient",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServiceServer).DeleteOAuthClient(ctx, req.(*DeleteOAuthClientReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdminService_CreateServiceAccount_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateServiceAccountReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServiceServer).CreateServiceAccount(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/userservice.AdminService/CreateServiceAccount",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServiceServer).CreateServiceAccount(ctx, req.(*CreateServiceAccountReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdminService_ListServiceAccounts_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListServiceAccountsReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServiceServer).ListServiceAccounts(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/userservice.AdminService/ListServiceAccounts",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServiceServer).ListServiceAccounts(ctx, req.(*ListServiceAccountsReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdminService_DeleteServiceAccount_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteServiceAccountReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServiceServer).DeleteServiceAccount(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/userservice.AdminService/DeleteServiceAccount",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServiceServer).DeleteServiceAccount(ctx, req.(*DeleteServiceAccountReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdminService_CreateRole_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateRoleReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServiceServer).CreateRole(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/userservice.AdminService/CreateRole",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServiceServer).CreateRole(ctx, req.(*CreateRoleReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdminService_DeleteRole_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteRoleReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServiceServer).DeleteRole(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/userservice.AdminService/DeleteRole",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServiceServer).DeleteRole(ctx, req.(*DeleteRoleReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdminService_ListRoles_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListRolesReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServiceServer).ListRoles(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/userservice.AdminService/ListRoles",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServiceServer).ListRoles(ctx, req.(*ListRolesReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdminService_CreatePermission_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreatePermissionReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServiceServer).CreatePermission(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/userservice.AdminService/CreatePermission",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServiceServer).CreatePermission(ctx, req.(*CreatePermissionReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdminService_ListPermissions_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListPermissionsReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServiceServer).ListPermissions(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/userservice.AdminService/ListPermissions",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServiceServer).ListPermissions(ctx, req.(*ListPermissionsReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdminService_GrantPermission_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GrantPermissionReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServiceServer).GrantPermission(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/userservice.AdminService/GrantPermission",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServiceServer).GrantPermission(ctx, req.(*GrantPermissionReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdminService_RevokePermission_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RevokePermissionReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServiceServer).RevokePermission(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/userservice.AdminService/RevokePermission",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServiceServer).RevokePermission(ctx, req.(*RevokePermissionReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdminService_AssignRole_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AssignRoleReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServiceServer).AssignRole(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/userservice.AdminService/AssignRole",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServiceServer).AssignRole(ctx, req.(*AssignRoleReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdminService_UnassignRole_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UnassignRoleReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServiceServer).UnassignRole(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/userservice.AdminService/UnassignRole",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServiceServer).UnassignRole(ctx, req.(*UnassignRoleReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // AdminService_ServiceDesc is the grpc.ServiceDesc for AdminService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -243,6 +883,86 @@ var AdminService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "DeleteUser",
 			Handler:    _AdminService_DeleteUser_Handler,
 		},
+		{
+			MethodName: "ListFailedEmails",
+			Handler:    _AdminService_ListFailedEmails_Handler,
+		},
+		{
+			MethodName: "ResendEmail",
+			Handler:    _AdminService_ResendEmail_Handler,
+		},
+		{
+			MethodName: "UnlockUser",
+			Handler:    _AdminService_UnlockUser_Handler,
+		},
+		{
+			MethodName: "ForceLogout",
+			Handler:    _AdminService_ForceLogout_Handler,
+		},
+		{
+			MethodName: "RotateSigningKey",
+			Handler:    _AdminService_RotateSigningKey_Handler,
+		},
+		{
+			MethodName: "CreateOAuthClient",
+			Handler:    _AdminService_CreateOAuthClient_Handler,
+		},
+		{
+			MethodName: "ListOAuthClients",
+			Handler:    _AdminService_ListOAuthClients_Handler,
+		},
+		{
+			MethodName: "DeleteOAuthClient",
+			Handler:    _AdminService_DeleteOAuthClient_Handler,
+		},
+		{
+			MethodName: "CreateServiceAccount",
+			Handler:    _AdminService_CreateServiceAccount_Handler,
+		},
+		{
+			MethodName: "ListServiceAccounts",
+			Handler:    _AdminService_ListServiceAccounts_Handler,
+		},
+		{
+			MethodName: "DeleteServiceAccount",
+			Handler:    _AdminService_DeleteServiceAccount_Handler,
+		},
+		{
+			MethodName: "CreateRole",
+			Handler:    _AdminService_CreateRole_Handler,
+		},
+		{
+			MethodName: "DeleteRole",
+			Handler:    _AdminService_DeleteRole_Handler,
+		},
+		{
+			MethodName: "ListRoles",
+			Handler:    _AdminService_ListRoles_Handler,
+		},
+		{
+			MethodName: "CreatePermission",
+			Handler:    _AdminService_CreatePermission_Handler,
+		},
+		{
+			MethodName: "ListPermissions",
+			Handler:    _AdminService_ListPermissions_Handler,
+		},
+		{
+			MethodName: "GrantPermission",
+			Handler:    _AdminService_GrantPermission_Handler,
+		},
+		{
+			MethodName: "RevokePermission",
+			Handler:    _AdminService_RevokePermission_Handler,
+		},
+		{
+			MethodName: "AssignRole",
+			Handler:    _AdminService_AssignRole_Handler,
+		},
+		{
+			MethodName: "UnassignRole",
+			Handler:    _AdminService_UnassignRole_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "protos/user-service/admin.proto",
@@ -0,0 +1,155 @@
+package authz
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"blog-auth/internal/config"
+	logger "blog-auth/internal/logger"
+	"blog-auth/internal/storage/postgres"
+
+	"github.com/casbin/casbin/v2"
+	fileadapter "github.com/casbin/casbin/v2/persist/file-adapter"
+	"go.uber.org/zap"
+)
+
+// DefaultDomain is used when a request does not name a domain.
+const DefaultDomain = "blog"
+
+// Request is one authorization question. Owner is the subject that owns
+// Object, if known, so policies for "owner" can apply. Roles are the roles
+// Subject holds outside the policy, from the users and user_roles tables;
+// rules for any of them apply to Subject too.
+type Request struct {
+	Subject string
+	Roles   []string
+	Domain  string
+	Object  string
+	Action  string
+	Owner   string
+}
+
+// Engine evaluates requests against a Casbin model and policy. The policy is
+// read from a CSV file or from the casbin_rules table, depending on
+// AUTHZSOURCE, and Watch reloads both while the server runs.
+type Engine struct {
+	modelPath  string
+	policyPath string
+	db         *sql.DB
+
+	mu       sync.RWMutex
+	enforcer *casbin.Enforcer
+	loaded   time.Time
+}
+
+func New(cfg config.Config, db *sql.DB) (*Engine, error) {
+	e := &Engine{modelPath: cfg.AUTHZMODEL}
+	switch cfg.AUTHZSOURCE {
+	case "", "file":
+		e.policyPath = cfg.AUTHZPOLICY
+	case "postgres":
+		e.db = db
+	default:
+		return nil, fmt.Errorf("unknown authorization policy source %q", cfg.AUTHZSOURCE)
+	}
+	if err := e.Reload(); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// Reload builds a new enforcer from the current model and policy and swaps it
+// in. If anything fails the previous one keeps serving.
+func (e *Engine) Reload() error {
+	started := time.Now()
+	var enforcer *casbin.Enforcer
+	var err error
+	if e.db != nil {
+		enforcer, err = casbin.NewEnforcer(e.modelPath, postgres.NewCasbinAdapter(e.db))
+	} else {
+		enforcer, err = casbin.NewEnforcer(e.modelPath, fileadapter.NewAdapter(e.policyPath))
+	}
+	if err != nil {
+		return err
+	}
+	e.mu.Lock()
+	e.enforcer = enforcer
+	e.loaded = started
+	e.mu.Unlock()
+	return nil
+}
+
+// Watch reloads the engine every interval until ctx is cancelled. File based
+// policies are only reloaded when the model or policy file changed.
+func (e *Engine) Watch(ctx context.Context, interval time.Duration) {
+	logs, err := logger.NewLogger()
+	if err != nil {
+		return
+	}
+	if interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if e.db == nil && !e.changed() {
+			continue
+		}
+		if err := e.Reload(); err != nil {
+			logs.Error("Error reloading authorization policy", zap.Error(err))
+		}
+	}
+}
+
+func (e *Engine) changed() bool {
+	e.mu.RLock()
+	loaded := e.loaded
+	e.mu.RUnlock()
+	for _, path := range []string{e.modelPath, e.policyPath} {
+		info, err := os.Stat(path)
+		if err == nil && info.ModTime().After(loaded) {
+			return true
+		}
+	}
+	return false
+}
+
+// Enforce reports whether subject may perform action on object in the default
+// domain.
+func (e *Engine) Enforce(subject, object, action string) (bool, error) {
+	return e.EnforceRequest(Request{Subject: subject, Object: object, Action: action})
+}
+
+// EnforceRequest reports whether r is allowed. The subject and each of its
+// roles are checked in turn, and as in the model a deny for any of them
+// overrides an allow for another.
+func (e *Engine) EnforceRequest(r Request) (bool, error) {
+	if r.Domain == "" {
+		r.Domain = DefaultDomain
+	}
+	e.mu.RLock()
+	enforcer := e.enforcer
+	e.mu.RUnlock()
+	allowed := false
+	for _, subject := range append([]string{r.Subject}, r.Roles...) {
+		ok, rule, err := enforcer.EnforceEx(subject, r.Domain, r.Object, r.Action, r.Owner)
+		if err != nil {
+			return false, err
+		}
+		// A refusal that names a rule was a deny rather than no match.
+		if !ok && len(rule) > 0 && rule[len(rule)-1] == "deny" {
+			return false, nil
+		}
+		allowed = allowed || ok
+	}
+	return allowed, nil
+}
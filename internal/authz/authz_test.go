@@ -0,0 +1,76 @@
+package authz
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"blog-auth/internal/config"
+)
+
+const testPolicy = `p, admin, *, /*, *, allow
+p, editor, blog, /posts/*, *, allow
+p, author, blog, /posts, create, allow
+p, banned, *, /*, *, deny
+p, user-9, blog, /posts/*, delete, deny
+p, owner, blog, /posts/:id, edit, allow
+p, owner, blog, /posts/:id, delete, allow
+g, user-7, editor, blog
+`
+
+func newTestEngine(t *testing.T) *Engine {
+	t.Helper()
+	policy := filepath.Join(t.TempDir(), "policy.csv")
+	if err := os.WriteFile(policy, []byte(testPolicy), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	e, err := New(config.Config{AUTHZMODEL: "../../api/model.conf", AUTHZPOLICY: policy}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return e
+}
+
+func TestEnforceRequest(t *testing.T) {
+	e := newTestEngine(t)
+	tests := []struct {
+		name string
+		req  Request
+		want bool
+	}{
+		{"role allows", Request{Subject: "user-1", Roles: []string{"editor"}, Object: "/posts/1", Action: "edit"}, true},
+		{"no rule", Request{Subject: "user-1", Roles: []string{"author"}, Object: "/posts/1", Action: "edit"}, false},
+		{"grouping in the policy", Request{Subject: "user-7", Object: "/posts/1", Action: "edit"}, true},
+		{"deny for a role overrides another role", Request{Subject: "user-1", Roles: []string{"admin", "banned"}, Object: "/posts/1", Action: "edit"}, false},
+		{"deny for a role listed first", Request{Subject: "user-1", Roles: []string{"banned", "editor"}, Object: "/posts", Action: "create"}, false},
+		{"deny for the user overrides a role", Request{Subject: "user-9", Roles: []string{"editor"}, Object: "/posts/1", Action: "delete"}, false},
+		{"deny for the user is scoped to its action", Request{Subject: "user-9", Roles: []string{"editor"}, Object: "/posts/1", Action: "edit"}, true},
+		{"owner", Request{Subject: "user-1", Object: "/posts/1", Action: "edit", Owner: "user-1"}, true},
+		{"not the owner", Request{Subject: "user-1", Object: "/posts/1", Action: "edit", Owner: "user-2"}, false},
+		{"owner unknown", Request{Subject: "user-1", Object: "/posts/1", Action: "edit"}, false},
+		{"deny overrides ownership", Request{Subject: "user-9", Object: "/posts/1", Action: "delete", Owner: "user-9"}, false},
+		{"default domain", Request{Subject: "user-1", Roles: []string{"author"}, Domain: DefaultDomain, Object: "/posts", Action: "create"}, true},
+		{"rule of another domain", Request{Subject: "user-1", Roles: []string{"author"}, Domain: "shop", Object: "/posts", Action: "create"}, false},
+		{"grouping of another domain", Request{Subject: "user-7", Domain: "shop", Object: "/posts/1", Action: "edit"}, false},
+		{"rule for every domain", Request{Subject: "user-1", Roles: []string{"admin"}, Domain: "shop", Object: "/orders/1", Action: "refund"}, true},
+	}
+	for _, tt := range tests {
+		got, err := e.EnforceRequest(tt.req)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if got != tt.want {
+			t.Errorf("%s: EnforceRequest = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestEnforce(t *testing.T) {
+	e := newTestEngine(t)
+	if ok, err := e.Enforce("admin", "/posts/1", "delete"); err != nil || !ok {
+		t.Errorf("Enforce = %v, %v", ok, err)
+	}
+	if ok, err := e.Enforce("banned", "/posts/1", "read"); err != nil || ok {
+		t.Errorf("Enforce for a denied subject = %v, %v", ok, err)
+	}
+}
@@ -3,6 +3,8 @@ package config
 import (
 	"fmt"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 	"github.com/spf13/cast"
@@ -19,9 +21,68 @@ type Config struct {
 	KAFKAPORT       int
 	REDISHOST       string
 	REDISPORT       int
+	REDISPASSWORD   string
+	RATEBACKEND     string
+	RATELIMITS      string
+	RATEDEFAULT     string
+	REVOKEBACKEND   string
+	AUTHZMODEL      string
+	AUTHZPOLICY     string
+	AUTHZSOURCE     string
+	AUTHZRELOAD     time.Duration
 	TOKENKEY        string
+	TOKENALGO       string
+	KEYROTATION     time.Duration
+	KEYOVERLAP      time.Duration
+	PASSWORDALGO    string
+	ARGONTIME       int
+	ARGONMEMORY     int
+	ARGONTHREADS    int
+	BCRYPTCOST      int
+	PASSWORDPEPPER  string
+	ACCESSTOKENTTL  time.Duration
+	REFRESHTOKENTTL time.Duration
+	RESETTOKENTTL   time.Duration
+	VERIFYCODETTL   time.Duration
+	VERIFYATTEMPTS  int
+	LOGINFREEFAILS  int
+	IPFREEFAILS     int
+	LOGINDELAY      time.Duration
+	LOGINMAXDELAY   time.Duration
+	LOCKTHRESHOLD   int
+	LOCKDURATION    time.Duration
+	MFATOKENTTL     time.Duration
+	MFAATTEMPTS     int
+	TOTPISSUER      string
+	MAGICLINKTTL    time.Duration
+	MAGICLINKURL    string
+	MAGICSIGNUP     bool
+	OAUTHCODETTL    time.Duration
+	OIDCISSUER      string
+	SIGNINURL       string
+	DEVICECODETTL   time.Duration
+	DEVICEINTERVAL  time.Duration
+	IDPROVIDERS     string
+	IDPCALLBACKURL  string
+	IDPLOGINTTL     time.Duration
+	TELEGRAMTOKEN   string
+	TELEGRAMMAXAGE  time.Duration
+	WEBAUTHNRPID    string
+	WEBAUTHNRPNAME  string
+	WEBAUTHNORIGINS []string
+	TRUSTEDPROXIES  []string
 	EMAILSECREDKEY  string
 	EMAIL           string
+	MAILDRIVER      string
+	MAILDIR         string
+	SMTPHOST        string
+	SMTPPORT        int
+	SMTPTLS         string
+	MAILWORKERS     int
+	MAILPOLL        time.Duration
+	MAILATTEMPTS    int
+	MAILBACKOFF     time.Duration
+	MAILRETENTION   time.Duration
 	MONGOPORT       int
 	MONGOHOST       string
 	MONGODBDATABASE string
@@ -30,6 +91,7 @@ type Config struct {
 	BOOKHOST        string
 	BOOKPORT        int
 	AUTHPORT        int
+	HTTPPORT        int
 	AUTHHOST        string
 	GATEWAYHOST     string
 	GATEWAYPORT     int
@@ -49,8 +111,68 @@ func Load() Config {
 		DBPASSWORD:      cast.ToString(getEnv("POSTGRESPASSWORD", "1")),
 		DBNAME:          cast.ToString(getEnv("POSTGESDB", "1")),
 		REDISHOST:       cast.ToString(getEnv("REDISHOST", "0")),
+		REDISPASSWORD:   cast.ToString(getEnv("REDISPASSWORD", "")),
+		RATEBACKEND:     cast.ToString(getEnv("RATEBACKEND", "memory")),
+		RATELIMITS:      cast.ToString(getEnv("RATELIMITS", "Login=5/1m:email,Login=20/1m:ip,ForgotPassword=3/1h:email,ForgotPassword=10/1h:ip,RegisterUser=5/1h:ip,RequestMagicLink=3/1h:email,VerifyEmail=10/10m:email,ResetPassword=10/1h:ip,VerifyMFA=10/5m:ip,oauth/authorize=5/1m:email,oauth/authorize=20/1m:ip,oauth/token=60/1m:ip,oauth/device_authorization=10/1m:ip,oauth/device=10/1m:ip,oauth/session=10/1m:ip")),
+		RATEDEFAULT:     cast.ToString(getEnv("RATEDEFAULT", "300/1m:ip")),
+		REVOKEBACKEND:   cast.ToString(getEnv("REVOKEBACKEND", "memory")),
+		AUTHZMODEL:      cast.ToString(getEnv("AUTHZMODEL", "api/model.conf")),
+		AUTHZPOLICY:     cast.ToString(getEnv("AUTHZPOLICY", "api/policy.csv")),
+		AUTHZSOURCE:     cast.ToString(getEnv("AUTHZSOURCE", "file")),
+		AUTHZRELOAD:     cast.ToDuration(getEnv("AUTHZRELOAD", "30s")),
+		TOKENKEY:        cast.ToString(getEnv("TOKENKEY", "")),
+		TOKENALGO:       cast.ToString(getEnv("TOKENALGO", "RS256")),
+		KEYROTATION:     cast.ToDuration(getEnv("KEYROTATION", "720h")),
+		KEYOVERLAP:      cast.ToDuration(getEnv("KEYOVERLAP", "24h")),
+		PASSWORDALGO:    cast.ToString(getEnv("PASSWORDALGO", "argon2id")),
+		ARGONTIME:       cast.ToInt(getEnv("ARGONTIME", 3)),
+		ARGONMEMORY:     cast.ToInt(getEnv("ARGONMEMORY", 64*1024)),
+		ARGONTHREADS:    cast.ToInt(getEnv("ARGONTHREADS", 2)),
+		BCRYPTCOST:      cast.ToInt(getEnv("BCRYPTCOST", 12)),
+		PASSWORDPEPPER:  cast.ToString(getEnv("PASSWORDPEPPER", "")),
+		ACCESSTOKENTTL:  cast.ToDuration(getEnv("ACCESSTOKENTTL", "15m")),
+		REFRESHTOKENTTL: cast.ToDuration(getEnv("REFRESHTOKENTTL", "720h")),
+		RESETTOKENTTL:   cast.ToDuration(getEnv("RESETTOKENTTL", "30m")),
+		VERIFYCODETTL:   cast.ToDuration(getEnv("VERIFYCODETTL", "15m")),
+		VERIFYATTEMPTS:  cast.ToInt(getEnv("VERIFYATTEMPTS", 5)),
+		LOGINFREEFAILS:  cast.ToInt(getEnv("LOGINFREEFAILS", 3)),
+		IPFREEFAILS:     cast.ToInt(getEnv("IPFREEFAILS", 20)),
+		LOGINDELAY:      cast.ToDuration(getEnv("LOGINDELAY", "1s")),
+		LOGINMAXDELAY:   cast.ToDuration(getEnv("LOGINMAXDELAY", "15m")),
+		LOCKTHRESHOLD:   cast.ToInt(getEnv("LOCKTHRESHOLD", 10)),
+		LOCKDURATION:    cast.ToDuration(getEnv("LOCKDURATION", "30m")),
+		MFATOKENTTL:     cast.ToDuration(getEnv("MFATOKENTTL", "5m")),
+		MFAATTEMPTS:     cast.ToInt(getEnv("MFAATTEMPTS", 5)),
+		TOTPISSUER:      cast.ToString(getEnv("TOTPISSUER", "Blog")),
+		MAGICLINKTTL:    cast.ToDuration(getEnv("MAGICLINKTTL", "15m")),
+		MAGICLINKURL:    cast.ToString(getEnv("MAGICLINKURL", "http://localhost:3000/auth/magic")),
+		MAGICSIGNUP:     cast.ToBool(getEnv("MAGICSIGNUP", false)),
+		OAUTHCODETTL:    cast.ToDuration(getEnv("OAUTHCODETTL", "1m")),
+		OIDCISSUER:      cast.ToString(getEnv("OIDCISSUER", "http://localhost:8080")),
+		SIGNINURL:       cast.ToString(getEnv("SIGNINURL", "")),
+		DEVICECODETTL:   cast.ToDuration(getEnv("DEVICECODETTL", "10m")),
+		DEVICEINTERVAL:  cast.ToDuration(getEnv("DEVICEINTERVAL", "5s")),
+		IDPROVIDERS:     cast.ToString(getEnv("IDPROVIDERS", "")),
+		IDPCALLBACKURL:  cast.ToString(getEnv("IDPCALLBACKURL", "http://localhost:3000/auth/callback")),
+		IDPLOGINTTL:     cast.ToDuration(getEnv("IDPLOGINTTL", "10m")),
+		TELEGRAMTOKEN:   cast.ToString(getEnv("TELEGRAMTOKEN", "")),
+		TELEGRAMMAXAGE:  cast.ToDuration(getEnv("TELEGRAMMAXAGE", "1h")),
+		WEBAUTHNRPID:    cast.ToString(getEnv("WEBAUTHNRPID", "localhost")),
+		WEBAUTHNRPNAME:  cast.ToString(getEnv("WEBAUTHNRPNAME", "Blog")),
+		WEBAUTHNORIGINS: strings.Split(cast.ToString(getEnv("WEBAUTHNORIGINS", "http://localhost:3000")), ","),
+		TRUSTEDPROXIES:  strings.Split(cast.ToString(getEnv("TRUSTEDPROXIES", "127.0.0.1/32,::1/128")), ","),
 		EMAILSECREDKEY:  cast.ToString(getEnv("EMAILSECREDKEY", "0")),
 		EMAIL:           cast.ToString(getEnv("EMAIL", "0")),
+		MAILDRIVER:      cast.ToString(getEnv("MAILDRIVER", "smtp")),
+		MAILDIR:         cast.ToString(getEnv("MAILDIR", "maildir")),
+		SMTPHOST:        cast.ToString(getEnv("SMTPHOST", "smtp.gmail.com")),
+		SMTPPORT:        cast.ToInt(getEnv("SMTPPORT", 587)),
+		SMTPTLS:         cast.ToString(getEnv("SMTPTLS", "starttls")),
+		MAILWORKERS:     cast.ToInt(getEnv("MAILWORKERS", 4)),
+		MAILPOLL:        cast.ToDuration(getEnv("MAILPOLL", "5s")),
+		MAILATTEMPTS:    cast.ToInt(getEnv("MAILATTEMPTS", 8)),
+		MAILBACKOFF:     cast.ToDuration(getEnv("MAILBACKOFF", "30s")),
+		MAILRETENTION:   cast.ToDuration(getEnv("MAILRETENTION", "168h")),
 		MONGOHOST:       cast.ToString(getEnv("MONGOHOST", "0")),
 		MONGODBDATABASE: cast.ToString(getEnv("MONGODBDATABASE", "0")),
 		BOOKINGHOST:     cast.ToString(getEnv("BOOKINGHOST", "0")),
@@ -63,12 +185,24 @@ func Load() Config {
 		MONGOPORT:       cast.ToInt(getEnv("MONGOPORT", 1)),
 		BOOKPORT:        cast.ToInt(getEnv("BOOKPORT", 1)),
 		AUTHPORT:        cast.ToInt(getEnv("AUTHPORT", 1)),
+		HTTPPORT:        cast.ToInt(getEnv("HTTPPORT", 8080)),
 		GATEWAYPORT:     cast.ToInt(getEnv("GATEWAYPORT", 1)),
 	}
 
 	return config
 }
 
+// MinTokenKeyLen is the shortest TOKENKEY accepted, in bytes.
+const MinTokenKeyLen = 32
+
+// Validate reports settings the server must not start with.
+func (c Config) Validate() error {
+	if len(c.TOKENKEY) < MinTokenKeyLen {
+		return fmt.Errorf("TOKENKEY must be set to a secret of at least %d bytes", MinTokenKeyLen)
+	}
+	return nil
+}
+
 // getEnv retrieves an environment variable or returns a default value.
 func getEnv(key string, defaultVal interface{}) interface{} {
 	val, exists := os.LookupEnv(key)
@@ -0,0 +1,17 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	for _, key := range []string{"", "0", strings.Repeat("k", MinTokenKeyLen-1)} {
+		if err := (Config{TOKENKEY: key}).Validate(); err == nil {
+			t.Errorf("TOKENKEY of %d bytes accepted", len(key))
+		}
+	}
+	if err := (Config{TOKENKEY: strings.Repeat("k", MinTokenKeyLen)}).Validate(); err != nil {
+		t.Errorf("Validate: %v", err)
+	}
+}
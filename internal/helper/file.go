@@ -0,0 +1,47 @@
+package helper
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+)
+
+// fileMailer writes every message into a maildir so that email flows can be
+// exercised locally and inspected with any mail client.
+type fileMailer struct {
+	dir     string
+	from    string
+	counter atomic.Uint64
+}
+
+func NewFileMailer(dir, from string) (Mailer, error) {
+	if dir == "" {
+		dir = "maildir"
+	}
+	for _, sub := range []string{"tmp", "new", "cur"} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0o755); err != nil {
+			return nil, err
+		}
+	}
+	return &fileMailer{dir: dir, from: from}, nil
+}
+
+func (m *fileMailer) Send(ctx context.Context, msg Message) error {
+	raw, err := buildMIME(m.from, msg)
+	if err != nil {
+		return err
+	}
+	host, _ := os.Hostname()
+	name := fmt.Sprintf("%d.%d_%d.%s.eml", time.Now().Unix(), os.Getpid(), m.counter.Add(1), host)
+
+	// Maildir delivery: write into tmp and rename into new so readers never
+	// see a partially written file.
+	tmp := filepath.Join(m.dir, "tmp", name)
+	if err := os.WriteFile(tmp, raw, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(m.dir, "new", name))
+}
@@ -1,52 +1,51 @@
 package helper
 
 import (
-	"html/template"
-	"log"
-	"net/smtp"
-	"os"
+	"context"
+	"embed"
+	htmltemplate "html/template"
 	"strings"
+	texttemplate "text/template"
+)
+
+//go:embed format.html format.txt
+var templates embed.FS
+
+var (
+	htmlTemplate = htmltemplate.Must(htmltemplate.ParseFS(templates, "format.html"))
+	textTemplate = texttemplate.Must(texttemplate.ParseFS(templates, "format.txt"))
 )
 
 type Params struct {
-	From     string
-	Password string
 	To       string
 	Message  string
 	Code     string
+	Link     string
 	UserName string
 }
 
-func SendVerificationCode(params Params) error {
-	htmlFile, err := os.ReadFile("helper/format.html")
-	if err != nil {
-		log.Println("Cannot read html file", err.Error())
-		return err
-	}
-	temp, err := template.New("email").Parse(string(htmlFile))
+// SendVerificationCode renders the code email for params and sends it with m.
+func SendVerificationCode(ctx context.Context, m Mailer, params Params) error {
+	msg, err := RenderCode(params)
 	if err != nil {
-		log.Println("Cannot parse html file")
 		return err
 	}
+	return m.Send(ctx, msg)
+}
 
-	var Builder strings.Builder
-	err = temp.Execute(&Builder, params)
-	if err != nil {
-		log.Println("Cannot execute HTML", err.Error())
-		return err
+// RenderCode renders the text and HTML bodies of a code or link email.
+func RenderCode(params Params) (Message, error) {
+	var html, text strings.Builder
+	if err := htmlTemplate.Execute(&html, params); err != nil {
+		return Message{}, err
 	}
-
-	message := "From: " + params.From + "\n" + "To: " + params.To + "\n" + "Message: " + params.Message + "\n" + "MIME-Version: 1.0\n" + "Content-type: text/html; charset=\"UTF-8\"\n" + "\n" + Builder.String()
-
-	err = smtp.SendMail("smtp.gmail.com:587",
-		smtp.PlainAuth("smtp", params.From, params.Password, "smtp.gmail.com"),
-		params.From, []string{params.To}, []byte(message),
-	)
-
-	if err != nil {
-		log.Println("Could not send an email", err.Error())
-		return err
+	if err := textTemplate.Execute(&text, params); err != nil {
+		return Message{}, err
 	}
-
-	return nil
+	return Message{
+		To:      params.To,
+		Subject: params.Message,
+		Text:    text.String(),
+		HTML:    html.String(),
+	}, nil
 }
@@ -0,0 +1,45 @@
+package helper
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"blog-auth/internal/config"
+)
+
+// Message is a single outgoing email. Either Text or HTML may be empty; when
+// both are set the message is sent as multipart/alternative.
+type Message struct {
+	To      string
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// Mailer delivers email messages.
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// NewMailer builds the mailer selected by MAILDRIVER: "smtp" (default),
+// "file" for a local maildir, or "memory".
+func NewMailer(cfg config.Config) (Mailer, error) {
+	switch cfg.MAILDRIVER {
+	case "", "smtp":
+		return NewSMTPMailer(SMTPConfig{
+			Host:     cfg.SMTPHOST,
+			Port:     strconv.Itoa(cfg.SMTPPORT),
+			Username: cfg.EMAIL,
+			Password: cfg.EMAILSECREDKEY,
+			From:     cfg.EMAIL,
+			TLS:      cfg.SMTPTLS,
+		})
+	case "file":
+		return NewFileMailer(cfg.MAILDIR, cfg.EMAIL)
+	case "memory":
+		return NewMemoryMailer(cfg.EMAIL), nil
+	default:
+		return nil, fmt.Errorf("unknown mail driver %q", cfg.MAILDRIVER)
+	}
+}
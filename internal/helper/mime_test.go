@@ -0,0 +1,120 @@
+package helper
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"strings"
+	"testing"
+)
+
+func TestBuildMIME(t *testing.T) {
+	msg := Message{
+		To:      "Ann <ann@example.com>",
+		Subject: "Código de verificación",
+		Text:    "Your code is 123456",
+		HTML:    "<p>Your code is <b>123456</b></p>",
+	}
+	raw, err := buildMIME("Blog <noreply@blog.example.com>", msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if to, err := parsed.Header.AddressList("To"); err != nil || to[0].Address != "ann@example.com" {
+		t.Errorf("To = %v, %v", to, err)
+	}
+	if from, err := parsed.Header.AddressList("From"); err != nil || from[0].Address != "noreply@blog.example.com" {
+		t.Errorf("From = %v, %v", from, err)
+	}
+	subject, err := new(mime.WordDecoder).DecodeHeader(parsed.Header.Get("Subject"))
+	if err != nil || subject != msg.Subject {
+		t.Errorf("Subject = %q, %v", subject, err)
+	}
+	if id := parsed.Header.Get("Message-Id"); !strings.HasSuffix(id, "@blog.example.com>") {
+		t.Errorf("Message-ID = %q", id)
+	}
+
+	mediaType, params, err := mime.ParseMediaType(parsed.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/alternative" {
+		t.Fatalf("Content-Type = %q, %v", mediaType, err)
+	}
+	reader := multipart.NewReader(parsed.Body, params["boundary"])
+	bodies := map[string]string{}
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, err := io.ReadAll(quotedprintable.NewReader(part))
+		if err != nil {
+			t.Fatal(err)
+		}
+		contentType, _, _ := mime.ParseMediaType(part.Header.Get("Content-Type"))
+		bodies[contentType] = string(body)
+	}
+	if bodies["text/plain"] != msg.Text || bodies["text/html"] != msg.HTML {
+		t.Errorf("bodies = %q", bodies)
+	}
+}
+
+func TestBuildMIMETextOnly(t *testing.T) {
+	raw, err := buildMIME("noreply@blog.example.com", Message{To: "ann@example.com", Subject: "Hi", Text: "Hello"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(raw, []byte("text/html")) {
+		t.Error("message has an empty HTML part")
+	}
+}
+
+func TestBuildMIMERejectsHeaderInjection(t *testing.T) {
+	for name, msg := range map[string]Message{
+		"to":      {To: "ann@example.com\r\nBcc: eve@example.com", Subject: "Hi", Text: "Hello"},
+		"subject": {To: "ann@example.com", Subject: "Hi\r\nBcc: eve@example.com", Text: "Hello"},
+		"invalid": {To: "not an address", Subject: "Hi", Text: "Hello"},
+	} {
+		if _, err := buildMIME("noreply@blog.example.com", msg); err == nil {
+			t.Errorf("%s: message built", name)
+		}
+	}
+	if _, err := buildMIME("noreply\r\nBcc: eve@example.com", Message{To: "ann@example.com", Subject: "Hi"}); err == nil {
+		t.Error("from: message built")
+	}
+}
+
+func TestMemoryMailer(t *testing.T) {
+	m := NewMemoryMailer("noreply@blog.example.com")
+	err := SendVerificationCode(context.Background(), m, Params{
+		To:       "ann@example.com",
+		Message:  "Your email verification code",
+		Code:     "123456",
+		UserName: "Ann",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Send(context.Background(), Message{To: "ann@example.com\nBcc: eve@example.com"}); err == nil {
+		t.Error("MemoryMailer sent a message that does not build")
+	}
+
+	sent := m.Messages()
+	if len(sent) != 1 {
+		t.Fatalf("sent %d messages, want 1", len(sent))
+	}
+	if sent[0].To != "ann@example.com" || sent[0].Subject != "Your email verification code" {
+		t.Errorf("message = %+v", sent[0])
+	}
+	if !strings.Contains(sent[0].Text, "123456") || !strings.Contains(sent[0].HTML, "123456") {
+		t.Error("code missing from the bodies")
+	}
+}
@@ -0,0 +1,303 @@
+package idp
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"blog-auth/internal/config"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// ErrLoginRejected means the provider turned the login down or sent an
+// id_token that does not verify. Other errors mean it could not be reached.
+var ErrLoginRejected = errors.New("identity provider rejected the login")
+
+// ProviderConfig is one upstream provider in the IDPROVIDERS file, which holds
+// a JSON array of them. Values may reference environment variables as
+// ${NAME}, so client secrets can stay out of the file.
+type ProviderConfig struct {
+	Name         string   `json:"name"`
+	DisplayName  string   `json:"display_name"`
+	Issuer       string   `json:"issuer"`
+	ClientID     string   `json:"client_id"`
+	ClientSecret string   `json:"client_secret"`
+	Scopes       []string `json:"scopes"`
+	// Signup lets people without an account create one through the
+	// provider. Otherwise only existing accounts can be linked.
+	Signup bool `json:"signup"`
+}
+
+// Claims are the id_token claims a login needs.
+type Claims struct {
+	Email           string `json:"email"`
+	EmailVerified   bool   `json:"email_verified"`
+	Name            string `json:"name"`
+	GivenName       string `json:"given_name"`
+	FamilyName      string `json:"family_name"`
+	Picture         string `json:"picture"`
+	Nonce           string `json:"nonce"`
+	AuthorizedParty string `json:"azp"`
+	jwt.RegisteredClaims
+}
+
+// Registry holds the configured providers in file order, and Telegram if a
+// bot token is set.
+type Registry struct {
+	providers []*Provider
+	telegram  *Telegram
+}
+
+func New(cfg config.Config) (*Registry, error) {
+	r := &Registry{}
+	if cfg.TELEGRAMTOKEN != "" {
+		secret := sha256.Sum256([]byte(cfg.TELEGRAMTOKEN))
+		r.telegram = &Telegram{secret: secret[:], maxAge: cfg.TELEGRAMMAXAGE}
+	}
+	if cfg.IDPROVIDERS == "" {
+		return r, nil
+	}
+	data, err := os.ReadFile(cfg.IDPROVIDERS)
+	if err != nil {
+		return nil, err
+	}
+	var configs []ProviderConfig
+	if err := json.Unmarshal([]byte(os.ExpandEnv(string(data))), &configs); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", cfg.IDPROVIDERS, err)
+	}
+	client := &http.Client{Timeout: 10 * time.Second}
+	for _, c := range configs {
+		if c.Name == "" || c.Issuer == "" || c.ClientID == "" {
+			return nil, fmt.Errorf("identity provider %q needs a name, issuer and client_id", c.Name)
+		}
+		if c.Name == TelegramProvider {
+			return nil, fmt.Errorf("identity provider name %q is reserved", c.Name)
+		}
+		if _, ok := r.Get(c.Name); ok {
+			return nil, fmt.Errorf("identity provider %q is configured twice", c.Name)
+		}
+		if c.DisplayName == "" {
+			c.DisplayName = c.Name
+		}
+		if len(c.Scopes) == 0 {
+			c.Scopes = []string{"openid", "email", "profile"}
+		}
+		if !contains(c.Scopes, "openid") {
+			c.Scopes = append([]string{"openid"}, c.Scopes...)
+		}
+		r.providers = append(r.providers, &Provider{cfg: c, redirectURI: cfg.IDPCALLBACKURL, client: client})
+	}
+	return r, nil
+}
+
+func (r *Registry) Get(name string) (*Provider, bool) {
+	for _, p := range r.providers {
+		if p.cfg.Name == name {
+			return p, true
+		}
+	}
+	return nil, false
+}
+
+func (r *Registry) Providers() []*Provider {
+	return r.providers
+}
+
+func (r *Registry) Telegram() (*Telegram, bool) {
+	return r.telegram, r.telegram != nil
+}
+
+// Provider signs users in with the authorization code flow and PKCE. Its
+// endpoints come from the discovery document, fetched on first use so the
+// server starts even while a provider is down.
+type Provider struct {
+	cfg         ProviderConfig
+	redirectURI string
+	client      *http.Client
+
+	mu        sync.Mutex
+	metadata  *metadata
+	keys      map[string]interface{}
+	keysFetch time.Time
+}
+
+type metadata struct {
+	Issuer                string   `json:"issuer"`
+	AuthorizationEndpoint string   `json:"authorization_endpoint"`
+	TokenEndpoint         string   `json:"token_endpoint"`
+	JWKSURI               string   `json:"jwks_uri"`
+	TokenAuthMethods      []string `json:"token_endpoint_auth_methods_supported"`
+}
+
+func (p *Provider) Name() string        { return p.cfg.Name }
+func (p *Provider) DisplayName() string { return p.cfg.DisplayName }
+func (p *Provider) Signup() bool        { return p.cfg.Signup }
+
+// AuthCodeURL returns where to send the user to sign in. The provider hands
+// state back with the code; nonce comes back in the id_token and verifier
+// must be presented with the code.
+func (p *Provider) AuthCodeURL(ctx context.Context, state, nonce, verifier string) (string, error) {
+	md, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+	u, err := url.Parse(md.AuthorizationEndpoint)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(verifier))
+	q := u.Query()
+	q.Set("response_type", "code")
+	q.Set("client_id", p.cfg.ClientID)
+	q.Set("redirect_uri", p.redirectURI)
+	q.Set("scope", strings.Join(p.cfg.Scopes, " "))
+	q.Set("state", state)
+	q.Set("nonce", nonce)
+	q.Set("code_challenge", base64.RawURLEncoding.EncodeToString(sum[:]))
+	q.Set("code_challenge_method", "S256")
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
+
+// Exchange redeems a code for an id_token and returns its verified claims.
+func (p *Provider) Exchange(ctx context.Context, code, verifier, nonce string) (*Claims, error) {
+	md, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {p.redirectURI},
+		"code_verifier": {verifier},
+	}
+	// client_secret_basic is the default unless the provider only lists
+	// client_secret_post. Public clients just name themselves.
+	basic := p.cfg.ClientSecret != "" && (len(md.TokenAuthMethods) == 0 || contains(md.TokenAuthMethods, "client_secret_basic"))
+	if !basic {
+		form.Set("client_id", p.cfg.ClientID)
+		if p.cfg.ClientSecret != "" {
+			form.Set("client_secret", p.cfg.ClientSecret)
+		}
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, md.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if basic {
+		req.SetBasicAuth(url.QueryEscape(p.cfg.ClientID), url.QueryEscape(p.cfg.ClientSecret))
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	var body struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil && resp.StatusCode == http.StatusOK {
+		return nil, fmt.Errorf("decode token response: %w", err)
+	}
+	if resp.StatusCode >= 400 && resp.StatusCode < 500 && body.Error != "" {
+		return nil, fmt.Errorf("%w: %s %s", ErrLoginRejected, body.Error, body.ErrorDescription)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("token endpoint returned %s", resp.Status)
+	}
+	if body.IDToken == "" {
+		return nil, fmt.Errorf("%w: no id_token in token response", ErrLoginRejected)
+	}
+	return p.verify(ctx, md, body.IDToken, nonce)
+}
+
+// verify checks an id_token as OpenID Connect Core 3.1.3.7 asks: signed with
+// a key of the provider, issued by it for this client, current, and carrying
+// the nonce of the login.
+func (p *Provider) verify(ctx context.Context, md *metadata, raw, nonce string) (*Claims, error) {
+	claims := &Claims{}
+	_, err := jwt.ParseWithClaims(raw, claims, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		return p.key(ctx, md.JWKSURI, kid)
+	},
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512", "EdDSA"}),
+		jwt.WithIssuer(md.Issuer),
+		jwt.WithAudience(p.cfg.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(time.Minute),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrLoginRejected, err)
+	}
+	if len(claims.Audience) > 1 && claims.AuthorizedParty != p.cfg.ClientID {
+		return nil, fmt.Errorf("%w: id_token was issued to another party", ErrLoginRejected)
+	}
+	if subtle.ConstantTimeCompare([]byte(claims.Nonce), []byte(nonce)) != 1 {
+		return nil, fmt.Errorf("%w: id_token nonce does not match", ErrLoginRejected)
+	}
+	if claims.Subject == "" {
+		return nil, fmt.Errorf("%w: id_token has no subject", ErrLoginRejected)
+	}
+	return claims, nil
+}
+
+func (p *Provider) discover(ctx context.Context) (*metadata, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.metadata != nil {
+		return p.metadata, nil
+	}
+	md := &metadata{}
+	if err := p.get(ctx, strings.TrimSuffix(p.cfg.Issuer, "/")+"/.well-known/openid-configuration", md); err != nil {
+		return nil, fmt.Errorf("discover %s: %w", p.cfg.Name, err)
+	}
+	if md.Issuer != p.cfg.Issuer {
+		return nil, fmt.Errorf("discover %s: document is for issuer %q", p.cfg.Name, md.Issuer)
+	}
+	if md.AuthorizationEndpoint == "" || md.TokenEndpoint == "" || md.JWKSURI == "" {
+		return nil, fmt.Errorf("discover %s: document lacks an endpoint", p.cfg.Name)
+	}
+	p.metadata = md
+	return md, nil
+}
+
+func (p *Provider) get(ctx context.Context, url string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s returned %s", url, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
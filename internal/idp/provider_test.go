@@ -0,0 +1,217 @@
+package idp
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	testClientID     = "blog-auth"
+	testClientSecret = "secret"
+	testRedirectURI  = "https://auth.example.com/callback"
+	testCode         = "code-1"
+	testVerifier     = "verifier-1"
+	testNonce        = "nonce-1"
+)
+
+// mockProvider is an OpenID provider serving discovery, a JWKS and a token
+// endpoint that answers the one code it knows with idToken.
+type mockProvider struct {
+	server  *httptest.Server
+	key     *rsa.PrivateKey
+	idToken string
+}
+
+func newMockProvider(t *testing.T) *mockProvider {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := &mockProvider{key: key}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"issuer":                 m.issuer(),
+			"authorization_endpoint": m.issuer() + "/authorize",
+			"token_endpoint":         m.issuer() + "/token",
+			"jwks_uri":               m.issuer() + "/jwks",
+		})
+	})
+	mux.HandleFunc("GET /jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"kid": "k1",
+				"use": "sig",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	})
+	mux.HandleFunc("POST /token", func(w http.ResponseWriter, r *http.Request) {
+		id, secret, _ := r.BasicAuth()
+		id, _ = url.QueryUnescape(id)
+		secret, _ = url.QueryUnescape(secret)
+		if id != testClientID || secret != testClientSecret {
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_client"})
+			return
+		}
+		if r.PostFormValue("code") != testCode || r.PostFormValue("code_verifier") != testVerifier ||
+			r.PostFormValue("redirect_uri") != testRedirectURI {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"id_token": m.idToken, "token_type": "Bearer"})
+	})
+	m.server = httptest.NewServer(mux)
+	t.Cleanup(m.server.Close)
+	return m
+}
+
+func (m *mockProvider) issuer() string {
+	return m.server.URL
+}
+
+func (m *mockProvider) provider() *Provider {
+	return &Provider{
+		cfg: ProviderConfig{
+			Name:         "mock",
+			Issuer:       m.issuer(),
+			ClientID:     testClientID,
+			ClientSecret: testClientSecret,
+			Scopes:       []string{"openid", "email"},
+		},
+		redirectURI: testRedirectURI,
+		client:      m.server.Client(),
+	}
+}
+
+// claims returns id_token claims that verify; tests break one of them.
+func (m *mockProvider) claims() *Claims {
+	now := time.Now()
+	return &Claims{
+		Email:         "ann@example.com",
+		EmailVerified: true,
+		Nonce:         testNonce,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    m.issuer(),
+			Subject:   "user-1",
+			Audience:  jwt.ClaimStrings{testClientID},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(5 * time.Minute)),
+		},
+	}
+}
+
+func (m *mockProvider) sign(t *testing.T, claims *Claims) {
+	t.Helper()
+	tok := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	tok.Header["kid"] = "k1"
+	raw, err := tok.SignedString(m.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.idToken = raw
+}
+
+func TestExchange(t *testing.T) {
+	m := newMockProvider(t)
+	m.sign(t, m.claims())
+
+	claims, err := m.provider().Exchange(context.Background(), testCode, testVerifier, testNonce)
+	if err != nil {
+		t.Fatalf("Exchange: %v", err)
+	}
+	if claims.Subject != "user-1" || claims.Email != "ann@example.com" || !claims.EmailVerified {
+		t.Errorf("claims = %+v", claims)
+	}
+}
+
+func TestExchangeRejects(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(m *mockProvider, c *Claims)
+		code   string
+	}{
+		{"issuer", func(m *mockProvider, c *Claims) { c.Issuer = "https://evil.example.com" }, testCode},
+		{"audience", func(m *mockProvider, c *Claims) { c.Audience = jwt.ClaimStrings{"other-client"} }, testCode},
+		{"authorized party", func(m *mockProvider, c *Claims) {
+			c.Audience = jwt.ClaimStrings{testClientID, "other-client"}
+			c.AuthorizedParty = "other-client"
+		}, testCode},
+		{"nonce", func(m *mockProvider, c *Claims) { c.Nonce = "nonce-2" }, testCode},
+		{"expired", func(m *mockProvider, c *Claims) {
+			c.IssuedAt = jwt.NewNumericDate(time.Now().Add(-time.Hour))
+			c.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-10 * time.Minute))
+		}, testCode},
+		{"no expiry", func(m *mockProvider, c *Claims) { c.ExpiresAt = nil }, testCode},
+		{"no subject", func(m *mockProvider, c *Claims) { c.Subject = "" }, testCode},
+		{"unknown code", func(m *mockProvider, c *Claims) {}, "code-2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newMockProvider(t)
+			claims := m.claims()
+			tt.modify(m, claims)
+			m.sign(t, claims)
+
+			_, err := m.provider().Exchange(context.Background(), tt.code, testVerifier, testNonce)
+			if !errors.Is(err, ErrLoginRejected) {
+				t.Fatalf("Exchange error = %v, want ErrLoginRejected", err)
+			}
+		})
+	}
+}
+
+func TestExchangeRejectsForeignKey(t *testing.T) {
+	m := newMockProvider(t)
+	other, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tok := jwt.NewWithClaims(jwt.SigningMethodRS256, m.claims())
+	tok.Header["kid"] = "k1"
+	if m.idToken, err = tok.SignedString(other); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = m.provider().Exchange(context.Background(), testCode, testVerifier, testNonce)
+	if !errors.Is(err, ErrLoginRejected) {
+		t.Fatalf("Exchange error = %v, want ErrLoginRejected", err)
+	}
+}
+
+func TestAuthCodeURL(t *testing.T) {
+	m := newMockProvider(t)
+	raw, err := m.provider().AuthCodeURL(context.Background(), "state-1", testNonce, testVerifier)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := u.Query()
+	if u.Path != "/authorize" || q.Get("client_id") != testClientID || q.Get("state") != "state-1" ||
+		q.Get("nonce") != testNonce || q.Get("code_challenge_method") != "S256" {
+		t.Errorf("AuthCodeURL = %s", raw)
+	}
+	if q.Get("code_challenge") == "" || q.Get("code_challenge") == testVerifier {
+		t.Errorf("code_challenge = %q", q.Get("code_challenge"))
+	}
+}
@@ -0,0 +1,110 @@
+package idp
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testBotToken = "123456:bot-token"
+
+func itoa(n int64) string {
+	return strconv.FormatInt(n, 10)
+}
+
+func newTestTelegram() *Telegram {
+	secret := sha256.Sum256([]byte(testBotToken))
+	return &Telegram{secret: secret[:], maxAge: time.Hour}
+}
+
+// signTelegram hashes fields the way the widget does.
+func signTelegram(fields map[string]string) string {
+	lines := make([]string, 0, len(fields))
+	for key, value := range fields {
+		lines = append(lines, key+"="+value)
+	}
+	sort.Strings(lines)
+	secret := sha256.Sum256([]byte(testBotToken))
+	mac := hmac.New(sha256.New, secret[:])
+	mac.Write([]byte(strings.Join(lines, "\n")))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestTelegramVerify(t *testing.T) {
+	telegram := newTestTelegram()
+	now := time.Now().Unix()
+	fields := map[string]string{
+		"id":         "42",
+		"first_name": "Ann",
+		"username":   "ann",
+		"auth_date":  itoa(now),
+	}
+	login := TelegramLogin{ID: 42, FirstName: "Ann", Username: "ann", AuthDate: now, Hash: signTelegram(fields)}
+	if err := telegram.Verify(login); err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+
+	tampered := login
+	tampered.FirstName = "Eve"
+	if err := telegram.Verify(tampered); err != ErrTelegramInvalid {
+		t.Errorf("tampered field: Verify = %v", err)
+	}
+	otherID := login
+	otherID.ID = 43
+	if err := telegram.Verify(otherID); err != ErrTelegramInvalid {
+		t.Errorf("tampered id: Verify = %v", err)
+	}
+	garbage := login
+	garbage.Hash = "not hex"
+	if err := telegram.Verify(garbage); err != ErrTelegramInvalid {
+		t.Errorf("malformed hash: Verify = %v", err)
+	}
+}
+
+func TestTelegramVerifyAuthDate(t *testing.T) {
+	telegram := newTestTelegram()
+	for name, date := range map[string]time.Time{
+		"stale":  time.Now().Add(-2 * time.Hour),
+		"future": time.Now().Add(10 * time.Minute),
+	} {
+		fields := map[string]string{"id": "42", "auth_date": itoa(date.Unix())}
+		login := TelegramLogin{ID: 42, AuthDate: date.Unix(), Hash: signTelegram(fields)}
+		if err := telegram.Verify(login); err != ErrTelegramInvalid {
+			t.Errorf("%s auth_date: Verify = %v", name, err)
+		}
+	}
+	// A little clock skew is tolerated.
+	date := time.Now().Add(30 * time.Second)
+	fields := map[string]string{"id": "42", "auth_date": itoa(date.Unix())}
+	if err := telegram.Verify(TelegramLogin{ID: 42, AuthDate: date.Unix(), Hash: signTelegram(fields)}); err != nil {
+		t.Errorf("auth_date within the skew: Verify = %v", err)
+	}
+}
+
+func TestTelegramVerifyOtherFields(t *testing.T) {
+	telegram := newTestTelegram()
+	now := time.Now().Unix()
+	fields := map[string]string{"id": "42", "auth_date": itoa(now), "allows_write_to_pm": "true"}
+	login := TelegramLogin{ID: 42, AuthDate: now, Hash: signTelegram(fields), Other: map[string]string{"allows_write_to_pm": "true"}}
+	if err := telegram.Verify(login); err != nil {
+		t.Fatalf("Verify with an extra field: %v", err)
+	}
+
+	dropped := login
+	dropped.Other = nil
+	if err := telegram.Verify(dropped); err != ErrTelegramInvalid {
+		t.Errorf("extra field left out: Verify = %v", err)
+	}
+	for _, key := range []string{"id", "first_name", "hash"} {
+		repeated := login
+		repeated.Other = map[string]string{"allows_write_to_pm": "true", key: "1"}
+		if err := telegram.Verify(repeated); err != ErrTelegramInvalid {
+			t.Errorf("%s repeated in the other fields: Verify = %v", key, err)
+		}
+	}
+}
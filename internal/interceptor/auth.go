@@ -0,0 +1,260 @@
+package interceptor
+
+import (
+	"context"
+	"strings"
+
+	pb "blog-auth/genproto/userservice"
+	logger "blog-auth/internal/logger"
+	"blog-auth/internal/revocation"
+	"blog-auth/internal/token"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	RoleUser       = "user"
+	RoleAdmin      = "admin"
+	RoleSuperAdmin = "super-admin"
+)
+
+// Policy describes who may call an RPC.
+type Policy struct {
+	// Public methods can be called without a token.
+	Public bool
+	// Roles that may call the method. Empty means any authenticated user.
+	Roles []string
+	// Owner returns the id of the user a request acts on. When set, callers
+	// without one of Roles may still act on themselves.
+	Owner func(req interface{}) string
+	// Scopes lets tokens issued to OAuth clients and service accounts call
+	// the method if they carry one of them. Without Scopes only first-party
+	// tokens may. Service accounts are checked against Scopes alone.
+	Scopes []string
+	// ServiceOnly methods can only be called by service accounts, for
+	// backends rather than users.
+	ServiceOnly bool
+}
+
+const (
+	// ScopeUsersRead lets service accounts look users up.
+	ScopeUsersRead = "users:read"
+	// ScopeTokensIntrospect lets service accounts introspect tokens.
+	ScopeTokensIntrospect = "tokens:introspect"
+)
+
+const (
+	userService  = "/userservice.UserService/"
+	adminService = "/userservice.AdminService/"
+)
+
+var adminRoles = []string{RoleAdmin, RoleSuperAdmin}
+
+// Policies is the access table for every RPC the server exposes. Methods that
+// are missing from it are denied.
+var Policies = map[string]Policy{
+	userService + "RegisterUser":              {Public: true},
+	userService + "Login":                     {Public: true},
+	userService + "ForgotPassword":            {Public: true},
+	userService + "VerifyEmail":               {Public: true},
+	userService + "RefreshToken":              {Public: true},
+	userService + "ResetPassword":             {Public: true},
+	userService + "VerifyMFA":                 {Public: true},
+	userService + "BeginPasskeyLogin":         {Public: true},
+	userService + "FinishPasskeyLogin":        {Public: true},
+	userService + "RequestMagicLink":          {Public: true},
+	userService + "ConsumeMagicLink":          {Public: true},
+	userService + "ListIdentityProviders":     {Public: true},
+	userService + "BeginExternalLogin":        {Public: true},
+	userService + "FinishExternalLogin":       {Public: true},
+	userService + "LoginWithTelegram":         {Public: true},
+	userService + "IntrospectToken":           {ServiceOnly: true, Scopes: []string{ScopeTokensIntrospect}},
+	userService + "RevokeToken":               {Public: true},
+	userService + "GetJWKS":                   {Public: true},
+	userService + "UpdateUser":                {Roles: adminRoles, Owner: updateUserOwner},
+	userService + "EnrollTOTP":                {},
+	userService + "ConfirmTOTP":               {},
+	userService + "BeginPasskeyRegistration":  {},
+	userService + "FinishPasskeyRegistration": {},
+	userService + "CheckPermission":           {},
+	userService + "Enforce":                   {},
+	userService + "ListSessions":              {},
+	userService + "RevokeSession":             {},
+	userService + "RevokeAllOtherSessions":    {},
+	userService + "CreatePersonalAccessToken": {},
+	userService + "ListPersonalAccessTokens":  {},
+	userService + "RevokePersonalAccessToken": {},
+	userService + "CreateSignInCode":          {},
+
+	adminService + "CreateUser":           {Roles: adminRoles},
+	adminService + "GetUserByID":          {Roles: adminRoles, Scopes: []string{ScopeUsersRead}},
+	adminService + "ForgetPassword":       {Roles: adminRoles},
+	adminService + "GetAllUsers":          {Roles: adminRoles},
+	adminService + "DeleteUser":           {Roles: adminRoles},
+	adminService + "ListFailedEmails":     {Roles: adminRoles},
+	adminService + "ResendEmail":          {Roles: adminRoles},
+	adminService + "UnlockUser":           {Roles: adminRoles},
+	adminService + "ForceLogout":          {Roles: adminRoles},
+	adminService + "RotateSigningKey":     {Roles: []string{RoleSuperAdmin}},
+	adminService + "CreateOAuthClient":    {Roles: adminRoles},
+	adminService + "ListOAuthClients":     {Roles: adminRoles},
+	adminService + "DeleteOAuthClient":    {Roles: adminRoles},
+	adminService + "CreateServiceAccount": {Roles: adminRoles},
+	adminService + "ListServiceAccounts":  {Roles: adminRoles},
+	adminService + "DeleteServiceAccount": {Roles: adminRoles},
+	adminService + "CreateRole":           {Roles: adminRoles},
+	adminService + "DeleteRole":           {Roles: adminRoles},
+	adminService + "ListRoles":            {Roles: adminRoles},
+	adminService + "CreatePermission":     {Roles: adminRoles},
+	adminService + "ListPermissions":      {Roles: adminRoles},
+	adminService + "GrantPermission":      {Roles: adminRoles},
+	adminService + "RevokePermission":     {Roles: adminRoles},
+	adminService + "AssignRole":           {Roles: adminRoles},
+	adminService + "UnassignRole":         {Roles: adminRoles},
+}
+
+func updateUserOwner(req interface{}) string {
+	if r, ok := req.(*pb.UpdateUserReq); ok && r.UserReq != nil {
+		return r.UserReq.Id
+	}
+	return ""
+}
+
+type principalKey struct{}
+
+// WithPrincipal returns a copy of ctx carrying the authenticated caller.
+func WithPrincipal(ctx context.Context, claims *token.Claims) context.Context {
+	return context.WithValue(ctx, principalKey{}, claims)
+}
+
+// PrincipalFrom returns the caller the auth interceptor authenticated.
+func PrincipalFrom(ctx context.Context) (*token.Claims, bool) {
+	claims, ok := ctx.Value(principalKey{}).(*token.Claims)
+	return claims, ok
+}
+
+// HasScope reports whether claims were granted one of scopes.
+func HasScope(claims *token.Claims, scopes ...string) bool {
+	for _, granted := range strings.Fields(claims.Scope) {
+		for _, scope := range scopes {
+			if granted == scope {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// HasRole reports whether claims carry one of roles.
+func HasRole(claims *token.Claims, roles ...string) bool {
+	for _, role := range roles {
+		if claims.Role == role {
+			return true
+		}
+	}
+	return false
+}
+
+// Revoked reports whether the token or its session is on the denylist.
+func Revoked(ctx context.Context, denylist revocation.Denylist, claims *token.Claims) (bool, error) {
+	ids := []string{revocation.TokenKey(claims.ID)}
+	if claims.SessionID != "" {
+		ids = append(ids, revocation.SessionKey(claims.SessionID))
+	}
+	if claims.Service {
+		ids = append(ids, revocation.ServiceAccountKey(claims.ClientID))
+	}
+	return denylist.Revoked(ctx, ids...)
+}
+
+// checkRevoked rejects tokens on the denylist. If the denylist cannot be read
+// the token is not trusted either.
+func checkRevoked(ctx context.Context, denylist revocation.Denylist, claims *token.Claims) error {
+	revoked, err := Revoked(ctx, denylist, claims)
+	if err != nil {
+		if logs, lerr := logger.NewLogger(); lerr == nil {
+			logs.Error("Error checking token revocation", zap.Error(err))
+		}
+		return status.Error(codes.Unavailable, "cannot check token revocation")
+	}
+	if revoked {
+		return status.Error(codes.Unauthenticated, "access token has been revoked")
+	}
+	return nil
+}
+
+// PersonalTokens verifies personal access tokens. Errors are gRPC statuses.
+type PersonalTokens interface {
+	VerifyPersonalAccessToken(ctx context.Context, raw string) (*token.Claims, error)
+}
+
+// authenticate verifies a bearer token: a personal access token, or a signed
+// access token that is not revoked.
+func authenticate(ctx context.Context, tokens *token.Manager, denylist revocation.Denylist, pats PersonalTokens, raw string) (*token.Claims, error) {
+	if token.IsPersonalAccessToken(raw) {
+		return pats.VerifyPersonalAccessToken(ctx, raw)
+	}
+	claims, err := tokens.ParseAccessToken(raw)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, "invalid access token")
+	}
+	if err = checkRevoked(ctx, denylist, claims); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// Auth validates the bearer token of every call and enforces policies. The
+// principal is put in the context for handlers; on public methods a valid
+// token is attached too, but a missing, invalid or revoked one is ignored.
+func Auth(tokens *token.Manager, denylist revocation.Denylist, pats PersonalTokens, policies map[string]Policy) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		policy, ok := policies[info.FullMethod]
+		if !ok {
+			return nil, status.Error(codes.PermissionDenied, "method is not allowed")
+		}
+
+		var claims *token.Claims
+		if raw, ok := BearerToken(ctx); ok {
+			parsed, err := authenticate(ctx, tokens, denylist, pats, raw)
+			if err != nil && !policy.Public {
+				return nil, err
+			}
+			if err == nil {
+				claims = parsed
+				ctx = WithPrincipal(ctx, claims)
+			}
+		}
+		if policy.Public {
+			return handler(ctx, req)
+		}
+		if claims == nil {
+			return nil, status.Error(codes.Unauthenticated, "missing bearer token")
+		}
+		if policy.ServiceOnly && !claims.Service {
+			return nil, status.Error(codes.PermissionDenied, "only service accounts may call this method")
+		}
+		if claims.Service {
+			if HasScope(claims, policy.Scopes...) {
+				return handler(ctx, req)
+			}
+			return nil, status.Error(codes.PermissionDenied, "service account scope does not allow this method")
+		}
+		if (claims.ClientID != "" || claims.PersonalToken) && !HasScope(claims, policy.Scopes...) {
+			return nil, status.Error(codes.PermissionDenied, "token scope does not allow this method")
+		}
+
+		if len(policy.Roles) == 0 || HasRole(claims, policy.Roles...) {
+			return handler(ctx, req)
+		}
+		if policy.Owner != nil {
+			if owner := policy.Owner(req); owner != "" && owner == claims.UserID {
+				return handler(ctx, req)
+			}
+		}
+		return nil, status.Error(codes.PermissionDenied, "permission denied")
+	}
+}
@@ -0,0 +1,160 @@
+package interceptor
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	pb "blog-auth/genproto/userservice"
+	"blog-auth/internal/config"
+	"blog-auth/internal/revocation"
+	"blog-auth/internal/token"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// fakePATs knows a single personal access token.
+type fakePATs struct {
+	raw    string
+	claims token.Claims
+}
+
+func (f fakePATs) VerifyPersonalAccessToken(ctx context.Context, raw string) (*token.Claims, error) {
+	if raw != f.raw {
+		return nil, status.Error(codes.Unauthenticated, "invalid personal access token")
+	}
+	claims := f.claims
+	return &claims, nil
+}
+
+func TestAuth(t *testing.T) {
+	tokens := token.NewManager(config.Config{TOKENKEY: "test-key", TOKENALGO: token.AlgHS256, ACCESSTOKENTTL: time.Minute})
+	denylist := revocation.NewMemoryDenylist()
+	pat, _, err := token.NewPersonalAccessToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pats := fakePATs{raw: pat, claims: token.Claims{UserID: "user-1", Role: RoleAdmin, Scope: ScopeUsersRead, PersonalToken: true}}
+	auth := Auth(tokens, denylist, pats, Policies)
+
+	sign := func(claims token.Claims) string {
+		raw, err := tokens.GenerateAccessToken(claims)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return raw
+	}
+	user := sign(token.Claims{UserID: "user-1", Role: RoleUser, SessionID: "session-1"})
+	admin := sign(token.Claims{UserID: "admin-1", Role: RoleAdmin, SessionID: "session-2"})
+	client := sign(token.Claims{UserID: "user-1", Role: RoleAdmin, ClientID: "app", Scope: "openid " + ScopeUsersRead})
+	clientNoScope := sign(token.Claims{UserID: "user-1", Role: RoleAdmin, ClientID: "app", Scope: "openid"})
+	service := sign(token.Claims{UserID: "svc", Role: RoleAdmin, ClientID: "svc", Service: true, Scope: ScopeUsersRead})
+	introspector := sign(token.Claims{UserID: "svc-2", ClientID: "svc-2", Service: true, Scope: ScopeTokensIntrospect})
+	revoked := sign(token.Claims{UserID: "user-1", Role: RoleUser, SessionID: "session-revoked"})
+	if err := denylist.Revoke(context.Background(), revocation.SessionKey("session-revoked"), time.Minute); err != nil {
+		t.Fatal(err)
+	}
+
+	updateSelf := &pb.UpdateUserReq{UserReq: &pb.UserModel{Id: "user-1"}}
+	updateOther := &pb.UpdateUserReq{UserReq: &pb.UserModel{Id: "user-2"}}
+
+	tests := []struct {
+		name   string
+		method string
+		token  string
+		req    interface{}
+		want   codes.Code
+	}{
+		{"missing token", "ListSessions", "", nil, codes.Unauthenticated},
+		{"invalid token", "ListSessions", "not-a-token", nil, codes.Unauthenticated},
+		{"revoked session", "ListSessions", revoked, nil, codes.Unauthenticated},
+		{"unknown personal token", "ListSessions", token.PersonalTokenPrefix + "unknown", nil, codes.Unauthenticated},
+		{"any user", "ListSessions", user, nil, codes.OK},
+		{"wrong role", "/userservice.AdminService/GetAllUsers", user, nil, codes.PermissionDenied},
+		{"role", "/userservice.AdminService/GetAllUsers", admin, nil, codes.OK},
+		{"owner acts on themselves", "UpdateUser", user, updateSelf, codes.OK},
+		{"owner acts on another user", "UpdateUser", user, updateOther, codes.PermissionDenied},
+		{"role acts on another user", "UpdateUser", admin, updateOther, codes.OK},
+		{"service account with the scope", "/userservice.AdminService/GetUserByID", service, nil, codes.OK},
+		{"service account role is ignored", "/userservice.AdminService/GetAllUsers", service, nil, codes.PermissionDenied},
+		{"service account on a method without scopes", "ListSessions", service, nil, codes.PermissionDenied},
+		{"service only method", "IntrospectToken", introspector, nil, codes.OK},
+		{"service only method for a user", "IntrospectToken", admin, nil, codes.PermissionDenied},
+		{"service account without the scope", "IntrospectToken", service, nil, codes.PermissionDenied},
+		{"client with the scope and role", "/userservice.AdminService/GetUserByID", client, nil, codes.OK},
+		{"client without the scope", "/userservice.AdminService/GetUserByID", clientNoScope, nil, codes.PermissionDenied},
+		{"client on a first-party method", "ListSessions", client, nil, codes.PermissionDenied},
+		{"personal token with the scope and role", "/userservice.AdminService/GetUserByID", pat, nil, codes.OK},
+		{"personal token on a first-party method", "ListSessions", pat, nil, codes.PermissionDenied},
+		{"public method without a token", "Login", "", nil, codes.OK},
+		{"public method ignores a bad token", "Login", "not-a-token", nil, codes.OK},
+		{"public method ignores a revoked token", "Login", revoked, nil, codes.OK},
+		{"method without a policy", "DropTables", admin, nil, codes.PermissionDenied},
+	}
+	for _, tt := range tests {
+		method := tt.method
+		if method[0] != '/' {
+			method = userService + method
+		}
+		ctx := context.Background()
+		if tt.token != "" {
+			ctx = metadata.NewIncomingContext(ctx, metadata.Pairs("authorization", "Bearer "+tt.token))
+		}
+		called := false
+		handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+			called = true
+			return nil, nil
+		}
+		_, err := auth(ctx, tt.req, &grpc.UnaryServerInfo{FullMethod: method}, handler)
+		if got := status.Code(err); got != tt.want {
+			t.Errorf("%s: code = %v, want %v (%v)", tt.name, got, tt.want, err)
+		}
+		if called != (tt.want == codes.OK) {
+			t.Errorf("%s: handler called = %v", tt.name, called)
+		}
+	}
+}
+
+func TestAuthAttachesPrincipal(t *testing.T) {
+	tokens := token.NewManager(config.Config{TOKENKEY: "test-key", TOKENALGO: token.AlgHS256, ACCESSTOKENTTL: time.Minute})
+	auth := Auth(tokens, revocation.NewMemoryDenylist(), fakePATs{}, Policies)
+	raw, err := tokens.GenerateAccessToken(token.Claims{UserID: "user-1", Role: RoleUser})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, method := range []string{"Login", "ListSessions"} {
+		ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer "+raw))
+		var principal *token.Claims
+		_, err := auth(ctx, nil, &grpc.UnaryServerInfo{FullMethod: userService + method}, func(ctx context.Context, req interface{}) (interface{}, error) {
+			principal, _ = PrincipalFrom(ctx)
+			return nil, nil
+		})
+		if err != nil || principal == nil || principal.UserID != "user-1" {
+			t.Errorf("%s: principal = %+v, %v", method, principal, err)
+		}
+	}
+}
+
+func TestPoliciesCoverEveryMethod(t *testing.T) {
+	registered := map[string]bool{}
+	for _, desc := range []grpc.ServiceDesc{pb.UserService_ServiceDesc, pb.AdminService_ServiceDesc} {
+		for _, m := range desc.Methods {
+			method := "/" + desc.ServiceName + "/" + m.MethodName
+			registered[method] = true
+			if _, ok := Policies[method]; !ok {
+				t.Errorf("%s has no policy", method)
+			}
+		}
+	}
+	for method, policy := range Policies {
+		if !registered[method] {
+			t.Errorf("policy for %s, which is not a registered method", method)
+		}
+		if policy.Public && (len(policy.Roles) > 0 || policy.ServiceOnly) {
+			t.Errorf("%s is public but also restricted", method)
+		}
+	}
+}
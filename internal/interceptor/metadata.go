@@ -0,0 +1,156 @@
+package interceptor
+
+import (
+	"context"
+	"net"
+	"net/netip"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+// TrustedProxies are the addresses allowed to tell who the end user is in
+// x-forwarded-for, normally the gateway and any load balancer in front of it.
+type TrustedProxies []netip.Prefix
+
+// ParseTrustedProxies parses CIDR prefixes; a bare address trusts just itself.
+func ParseTrustedProxies(list []string) (TrustedProxies, error) {
+	var proxies TrustedProxies
+	for _, entry := range list {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if !strings.Contains(entry, "/") {
+			addr, err := netip.ParseAddr(entry)
+			if err != nil {
+				return nil, err
+			}
+			entry = netip.PrefixFrom(addr, addr.BitLen()).String()
+		}
+		prefix, err := netip.ParsePrefix(entry)
+		if err != nil {
+			return nil, err
+		}
+		proxies = append(proxies, prefix.Masked())
+	}
+	return proxies, nil
+}
+
+func (t TrustedProxies) trusts(addr string) bool {
+	ip, err := netip.ParseAddr(addr)
+	if err != nil {
+		return false
+	}
+	ip = ip.Unmap()
+	for _, prefix := range t {
+		if prefix.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// ClientAddr returns the end user's address for a request from remote that
+// carries the given x-forwarded-for values. The hops are walked back from
+// the nearest one only while they are trusted proxies, so an address the
+// client wrote into the header itself is never taken.
+func (t TrustedProxies) ClientAddr(remote string, forwarded []string) string {
+	var hops []string
+	for _, v := range forwarded {
+		for _, hop := range strings.Split(v, ",") {
+			if hop = strings.TrimSpace(hop); hop != "" {
+				hops = append(hops, hop)
+			}
+		}
+	}
+	addr := remote
+	for i := len(hops) - 1; i >= 0 && t.trusts(addr); i-- {
+		addr = hops[i]
+	}
+	return addr
+}
+
+type clientIPKey struct{}
+
+// WithClientIP returns a copy of ctx carrying the end user's address.
+func WithClientIP(ctx context.Context, ip string) context.Context {
+	return context.WithValue(ctx, clientIPKey{}, ip)
+}
+
+// ClientAddress resolves the end user's address once per call, honoring
+// x-forwarded-for only from proxies, and stores it for ClientIP. It must run
+// before the interceptors that throttle by address.
+func ClientAddress(proxies TrustedProxies) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		ip := proxies.ClientAddr(peerAddr(ctx), md.Get("x-forwarded-for"))
+		return handler(WithClientIP(ctx, ip), req)
+	}
+}
+
+// ClientIP returns the address of the end user as ClientAddress resolved it,
+// or the address of the peer for calls that did not pass through it.
+func ClientIP(ctx context.Context) string {
+	if ip, ok := ctx.Value(clientIPKey{}).(string); ok {
+		return ip
+	}
+	return peerAddr(ctx)
+}
+
+func peerAddr(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+	return host
+}
+
+// BearerToken extracts the token from the "authorization: Bearer <token>"
+// metadata of an incoming call.
+func BearerToken(ctx context.Context) (string, bool) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return "", false
+	}
+	for _, v := range md.Get("authorization") {
+		if len(v) > 7 && strings.EqualFold(v[:7], "bearer ") {
+			return strings.TrimSpace(v[7:]), true
+		}
+	}
+	return "", false
+}
+
+// UserAgent returns the user agent of the end user, preferring the one the
+// gateway forwards over its own.
+func UserAgent(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	for _, key := range []string{"grpcgateway-user-agent", "user-agent"} {
+		if v := md.Get(key); len(v) > 0 && v[0] != "" {
+			return v[0]
+		}
+	}
+	return ""
+}
+
+// DeviceName returns the name clients may give their device in the
+// x-device-name metadata, e.g. "Pixel 8" or "Work laptop".
+func DeviceName(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	if v := md.Get("x-device-name"); len(v) > 0 {
+		return strings.TrimSpace(v[0])
+	}
+	return ""
+}
@@ -0,0 +1,105 @@
+package interceptor
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	logger "blog-auth/internal/logger"
+	"blog-auth/internal/ratelimit"
+	"blog-auth/internal/token"
+
+	"go.uber.org/zap"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// RateLimit enforces the token bucket limits configured for each RPC. A call
+// that exceeds a limit fails with ResourceExhausted, a RetryInfo detail and a
+// retry-after header. If the backend is unavailable calls are let through, so
+// a Redis outage does not take logins down with it.
+func RateLimit(limiter ratelimit.Limiter, rules ratelimit.Rules, tokens *token.Manager) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		limits := rules.For(info.FullMethod)
+		if len(limits) == 0 {
+			return handler(ctx, req)
+		}
+		wait := Throttle(ctx, limiter, info.FullMethod, limits, func(key string) string {
+			return limitSubject(ctx, req, key, tokens)
+		})
+		if wait == 0 {
+			return handler(ctx, req)
+		}
+
+		seconds := int((wait + time.Second - 1) / time.Second)
+		grpc.SetHeader(ctx, metadata.Pairs("retry-after", strconv.Itoa(seconds)))
+		st := status.Newf(codes.ResourceExhausted, "rate limit exceeded, retry in %ds", seconds)
+		if detailed, err := st.WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(wait)}); err == nil {
+			st = detailed
+		}
+		return nil, st.Err()
+	}
+}
+
+// Throttle takes a token from each of limits for method and returns how long
+// the caller must wait, or zero if every limit allows the call. subject names
+// who a limit with the given key is counted against. Backend errors are
+// logged and do not block the call.
+func Throttle(ctx context.Context, limiter ratelimit.Limiter, method string, limits []ratelimit.Limit, subject func(key string) string) time.Duration {
+	logs, err := logger.NewLogger()
+	if err != nil {
+		return 0
+	}
+	var wait time.Duration
+	for _, limit := range limits {
+		key := method + ":" + limit.String() + ":" + subject(limit.Key)
+		ok, retry, err := limiter.Allow(ctx, key, limit)
+		if err != nil {
+			logs.Error("Error checking rate limit", zap.String("method", method), zap.Error(err))
+			continue
+		}
+		if !ok && retry > wait {
+			wait = retry
+		}
+	}
+	return wait
+}
+
+// limitSubject returns who a limit is counted against. Limits per user or
+// email fall back to the client IP when the call carries neither.
+func limitSubject(ctx context.Context, req interface{}, key string, tokens *token.Manager) string {
+	switch key {
+	case ratelimit.KeyUser:
+		if raw, ok := BearerToken(ctx); ok {
+			if claims, err := tokens.ParseAccessToken(raw); err == nil {
+				return "user:" + claims.UserID
+			}
+		}
+	case ratelimit.KeyEmail:
+		if email := requestEmail(req); email != "" {
+			return "email:" + email
+		}
+	}
+	return "ip:" + ClientIP(ctx)
+}
+
+// requestEmail returns the top-level "email" field of a request, if it has one.
+func requestEmail(req interface{}) string {
+	msg, ok := req.(proto.Message)
+	if !ok {
+		return ""
+	}
+	m := msg.ProtoReflect()
+	field := m.Descriptor().Fields().ByName("email")
+	if field == nil || field.Kind() != protoreflect.StringKind {
+		return ""
+	}
+	return strings.ToLower(strings.TrimSpace(m.Get(field).String()))
+}
@@ -0,0 +1,176 @@
+package keys
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"blog-auth/internal/config"
+	logger "blog-auth/internal/logger"
+	"blog-auth/internal/storage/postgres"
+	"blog-auth/internal/token"
+
+	"go.uber.org/zap"
+)
+
+const refreshInterval = time.Minute
+
+// reloadInterval bounds how often tokens naming an unknown kid make the key
+// set be read again, so made-up kids cannot hammer the database.
+const reloadInterval = 10 * time.Second
+
+var ErrSymmetricSigning = errors.New("access tokens are signed with a shared secret, there are no keys to rotate")
+
+// Rotator keeps the token manager's key set in sync with the signing_keys
+// table and rotates keys on schedule. The next key is created and published
+// KEYOVERLAP before the current one retires, and a retired key keeps
+// verifying for KEYOVERLAP (at least one access token lifetime) afterwards, so
+// verifiers caching the JWKS never see a token signed with an unknown key.
+type Rotator struct {
+	keys      postgres.SigningKeyStorage
+	tokens    *token.Manager
+	algorithm string
+	rotation  time.Duration
+	overlap   time.Duration
+}
+
+func NewRotator(keys postgres.SigningKeyStorage, tokens *token.Manager, cfg config.Config) *Rotator {
+	r := &Rotator{
+		keys:      keys,
+		tokens:    tokens,
+		algorithm: tokens.Algorithm(),
+		rotation:  cfg.KEYROTATION,
+		overlap:   cfg.KEYOVERLAP,
+	}
+	if r.overlap < tokens.AccessTTL() {
+		r.overlap = tokens.AccessTTL()
+	}
+	if r.rotation < 2*r.overlap {
+		r.rotation = 2 * r.overlap
+	}
+	if r.algorithm != token.AlgHS256 {
+		tokens.SetReloader(r.load, reloadInterval)
+	}
+	return r
+}
+
+// load installs the stored key set in the token manager as it is.
+func (r *Rotator) load(ctx context.Context) error {
+	keys, err := r.keys.ListSigningKeys(ctx)
+	if err != nil {
+		return err
+	}
+	r.tokens.SetKeys(keys)
+	return nil
+}
+
+// Run refreshes the key set until ctx is cancelled.
+func (r *Rotator) Run(ctx context.Context) {
+	logs, err := logger.NewLogger()
+	if err != nil {
+		return
+	}
+	if r.algorithm == token.AlgHS256 {
+		return
+	}
+	ticker := time.NewTicker(refreshInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if err := r.Refresh(ctx); err != nil {
+			logs.Error("Error refreshing signing keys", zap.Error(err))
+		}
+	}
+}
+
+// Refresh creates the keys the schedule calls for and installs the current
+// set in the token manager.
+func (r *Rotator) Refresh(ctx context.Context) error {
+	if r.algorithm == token.AlgHS256 {
+		return nil
+	}
+	keys, err := r.keys.ListSigningKeys(ctx)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	var active, next *token.SigningKey
+	for i := range keys {
+		key := &keys[i]
+		if key.Algorithm != r.algorithm {
+			continue
+		}
+		if !now.Before(key.ActivatesAt) && now.Before(key.RetiresAt) && (active == nil || key.ActivatesAt.After(active.ActivatesAt)) {
+			active = key
+		}
+		if now.Before(key.ActivatesAt) {
+			next = key
+		}
+	}
+
+	var at time.Time
+	switch {
+	case active == nil:
+		at = now
+	case next == nil && active.RetiresAt.Sub(now) <= r.overlap:
+		at = active.RetiresAt
+	}
+	if !at.IsZero() {
+		key, err := r.newKey(at)
+		if err != nil {
+			return err
+		}
+		if _, err = r.keys.AddSigningKey(ctx, key); err != nil {
+			return err
+		}
+		if keys, err = r.keys.ListSigningKeys(ctx); err != nil {
+			return err
+		}
+	}
+	r.tokens.SetKeys(keys)
+	return nil
+}
+
+// Rotate replaces the signing key. The new key starts signing one refresh
+// interval from now, once every replica has loaded it, and the current key
+// signs until then. In an emergency, when a key may have leaked, the new key
+// signs right away and the old keys stop verifying immediately as well, which
+// invalidates every access token signed with them. Other replicas load the
+// new key when its first token reaches them.
+func (r *Rotator) Rotate(ctx context.Context, emergency bool) (token.SigningKey, error) {
+	if r.algorithm == token.AlgHS256 {
+		return token.SigningKey{}, ErrSymmetricSigning
+	}
+	now := time.Now()
+	activatesAt := now.Add(refreshInterval)
+	if emergency {
+		activatesAt = now
+	}
+	key, err := r.newKey(activatesAt)
+	if err != nil {
+		return token.SigningKey{}, err
+	}
+	expireOthersAt := activatesAt.Add(r.overlap)
+	if emergency {
+		expireOthersAt = now
+	}
+	if err = r.keys.ReplaceSigningKeys(ctx, key, expireOthersAt); err != nil {
+		return token.SigningKey{}, err
+	}
+	return key, r.Refresh(ctx)
+}
+
+func (r *Rotator) newKey(activatesAt time.Time) (token.SigningKey, error) {
+	key, err := token.GenerateSigningKey(r.algorithm)
+	if err != nil {
+		return token.SigningKey{}, err
+	}
+	key.ActivatesAt = activatesAt
+	key.RetiresAt = activatesAt.Add(r.rotation)
+	key.ExpiresAt = key.RetiresAt.Add(r.overlap)
+	return key, nil
+}
@@ -0,0 +1,144 @@
+package mfa
+
+import (
+	"encoding/base32"
+	"net/url"
+	"regexp"
+	"testing"
+	"time"
+)
+
+// rfc6238Secret is the SHA-1 key of the RFC 6238 test vectors.
+var rfc6238Secret = base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+
+func TestValidateTOTPVectors(t *testing.T) {
+	// RFC 6238 Appendix B, truncated to six digits.
+	vectors := []struct {
+		unix int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+	for _, v := range vectors {
+		step, ok := ValidateTOTP(rfc6238Secret, v.code, time.Unix(v.unix, 0))
+		if !ok {
+			t.Errorf("ValidateTOTP(%d, %s) rejected the code", v.unix, v.code)
+			continue
+		}
+		if step != v.unix/totpPeriod {
+			t.Errorf("ValidateTOTP(%d) step = %d, want %d", v.unix, step, v.unix/totpPeriod)
+		}
+	}
+}
+
+func TestValidateTOTPSkew(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	key, _ := b32.DecodeString(rfc6238Secret)
+	step := now.Unix() / totpPeriod
+
+	for offset := int64(-totpSkew); offset <= totpSkew; offset++ {
+		if got, ok := ValidateTOTP(rfc6238Secret, hotp(key, step+offset), now); !ok || got != step+offset {
+			t.Errorf("offset %d: ValidateTOTP = %d, %v", offset, got, ok)
+		}
+	}
+	for _, offset := range []int64{-totpSkew - 1, totpSkew + 1} {
+		if _, ok := ValidateTOTP(rfc6238Secret, hotp(key, step+offset), now); ok {
+			t.Errorf("offset %d: code outside the window accepted", offset)
+		}
+	}
+}
+
+func TestValidateTOTPRejectsMalformed(t *testing.T) {
+	now := time.Now()
+	for _, tc := range []struct{ secret, code string }{
+		{rfc6238Secret, ""},
+		{rfc6238Secret, "12345"},
+		{rfc6238Secret, "1234567"},
+		{"not base32!", "123456"},
+	} {
+		if _, ok := ValidateTOTP(tc.secret, tc.code, now); ok {
+			t.Errorf("ValidateTOTP(%q, %q) accepted", tc.secret, tc.code)
+		}
+	}
+}
+
+func TestNewTOTPSecret(t *testing.T) {
+	secret, err := NewTOTPSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := b32.DecodeString(secret)
+	if err != nil || len(key) != 20 {
+		t.Errorf("NewTOTPSecret = %q: %d bytes, %v", secret, len(key), err)
+	}
+	code := hotp(key, time.Now().Unix()/totpPeriod)
+	if _, ok := ValidateTOTP(secret, code, time.Now()); !ok {
+		t.Error("current code of a new secret rejected")
+	}
+}
+
+func TestTOTPURI(t *testing.T) {
+	u, err := url.Parse(TOTPURI("Blog Auth", "ann@example.com", "SECRET"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := u.Query()
+	if u.Scheme != "otpauth" || u.Host != "totp" || u.Path != "/Blog Auth:ann@example.com" {
+		t.Errorf("TOTPURI = %s", u)
+	}
+	if q.Get("secret") != "SECRET" || q.Get("issuer") != "Blog Auth" || q.Get("digits") != "6" || q.Get("period") != "30" {
+		t.Errorf("TOTPURI query = %v", q)
+	}
+}
+
+func TestEncryptSecret(t *testing.T) {
+	sealed, err := EncryptSecret("server-key", "JBSWY3DPEHPK3PXP")
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := EncryptSecret("server-key", "JBSWY3DPEHPK3PXP")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sealed == again {
+		t.Error("encrypting twice gave the same ciphertext")
+	}
+	plain, err := DecryptSecret("server-key", sealed)
+	if err != nil || plain != "JBSWY3DPEHPK3PXP" {
+		t.Errorf("DecryptSecret = %q, %v", plain, err)
+	}
+	if _, err := DecryptSecret("other-key", sealed); err == nil {
+		t.Error("secret decrypted with another key")
+	}
+	if _, err := DecryptSecret("server-key", "c2hvcnQ="); err == nil {
+		t.Error("truncated ciphertext decrypted")
+	}
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	codes, err := NewRecoveryCodes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(codes) != recoveryCodeCount {
+		t.Fatalf("got %d codes, want %d", len(codes), recoveryCodeCount)
+	}
+	format := regexp.MustCompile(`^[a-z2-7]{5}-[a-z2-7]{5}$`)
+	seen := map[string]bool{}
+	for _, code := range codes {
+		if !format.MatchString(code) {
+			t.Errorf("code %q is not formatted as xxxxx-xxxxx", code)
+		}
+		if seen[code] {
+			t.Errorf("code %q repeated", code)
+		}
+		seen[code] = true
+	}
+	if got := NormalizeRecoveryCode(" ABCDE-FGHIJ "); got != "abcdefghij" {
+		t.Errorf("NormalizeRecoveryCode = %q", got)
+	}
+}
@@ -0,0 +1,30 @@
+package mfa
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"strings"
+)
+
+const recoveryCodeCount = 10
+
+// NewRecoveryCodes returns one-time recovery codes formatted as xxxxx-xxxxx.
+func NewRecoveryCodes() ([]string, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	enc := base32.StdEncoding.WithPadding(base32.NoPadding)
+	for i := 0; i < recoveryCodeCount; i++ {
+		buf := make([]byte, 7)
+		if _, err := rand.Read(buf); err != nil {
+			return nil, err
+		}
+		s := strings.ToLower(enc.EncodeToString(buf))[:10]
+		codes = append(codes, s[:5]+"-"+s[5:])
+	}
+	return codes, nil
+}
+
+// NormalizeRecoveryCode makes recovery codes case and dash insensitive before
+// hashing.
+func NormalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+}
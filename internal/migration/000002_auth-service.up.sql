@@ -0,0 +1 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS email_verified boolean default false not null;
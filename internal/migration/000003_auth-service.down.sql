@@ -0,0 +1,3 @@
+drop table if exists audit_logs;
+
+drop table if exists refresh_tokens;
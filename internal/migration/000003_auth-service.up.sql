@@ -0,0 +1,23 @@
+CREATE TABLE IF NOT EXISTS refresh_tokens(
+    id uuid primary key not null,
+    user_id uuid not null references users(id) on delete cascade,
+    family_id uuid not null,
+    token_hash varchar(64) unique not null,
+    expires_at timestamp not null,
+    rotated_at timestamp,
+    revoked_at timestamp,
+    created_at timestamp default now() not null
+);
+
+CREATE INDEX IF NOT EXISTS refresh_tokens_family_id_idx ON refresh_tokens(family_id);
+CREATE INDEX IF NOT EXISTS refresh_tokens_user_id_idx ON refresh_tokens(user_id);
+
+CREATE TABLE IF NOT EXISTS audit_logs(
+    id uuid primary key not null,
+    user_id uuid,
+    event varchar(100) not null,
+    details text default '' not null,
+    created_at timestamp default now() not null
+);
+
+CREATE INDEX IF NOT EXISTS audit_logs_user_id_idx ON audit_logs(user_id);
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS password_resets(
+    id uuid primary key not null,
+    user_id uuid not null references users(id) on delete cascade,
+    token_hash varchar(64) unique not null,
+    expires_at timestamp not null,
+    used_at timestamp,
+    created_at timestamp default now() not null
+);
+
+CREATE INDEX IF NOT EXISTS password_resets_user_id_idx ON password_resets(user_id);
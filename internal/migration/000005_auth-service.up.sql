@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS email_verifications(
+    id uuid primary key not null,
+    user_id uuid not null references users(id) on delete cascade,
+    code_hash varchar(64) not null,
+    attempts int default 0 not null,
+    expires_at timestamp not null,
+    used_at timestamp,
+    created_at timestamp default now() not null
+);
+
+CREATE INDEX IF NOT EXISTS email_verifications_user_id_idx ON email_verifications(user_id);
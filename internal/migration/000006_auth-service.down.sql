@@ -0,0 +1,3 @@
+drop table if exists email_queue;
+
+drop type if exists email_status;
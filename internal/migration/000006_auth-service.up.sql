@@ -0,0 +1,19 @@
+create type email_status as ENUM('pending','sending','sent','dead');
+
+CREATE TABLE IF NOT EXISTS email_queue(
+    id uuid primary key not null,
+    recipient varchar(150) not null,
+    subject varchar(255) not null,
+    text_body text default '' not null,
+    html_body text default '' not null,
+    status email_status default 'pending' not null,
+    attempts int default 0 not null,
+    last_error text default '' not null,
+    next_attempt_at timestamp default now() not null,
+    locked_until timestamp,
+    sent_at timestamp,
+    created_at timestamp default now() not null,
+    updated_at timestamp default now() not null
+);
+
+CREATE INDEX IF NOT EXISTS email_queue_status_next_attempt_idx ON email_queue(status, next_attempt_at);
@@ -0,0 +1,5 @@
+drop table if exists mfa_challenges;
+
+drop table if exists mfa_recovery_codes;
+
+drop table if exists user_totp;
@@ -0,0 +1,28 @@
+CREATE TABLE IF NOT EXISTS user_totp(
+    user_id uuid primary key not null references users(id) on delete cascade,
+    secret text not null,
+    enabled boolean default false not null,
+    last_used_step bigint default 0 not null,
+    confirmed_at timestamp,
+    created_at timestamp default now() not null
+);
+
+CREATE TABLE IF NOT EXISTS mfa_recovery_codes(
+    id uuid primary key not null,
+    user_id uuid not null references users(id) on delete cascade,
+    code_hash varchar(64) not null,
+    used_at timestamp,
+    created_at timestamp default now() not null
+);
+
+CREATE INDEX IF NOT EXISTS mfa_recovery_codes_user_id_idx ON mfa_recovery_codes(user_id);
+
+CREATE TABLE IF NOT EXISTS mfa_challenges(
+    id uuid primary key not null,
+    user_id uuid not null references users(id) on delete cascade,
+    token_hash varchar(64) unique not null,
+    attempts int default 0 not null,
+    expires_at timestamp not null,
+    used_at timestamp,
+    created_at timestamp default now() not null
+);
@@ -0,0 +1,3 @@
+drop table if exists webauthn_sessions;
+
+drop table if exists webauthn_credentials;
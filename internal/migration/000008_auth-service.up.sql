@@ -0,0 +1,22 @@
+CREATE TABLE IF NOT EXISTS webauthn_credentials(
+    id uuid primary key not null,
+    user_id uuid not null references users(id) on delete cascade,
+    credential_id bytea unique not null,
+    credential jsonb not null,
+    sign_count bigint default 0 not null,
+    name varchar(100) default '' not null,
+    second_factor boolean default false not null,
+    last_used_at timestamp,
+    created_at timestamp default now() not null
+);
+
+CREATE INDEX IF NOT EXISTS webauthn_credentials_user_id_idx ON webauthn_credentials(user_id);
+
+CREATE TABLE IF NOT EXISTS webauthn_sessions(
+    id uuid primary key not null,
+    user_id uuid references users(id) on delete cascade,
+    kind varchar(20) not null,
+    data jsonb not null,
+    expires_at timestamp not null,
+    created_at timestamp default now() not null
+);
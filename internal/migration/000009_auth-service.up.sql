@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS magic_links(
+    id uuid primary key not null,
+    user_id uuid references users(id) on delete cascade,
+    email varchar(150) not null,
+    token_hash varchar(64) unique not null,
+    expires_at timestamp not null,
+    used_at timestamp,
+    created_at timestamp default now() not null
+);
+
+CREATE INDEX IF NOT EXISTS magic_links_email_idx ON magic_links(email);
@@ -0,0 +1,3 @@
+drop table if exists login_failures;
+
+ALTER TABLE users DROP COLUMN IF EXISTS locked_until;
@@ -0,0 +1,10 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS locked_until timestamp;
+
+CREATE TABLE IF NOT EXISTS login_failures(
+    scope varchar(10) not null,
+    subject varchar(150) not null,
+    failures int default 0 not null,
+    blocked_until timestamp,
+    updated_at timestamp default now() not null,
+    primary key (scope, subject)
+);
@@ -0,0 +1,57 @@
+CREATE TABLE IF NOT EXISTS roles(
+    id uuid primary key not null,
+    name varchar(50) unique not null,
+    description varchar(255) default '' not null,
+    created_at timestamp default now() not null
+);
+
+CREATE TABLE IF NOT EXISTS permissions(
+    id uuid primary key not null,
+    name varchar(100) unique not null,
+    description varchar(255) default '' not null,
+    created_at timestamp default now() not null
+);
+
+CREATE TABLE IF NOT EXISTS role_permissions(
+    role_id uuid not null references roles(id) on delete cascade,
+    permission_id uuid not null references permissions(id) on delete cascade,
+    primary key (role_id, permission_id)
+);
+
+CREATE TABLE IF NOT EXISTS user_roles(
+    user_id uuid not null references users(id) on delete cascade,
+    role_id uuid not null references roles(id) on delete cascade,
+    created_at timestamp default now() not null,
+    primary key (user_id, role_id)
+);
+
+CREATE INDEX IF NOT EXISTS user_roles_role_id_idx ON user_roles(role_id);
+
+INSERT INTO roles (id, name, description) VALUES
+    (gen_random_uuid(), 'editor', 'Edits and publishes any post'),
+    (gen_random_uuid(), 'author', 'Writes and publishes own posts'),
+    (gen_random_uuid(), 'contributor', 'Writes posts for review'),
+    (gen_random_uuid(), 'moderator', 'Moderates comments')
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO permissions (id, name, description) VALUES
+    (gen_random_uuid(), 'post:create', 'Create draft posts'),
+    (gen_random_uuid(), 'post:edit', 'Edit any post'),
+    (gen_random_uuid(), 'post:publish', 'Publish posts'),
+    (gen_random_uuid(), 'post:delete', 'Delete posts'),
+    (gen_random_uuid(), 'comment:moderate', 'Hide and delete comments')
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id FROM roles r, permissions p WHERE (r.name, p.name) IN (
+    ('editor', 'post:create'),
+    ('editor', 'post:edit'),
+    ('editor', 'post:publish'),
+    ('editor', 'post:delete'),
+    ('editor', 'comment:moderate'),
+    ('author', 'post:create'),
+    ('author', 'post:publish'),
+    ('contributor', 'post:create'),
+    ('moderator', 'comment:moderate')
+)
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS casbin_rules(
+    id bigserial primary key,
+    ptype varchar(10) not null,
+    v0 varchar(255) default '' not null,
+    v1 varchar(255) default '' not null,
+    v2 varchar(255) default '' not null,
+    v3 varchar(255) default '' not null,
+    v4 varchar(255) default '' not null,
+    v5 varchar(255) default '' not null
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS casbin_rules_rule_idx ON casbin_rules(ptype, v0, v1, v2, v3, v4, v5);
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS sessions(
+    id uuid primary key not null,
+    user_id uuid not null references users(id) on delete cascade,
+    device_name varchar(100) default '' not null,
+    user_agent text default '' not null,
+    ip varchar(64) default '' not null,
+    last_seen_at timestamp default now() not null,
+    revoked_at timestamp,
+    created_at timestamp default now() not null
+);
+
+CREATE INDEX IF NOT EXISTS sessions_user_id_idx ON sessions(user_id);
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS signing_keys(
+    kid varchar(64) primary key not null,
+    algorithm varchar(16) not null,
+    private_key text not null,
+    activates_at timestamp not null,
+    retires_at timestamp not null,
+    expires_at timestamp not null,
+    created_at timestamp default now() not null
+);
+
+CREATE INDEX IF NOT EXISTS signing_keys_expires_at_idx ON signing_keys(expires_at);
@@ -0,0 +1,8 @@
+ALTER TABLE sessions DROP COLUMN IF EXISTS scope;
+ALTER TABLE sessions DROP COLUMN IF EXISTS client_id;
+
+drop table if exists oauth_consents;
+
+drop table if exists oauth_authorization_codes;
+
+drop table if exists oauth_clients;
@@ -0,0 +1,32 @@
+CREATE TABLE IF NOT EXISTS oauth_clients(
+    id varchar(64) primary key not null,
+    name varchar(100) not null,
+    secret_hash varchar(64),
+    redirect_uris text[] default '{}' not null,
+    scopes text[] default '{}' not null,
+    created_at timestamp default now() not null,
+    deleted_at bigint default 0 not null
+);
+
+CREATE TABLE IF NOT EXISTS oauth_authorization_codes(
+    code_hash varchar(64) primary key not null,
+    client_id varchar(64) not null references oauth_clients(id) on delete cascade,
+    user_id uuid not null references users(id) on delete cascade,
+    redirect_uri text not null,
+    scope text default '' not null,
+    code_challenge varchar(128) not null,
+    expires_at timestamp not null,
+    used_at timestamp,
+    created_at timestamp default now() not null
+);
+
+CREATE TABLE IF NOT EXISTS oauth_consents(
+    user_id uuid not null references users(id) on delete cascade,
+    client_id varchar(64) not null references oauth_clients(id) on delete cascade,
+    scopes text[] default '{}' not null,
+    updated_at timestamp default now() not null,
+    primary key (user_id, client_id)
+);
+
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS client_id varchar(64) default '' not null;
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS scope text default '' not null;
@@ -0,0 +1,3 @@
+ALTER TABLE users DROP COLUMN IF EXISTS profile_image;
+
+ALTER TABLE oauth_authorization_codes DROP COLUMN IF EXISTS nonce;
@@ -0,0 +1,3 @@
+ALTER TABLE oauth_authorization_codes ADD COLUMN IF NOT EXISTS nonce varchar(255) default '' not null;
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS profile_image varchar(500) default '' not null;
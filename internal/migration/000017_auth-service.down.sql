@@ -0,0 +1 @@
+drop table if exists service_accounts;
@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS service_accounts(
+    id varchar(64) primary key not null,
+    name varchar(100) not null,
+    secret_hash varchar(64),
+    public_key text,
+    scopes text[] default '{}' not null,
+    created_at timestamp default now() not null,
+    deleted_at bigint default 0 not null
+);
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS oauth_device_codes(
+    device_code_hash varchar(64) primary key not null,
+    user_code varchar(16) unique not null,
+    client_id varchar(64) not null references oauth_clients(id) on delete cascade,
+    scope text default '' not null,
+    user_id uuid references users(id) on delete cascade,
+    status varchar(16) default 'pending' not null,
+    poll_interval int not null,
+    last_polled_at timestamp,
+    expires_at timestamp not null,
+    used_at timestamp,
+    created_at timestamp default now() not null
+);
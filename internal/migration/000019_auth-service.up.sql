@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS personal_access_tokens(
+    id uuid primary key not null,
+    user_id uuid not null references users(id) on delete cascade,
+    name varchar(100) not null,
+    token_hash varchar(64) unique not null,
+    scopes text[] default '{}' not null,
+    expires_at timestamp,
+    last_used_at timestamp,
+    created_at timestamp default now() not null,
+    revoked_at timestamp
+);
+
+CREATE INDEX IF NOT EXISTS personal_access_tokens_user_id_idx ON personal_access_tokens(user_id);
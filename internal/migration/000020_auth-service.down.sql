@@ -0,0 +1,2 @@
+drop table if exists external_login_states;
+drop table if exists user_identities;
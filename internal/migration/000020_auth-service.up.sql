@@ -0,0 +1,22 @@
+CREATE TABLE IF NOT EXISTS user_identities(
+    id uuid primary key not null,
+    user_id uuid not null references users(id) on delete cascade,
+    provider varchar(64) not null,
+    subject varchar(255) not null,
+    email varchar(150) default '' not null,
+    last_login_at timestamp,
+    created_at timestamp default now() not null,
+    unique (provider, subject)
+);
+
+CREATE INDEX IF NOT EXISTS user_identities_user_id_idx ON user_identities(user_id);
+
+CREATE TABLE IF NOT EXISTS external_login_states(
+    state_hash varchar(64) primary key not null,
+    provider varchar(64) not null,
+    nonce varchar(64) not null,
+    code_verifier varchar(128) not null,
+    expires_at timestamp not null,
+    used_at timestamp,
+    created_at timestamp default now() not null
+);
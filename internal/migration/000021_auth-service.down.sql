@@ -0,0 +1,3 @@
+ALTER TABLE users DROP COLUMN IF EXISTS phone;
+
+ALTER TABLE users DROP COLUMN IF EXISTS bio;
@@ -0,0 +1,3 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS bio text default '' not null;
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS phone varchar(20) default '' not null;
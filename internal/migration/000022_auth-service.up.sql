@@ -0,0 +1 @@
+ALTER TABLE oauth_authorization_codes ADD COLUMN IF NOT EXISTS auth_time timestamp;
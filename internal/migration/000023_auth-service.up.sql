@@ -0,0 +1,9 @@
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS expires_at timestamp;
+
+UPDATE sessions s SET expires_at = COALESCE(
+    (SELECT max(r.expires_at) FROM refresh_tokens r WHERE r.family_id = s.id AND r.rotated_at IS NULL AND r.revoked_at IS NULL),
+    s.last_seen_at
+) WHERE s.expires_at IS NULL;
+
+ALTER TABLE sessions ALTER COLUMN expires_at SET DEFAULT now();
+ALTER TABLE sessions ALTER COLUMN expires_at SET NOT NULL;
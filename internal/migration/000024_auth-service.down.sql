@@ -0,0 +1 @@
+drop table if exists sign_in_codes;
@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS sign_in_codes(
+    code_hash varchar(64) primary key not null,
+    session_id uuid not null references sessions(id) on delete cascade,
+    handoff_hash varchar(64) not null,
+    expires_at timestamp not null,
+    used_at timestamp,
+    created_at timestamp default now() not null
+);
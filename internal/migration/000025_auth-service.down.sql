@@ -0,0 +1 @@
+-- Cleared email bodies cannot be restored.
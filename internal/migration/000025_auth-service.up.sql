@@ -0,0 +1 @@
+UPDATE email_queue SET text_body = '', html_body = '' WHERE status IN ('sent', 'dead');
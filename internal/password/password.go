@@ -0,0 +1,230 @@
+package password
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"blog-auth/internal/config"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	Argon2id = "argon2id"
+	Bcrypt   = "bcrypt"
+
+	// bcryptSHA256 marks bcrypt hashes created by this package. The password
+	// is pre-hashed with HMAC-SHA256 so bcrypt never sees more than 72 bytes.
+	bcryptSHA256 = "bcrypt-sha256"
+
+	saltLength = 16
+	keyLength  = 32
+)
+
+var ErrUnknownHash = errors.New("unknown password hash format")
+
+// Hasher creates and verifies password hashes. Every hash records the
+// algorithm and parameters it was made with, so older hashes keep verifying
+// after the configuration changes and can be upgraded on the next login.
+//
+// Supported formats:
+//
+//	$argon2id$v=19$m=<KiB>,t=<passes>,p=<threads>[,k=1]$<salt>$<key>
+//	$bcrypt-sha256$v=1[,k=1]$<bcrypt hash>
+//	$2a$..., $2b$..., $2y$...   legacy bcrypt of the raw password
+//
+// k=1 means the password was peppered with PASSWORDPEPPER before hashing.
+type Hasher struct {
+	algorithm  string
+	time       uint32
+	memory     uint32
+	threads    uint8
+	bcryptCost int
+	pepper     string
+
+	dummyOnce sync.Once
+	dummy     string
+}
+
+func NewHasher(cfg config.Config) *Hasher {
+	h := &Hasher{
+		algorithm:  cfg.PASSWORDALGO,
+		time:       uint32(cfg.ARGONTIME),
+		memory:     uint32(cfg.ARGONMEMORY),
+		threads:    uint8(cfg.ARGONTHREADS),
+		bcryptCost: cfg.BCRYPTCOST,
+		pepper:     cfg.PASSWORDPEPPER,
+	}
+	if h.algorithm != Bcrypt {
+		h.algorithm = Argon2id
+	}
+	if h.time < 1 {
+		h.time = 1
+	}
+	if h.threads < 1 {
+		h.threads = 1
+	}
+	// argon2 needs at least 8 KiB per thread.
+	if h.memory < 8*uint32(h.threads) {
+		h.memory = 64 * 1024
+	}
+	if h.bcryptCost < bcrypt.MinCost || h.bcryptCost > bcrypt.MaxCost {
+		h.bcryptCost = bcrypt.DefaultCost
+	}
+	return h
+}
+
+// Hash returns an encoded hash of password using the configured algorithm.
+func (h *Hasher) Hash(password string) (string, error) {
+	peppered := h.pepper != ""
+	if h.algorithm == Bcrypt {
+		hash, err := bcrypt.GenerateFromPassword(h.prehash(password, peppered), h.bcryptCost)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("$%s$v=1%s%s", bcryptSHA256, pepperFlag(peppered), hash), nil
+	}
+
+	salt := make([]byte, saltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := argon2.IDKey(h.input(password, peppered), salt, h.time, h.memory, h.threads, keyLength)
+	return fmt.Sprintf("$%s$v=%d$m=%d,t=%d,p=%d%s$%s$%s",
+		Argon2id, argon2.Version, h.memory, h.time, h.threads, pepperFlag(peppered),
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+// Verify reports whether password matches encoded. needsRehash is true when
+// the hash was made with a different algorithm, parameters or pepper setting
+// than the current configuration.
+func (h *Hasher) Verify(password, encoded string) (ok, needsRehash bool, err error) {
+	switch {
+	case strings.HasPrefix(encoded, "$"+Argon2id+"$"):
+		return h.verifyArgon2id(password, encoded)
+	case strings.HasPrefix(encoded, "$"+bcryptSHA256+"$"):
+		return h.verifyBcryptSHA256(password, encoded)
+	case strings.HasPrefix(encoded, "$2a$"), strings.HasPrefix(encoded, "$2b$"), strings.HasPrefix(encoded, "$2y$"):
+		if bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password)) != nil {
+			return false, false, nil
+		}
+		return true, true, nil
+	case encoded == "":
+		// Accounts created without a password, e.g. through a magic link.
+		return false, false, nil
+	}
+	return false, false, ErrUnknownHash
+}
+
+// VerifyDummy checks password against a hash nobody knows the password of, made
+// with the current configuration. Logins for unknown users call it so they
+// take as long as a wrong password and do not reveal who has an account.
+func (h *Hasher) VerifyDummy(password string) {
+	h.dummyOnce.Do(func() {
+		secret := make([]byte, 32)
+		if _, err := rand.Read(secret); err == nil {
+			h.dummy, _ = h.Hash(base64.RawStdEncoding.EncodeToString(secret))
+		}
+	})
+	h.Verify(password, h.dummy)
+}
+
+func (h *Hasher) verifyArgon2id(password, encoded string) (bool, bool, error) {
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 {
+		return false, false, ErrUnknownHash
+	}
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return false, false, ErrUnknownHash
+	}
+	params, peppered := cutPepperFlag(parts[3])
+	var memory, time uint32
+	var threads uint8
+	if _, err := fmt.Sscanf(params, "m=%d,t=%d,p=%d", &memory, &time, &threads); err != nil {
+		return false, false, ErrUnknownHash
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return false, false, ErrUnknownHash
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return false, false, ErrUnknownHash
+	}
+	other := argon2.IDKey(h.input(password, peppered), salt, time, memory, threads, uint32(len(key)))
+	if subtle.ConstantTimeCompare(key, other) != 1 {
+		return false, false, nil
+	}
+	stale := h.algorithm != Argon2id || memory != h.memory || time != h.time || threads != h.threads ||
+		len(key) != keyLength || peppered != (h.pepper != "")
+	return true, stale, nil
+}
+
+func (h *Hasher) verifyBcryptSHA256(password, encoded string) (bool, bool, error) {
+	rest := strings.TrimPrefix(encoded, "$"+bcryptSHA256+"$")
+	params, hash, ok := strings.Cut(rest, "$")
+	if !ok {
+		return false, false, ErrUnknownHash
+	}
+	params, peppered := cutPepperFlag(params)
+	if params != "v=1" {
+		return false, false, ErrUnknownHash
+	}
+	hash = "$" + hash
+	if bcrypt.CompareHashAndPassword([]byte(hash), h.prehash(password, peppered)) != nil {
+		return false, false, nil
+	}
+	cost, err := bcrypt.Cost([]byte(hash))
+	if err != nil {
+		return false, false, ErrUnknownHash
+	}
+	stale := h.algorithm != Bcrypt || cost != h.bcryptCost || peppered != (h.pepper != "")
+	return true, stale, nil
+}
+
+// input returns the bytes that are actually hashed.
+func (h *Hasher) input(password string, peppered bool) []byte {
+	if !peppered {
+		return []byte(password)
+	}
+	mac := hmac.New(sha256.New, []byte(h.pepper))
+	mac.Write([]byte(password))
+	return mac.Sum(nil)
+}
+
+// prehash maps any password to 44 base64 characters: bcrypt stops at 72 bytes
+// and at the first NUL byte.
+func (h *Hasher) prehash(password string, peppered bool) []byte {
+	key := ""
+	if peppered {
+		key = h.pepper
+	}
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write([]byte(password))
+	return []byte(base64.StdEncoding.EncodeToString(mac.Sum(nil)))
+}
+
+func pepperFlag(peppered bool) string {
+	if peppered {
+		return ",k=1"
+	}
+	return ""
+}
+
+func cutPepperFlag(params string) (string, bool) {
+	if p, ok := strings.CutSuffix(params, ",k=1"); ok {
+		return p, true
+	}
+	return params, false
+}
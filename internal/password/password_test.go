@@ -0,0 +1,195 @@
+package password
+
+import (
+	"strings"
+	"testing"
+
+	"blog-auth/internal/config"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// testConfig keeps the work factors at their minimum so the tests run fast.
+func testConfig(algorithm, pepper string) config.Config {
+	return config.Config{
+		PASSWORDALGO:   algorithm,
+		ARGONTIME:      1,
+		ARGONMEMORY:    64,
+		ARGONTHREADS:   1,
+		BCRYPTCOST:     bcrypt.MinCost,
+		PASSWORDPEPPER: pepper,
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, tc := range []struct {
+		algorithm, pepper, prefix string
+	}{
+		{Argon2id, "", "$argon2id$v=19$m=64,t=1,p=1$"},
+		{Argon2id, "pepper", "$argon2id$v=19$m=64,t=1,p=1,k=1$"},
+		{Bcrypt, "", "$bcrypt-sha256$v=1$2a$"},
+		{Bcrypt, "pepper", "$bcrypt-sha256$v=1,k=1$2a$"},
+	} {
+		h := NewHasher(testConfig(tc.algorithm, tc.pepper))
+		hash, err := h.Hash("correct horse")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(hash, tc.prefix) {
+			t.Errorf("%s pepper=%q: hash %q does not start with %q", tc.algorithm, tc.pepper, hash, tc.prefix)
+		}
+		if ok, stale, err := h.Verify("correct horse", hash); !ok || stale || err != nil {
+			t.Errorf("%s pepper=%q: Verify = %v, %v, %v", tc.algorithm, tc.pepper, ok, stale, err)
+		}
+		if ok, _, err := h.Verify("wrong horse", hash); ok || err != nil {
+			t.Errorf("%s pepper=%q: wrong password: Verify = %v, %v", tc.algorithm, tc.pepper, ok, err)
+		}
+	}
+}
+
+func TestHashesAreSalted(t *testing.T) {
+	h := NewHasher(testConfig(Argon2id, ""))
+	a, _ := h.Hash("correct horse")
+	b, _ := h.Hash("correct horse")
+	if a == b {
+		t.Error("the same password hashed twice gave the same hash")
+	}
+}
+
+func TestNeedsRehash(t *testing.T) {
+	argon := NewHasher(testConfig(Argon2id, ""))
+	argonHash, _ := argon.Hash("correct horse")
+	bcryptHash, _ := NewHasher(testConfig(Bcrypt, "")).Hash("correct horse")
+
+	moreTime := testConfig(Argon2id, "")
+	moreTime.ARGONTIME = 2
+	moreMemory := testConfig(Argon2id, "")
+	moreMemory.ARGONMEMORY = 128
+	higherCost := testConfig(Bcrypt, "")
+	higherCost.BCRYPTCOST = bcrypt.MinCost + 1
+
+	tests := []struct {
+		name string
+		cfg  config.Config
+		hash string
+	}{
+		{"argon2id time", moreTime, argonHash},
+		{"argon2id memory", moreMemory, argonHash},
+		{"argon2id to bcrypt", testConfig(Bcrypt, ""), argonHash},
+		{"bcrypt to argon2id", testConfig(Argon2id, ""), bcryptHash},
+		{"bcrypt cost", higherCost, bcryptHash},
+		{"pepper added to argon2id", testConfig(Argon2id, "pepper"), argonHash},
+		{"pepper added to bcrypt", testConfig(Bcrypt, "pepper"), bcryptHash},
+	}
+	for _, tt := range tests {
+		ok, stale, err := NewHasher(tt.cfg).Verify("correct horse", tt.hash)
+		if !ok || !stale || err != nil {
+			t.Errorf("%s: Verify = %v, %v, %v; want a match that needs a rehash", tt.name, ok, stale, err)
+		}
+	}
+}
+
+func TestPepper(t *testing.T) {
+	peppered := NewHasher(testConfig(Argon2id, "pepper"))
+	hash, _ := peppered.Hash("correct horse")
+
+	// Without the pepper that made it the hash cannot be checked.
+	if ok, _, _ := NewHasher(testConfig(Argon2id, "other")).Verify("correct horse", hash); ok {
+		t.Error("hash verified with another pepper")
+	}
+	// k=1 is what says the pepper was used: a hash without it is checked
+	// against the bare password and must be upgraded.
+	plain, _ := NewHasher(testConfig(Argon2id, "")).Hash("correct horse")
+	if ok, stale, _ := peppered.Verify("correct horse", plain); !ok || !stale {
+		t.Errorf("unpeppered hash: Verify = %v, %v", ok, stale)
+	}
+	// Removing the pepper later leaves peppered hashes unverifiable rather
+	// than silently checking them without it.
+	if ok, _, _ := NewHasher(testConfig(Argon2id, "")).Verify("correct horse", hash); ok {
+		t.Error("peppered hash verified without the pepper")
+	}
+}
+
+func TestLegacyBcrypt(t *testing.T) {
+	legacy, err := bcrypt.GenerateFromPassword([]byte("correct horse"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := NewHasher(testConfig(Bcrypt, ""))
+	if ok, stale, err := h.Verify("correct horse", string(legacy)); !ok || !stale || err != nil {
+		t.Errorf("Verify = %v, %v, %v; want a match that needs a rehash", ok, stale, err)
+	}
+	if ok, _, _ := h.Verify("wrong horse", string(legacy)); ok {
+		t.Error("wrong password matched a legacy hash")
+	}
+}
+
+func TestLongPasswords(t *testing.T) {
+	// bcrypt alone ignores everything past 72 bytes, so these would collide.
+	long := strings.Repeat("a", 72)
+	for _, algorithm := range []string{Argon2id, Bcrypt} {
+		h := NewHasher(testConfig(algorithm, ""))
+		hash, err := h.Hash(long + "1")
+		if err != nil {
+			t.Fatalf("%s: %v", algorithm, err)
+		}
+		if ok, _, _ := h.Verify(long+"1", hash); !ok {
+			t.Errorf("%s: password over 72 bytes rejected", algorithm)
+		}
+		if ok, _, _ := h.Verify(long+"2", hash); ok {
+			t.Errorf("%s: only the first 72 bytes were checked", algorithm)
+		}
+	}
+}
+
+func TestPasswordsWithNUL(t *testing.T) {
+	for _, algorithm := range []string{Argon2id, Bcrypt} {
+		h := NewHasher(testConfig(algorithm, ""))
+		hash, err := h.Hash("abc\x00def")
+		if err != nil {
+			t.Fatalf("%s: %v", algorithm, err)
+		}
+		if ok, _, _ := h.Verify("abc\x00def", hash); !ok {
+			t.Errorf("%s: password with NUL rejected", algorithm)
+		}
+		if ok, _, _ := h.Verify("abc\x00xyz", hash); ok {
+			t.Errorf("%s: password was cut at the NUL byte", algorithm)
+		}
+	}
+}
+
+func TestUnknownHash(t *testing.T) {
+	h := NewHasher(testConfig(Argon2id, ""))
+	for _, encoded := range []string{
+		"plaintext",
+		"$md5$abc",
+		"$argon2id$v=19$m=64,t=1,p=1$c2FsdA",
+		"$argon2id$v=18$m=64,t=1,p=1$c2FsdA$a2V5",
+		"$argon2id$v=19$m=x,t=1,p=1$c2FsdA$a2V5",
+		"$argon2id$v=19$m=64,t=1,p=1$!!!$a2V5",
+		"$argon2id$v=19$m=64,t=1,p=1$c2FsdA$!!!",
+		"$bcrypt-sha256$v=1",
+		"$bcrypt-sha256$v=2$2a$04$abcdefghijklmnopqrstuv",
+	} {
+		if _, _, err := h.Verify("correct horse", encoded); err != ErrUnknownHash {
+			t.Errorf("Verify(%q) error = %v, want ErrUnknownHash", encoded, err)
+		}
+	}
+}
+
+func TestEmptyHash(t *testing.T) {
+	h := NewHasher(testConfig(Argon2id, ""))
+	for _, password := range []string{"", "correct horse"} {
+		if ok, stale, err := h.Verify(password, ""); ok || stale || err != nil {
+			t.Errorf("Verify(%q, \"\") = %v, %v, %v", password, ok, stale, err)
+		}
+	}
+}
+
+func TestVerifyDummy(t *testing.T) {
+	h := NewHasher(testConfig(Argon2id, ""))
+	h.VerifyDummy("correct horse")
+	if !strings.HasPrefix(h.dummy, "$argon2id$") {
+		t.Errorf("dummy hash = %q", h.dummy)
+	}
+}
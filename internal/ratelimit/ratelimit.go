@@ -0,0 +1,137 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"blog-auth/internal/config"
+)
+
+// Keys a limit can be applied per.
+const (
+	KeyIP    = "ip"
+	KeyUser  = "user"
+	KeyEmail = "email"
+)
+
+// Limit is a token bucket: up to Burst calls at once, refilled at Burst tokens
+// per Period.
+type Limit struct {
+	Burst  int
+	Period time.Duration
+	Key    string
+}
+
+// String identifies the limit, e.g. "5/1m0s:email". Buckets are stored per
+// limit, so two limits on the same key never share one.
+func (l Limit) String() string {
+	return strconv.Itoa(l.Burst) + "/" + l.Period.String() + ":" + l.Key
+}
+
+// Limiter takes one token from the bucket stored under key. When the bucket is
+// empty it returns false and how long until a token is available.
+type Limiter interface {
+	Allow(ctx context.Context, key string, limit Limit) (bool, time.Duration, error)
+}
+
+// Rules maps an RPC to its limits. Methods are matched as "Service/Method"
+// first and then by bare method name; Default applies to all other methods.
+type Rules struct {
+	Methods map[string][]Limit
+	Default []Limit
+}
+
+// For returns the limits for a full gRPC method name such as
+// "/userservice.UserService/Login".
+func (r Rules) For(fullMethod string) []Limit {
+	name := strings.TrimPrefix(fullMethod, "/")
+	service, method, _ := strings.Cut(name, "/")
+	if i := strings.LastIndex(service, "."); i >= 0 {
+		service = service[i+1:]
+	}
+	if limits, ok := r.Methods[service+"/"+method]; ok {
+		return limits
+	}
+	if limits, ok := r.Methods[method]; ok {
+		return limits
+	}
+	return r.Default
+}
+
+// ParseRules reads RATELIMITS and RATEDEFAULT. Both are comma separated lists
+// of "<Method>=<burst>/<period>[:<key>]" (RATEDEFAULT without the method), for
+// example "Login=5/1m:email,Login=20/1m:ip". The key defaults to ip. HTTP
+// endpoints are named by their path, as in "oauth/token=60/1m".
+func ParseRules(methods, def string) (Rules, error) {
+	rules := Rules{Methods: map[string][]Limit{}}
+	for _, item := range splitList(methods) {
+		method, spec, ok := strings.Cut(item, "=")
+		if !ok {
+			return Rules{}, fmt.Errorf("rate limit %q: missing method", item)
+		}
+		limit, err := parseLimit(spec)
+		if err != nil {
+			return Rules{}, err
+		}
+		method = strings.TrimSpace(method)
+		rules.Methods[method] = append(rules.Methods[method], limit)
+	}
+	for _, item := range splitList(def) {
+		limit, err := parseLimit(item)
+		if err != nil {
+			return Rules{}, err
+		}
+		rules.Default = append(rules.Default, limit)
+	}
+	return rules, nil
+}
+
+func parseLimit(spec string) (Limit, error) {
+	spec = strings.TrimSpace(spec)
+	rate, key, _ := strings.Cut(spec, ":")
+	count, period, ok := strings.Cut(rate, "/")
+	if !ok {
+		return Limit{}, fmt.Errorf("rate limit %q: expected <burst>/<period>", spec)
+	}
+	burst, err := strconv.Atoi(count)
+	if err != nil || burst < 1 {
+		return Limit{}, fmt.Errorf("rate limit %q: invalid burst", spec)
+	}
+	d, err := time.ParseDuration(period)
+	if err != nil || d <= 0 {
+		return Limit{}, fmt.Errorf("rate limit %q: invalid period", spec)
+	}
+	switch key {
+	case "":
+		key = KeyIP
+	case KeyIP, KeyUser, KeyEmail:
+	default:
+		return Limit{}, fmt.Errorf("rate limit %q: unknown key %q", spec, key)
+	}
+	return Limit{Burst: burst, Period: d, Key: key}, nil
+}
+
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// NewLimiter returns the backend selected by RATEBACKEND.
+func NewLimiter(cfg config.Config) (Limiter, error) {
+	switch cfg.RATEBACKEND {
+	case "", "memory":
+		return NewMemoryLimiter(), nil
+	case "redis":
+		return NewRedisLimiter(cfg)
+	default:
+		return nil, fmt.Errorf("unknown rate limit backend %q", cfg.RATEBACKEND)
+	}
+}
@@ -0,0 +1,97 @@
+package ratelimit
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestMemoryLimiter(t *testing.T) {
+	ctx := context.Background()
+	l := NewMemoryLimiter()
+	limit := Limit{Burst: 3, Period: time.Minute, Key: KeyIP}
+
+	for i := 0; i < limit.Burst; i++ {
+		if ok, _, err := l.Allow(ctx, "a", limit); !ok || err != nil {
+			t.Fatalf("call %d: Allow = %v, %v", i+1, ok, err)
+		}
+	}
+	ok, retry, err := l.Allow(ctx, "a", limit)
+	if ok || err != nil {
+		t.Fatalf("call over the burst: Allow = %v, %v", ok, err)
+	}
+	// One token is refilled every Period/Burst.
+	if retry <= 0 || retry > limit.Period/time.Duration(limit.Burst) {
+		t.Errorf("retry = %v", retry)
+	}
+	if ok, _, _ := l.Allow(ctx, "b", limit); !ok {
+		t.Error("another key shares the bucket")
+	}
+}
+
+func TestMemoryLimiterRefills(t *testing.T) {
+	ctx := context.Background()
+	l := NewMemoryLimiter()
+	limit := Limit{Burst: 2, Period: 100 * time.Millisecond, Key: KeyIP}
+
+	l.Allow(ctx, "a", limit)
+	l.Allow(ctx, "a", limit)
+	ok, retry, _ := l.Allow(ctx, "a", limit)
+	if ok {
+		t.Fatal("call over the burst allowed")
+	}
+	time.Sleep(retry + 10*time.Millisecond)
+	if ok, _, _ := l.Allow(ctx, "a", limit); !ok {
+		t.Error("bucket did not refill")
+	}
+}
+
+func TestParseRules(t *testing.T) {
+	rules, err := ParseRules("Login=5/1m:email, Login=20/1m, AdminService/DeleteUser=1/1s:user, oauth/token=60/1m", "300/1m")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		method string
+		want   []Limit
+	}{
+		{"/userservice.UserService/Login", []Limit{
+			{Burst: 5, Period: time.Minute, Key: KeyEmail},
+			{Burst: 20, Period: time.Minute, Key: KeyIP},
+		}},
+		{"/userservice.AdminService/DeleteUser", []Limit{{Burst: 1, Period: time.Second, Key: KeyUser}}},
+		{"/userservice.UserService/DeleteUser", []Limit{{Burst: 300, Period: time.Minute, Key: KeyIP}}},
+		{"/oauth/token", []Limit{{Burst: 60, Period: time.Minute, Key: KeyIP}}},
+		{"/oauth/authorize", []Limit{{Burst: 300, Period: time.Minute, Key: KeyIP}}},
+	}
+	for _, tt := range tests {
+		if got := rules.For(tt.method); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("For(%s) = %v, want %v", tt.method, got, tt.want)
+		}
+	}
+}
+
+func TestParseRulesErrors(t *testing.T) {
+	for _, spec := range []string{
+		"Login",
+		"Login=5",
+		"Login=0/1m",
+		"Login=x/1m",
+		"Login=5/soon",
+		"Login=5/-1m",
+		"Login=5/1m:phone",
+	} {
+		if _, err := ParseRules(spec, ""); err == nil {
+			t.Errorf("ParseRules(%q) accepted", spec)
+		}
+	}
+}
+
+func TestLimitString(t *testing.T) {
+	a := Limit{Burst: 5, Period: time.Minute, Key: KeyIP}
+	b := Limit{Burst: 20, Period: time.Minute, Key: KeyIP}
+	if a.String() == b.String() {
+		t.Errorf("limits with the same key share the id %q", a)
+	}
+}
@@ -0,0 +1,72 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"blog-auth/internal/config"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// tokenBucket refills and takes from the bucket in one round trip. It uses the
+// Redis clock so replicas with skewed clocks share the same view.
+//
+// KEYS[1] bucket, ARGV[1] burst, ARGV[2] period in milliseconds.
+// Returns {allowed, milliseconds until the next token}.
+var tokenBucket = redis.NewScript(`
+local burst = tonumber(ARGV[1])
+local period = tonumber(ARGV[2])
+local rate = burst / period
+local t = redis.call('TIME')
+local now = t[1] * 1000 + math.floor(t[2] / 1000)
+
+local b = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(b[1]) or burst
+local ts = tonumber(b[2]) or now
+tokens = math.min(burst, tokens + math.max(0, now - ts) * rate)
+
+local allowed = 0
+local wait = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+else
+	wait = math.ceil((1 - tokens) / rate)
+end
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', now)
+redis.call('PEXPIRE', KEYS[1], period)
+return {allowed, wait}
+`)
+
+// RedisLimiter keeps buckets in Redis (or anything speaking its protocol) so
+// all replicas share them.
+type RedisLimiter struct {
+	client *redis.Client
+}
+
+func NewRedisLimiter(cfg config.Config) (*RedisLimiter, error) {
+	client := redis.NewClient(&redis.Options{
+		Addr:     fmt.Sprintf("%s:%d", cfg.REDISHOST, cfg.REDISPORT),
+		Password: cfg.REDISPASSWORD,
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, err
+	}
+	return &RedisLimiter{client: client}, nil
+}
+
+func (l *RedisLimiter) Allow(ctx context.Context, key string, limit Limit) (bool, time.Duration, error) {
+	res, err := tokenBucket.Run(ctx, l.client, []string{"ratelimit:" + key}, limit.Burst, limit.Period.Milliseconds()).Int64Slice()
+	if err != nil {
+		return false, 0, err
+	}
+	if len(res) != 2 {
+		return false, 0, fmt.Errorf("unexpected rate limit reply %v", res)
+	}
+	return res[0] == 1, time.Duration(res[1]) * time.Millisecond, nil
+}
@@ -0,0 +1,60 @@
+package revocation
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const sweepInterval = time.Minute
+
+// MemoryDenylist keeps revoked ids in process memory. It is only correct when
+// a single instance serves all traffic.
+type MemoryDenylist struct {
+	mu      sync.Mutex
+	entries map[string]time.Time
+	swept   time.Time
+}
+
+func NewMemoryDenylist() *MemoryDenylist {
+	return &MemoryDenylist{entries: map[string]time.Time{}, swept: time.Now()}
+}
+
+func (d *MemoryDenylist) Revoke(ctx context.Context, id string, ttl time.Duration) error {
+	if ttl <= 0 {
+		return nil
+	}
+	now := time.Now()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.sweep(now)
+	if until := now.Add(ttl); until.After(d.entries[id]) {
+		d.entries[id] = until
+	}
+	return nil
+}
+
+func (d *MemoryDenylist) Revoked(ctx context.Context, ids ...string) (bool, error) {
+	now := time.Now()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, id := range ids {
+		if until, ok := d.entries[id]; ok && now.Before(until) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// sweep drops expired entries so the map does not grow without bound.
+func (d *MemoryDenylist) sweep(now time.Time) {
+	if now.Sub(d.swept) < sweepInterval {
+		return
+	}
+	for id, until := range d.entries {
+		if !now.Before(until) {
+			delete(d.entries, id)
+		}
+	}
+	d.swept = now
+}
@@ -0,0 +1,55 @@
+package revocation
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"blog-auth/internal/config"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const redisPrefix = "revoked:"
+
+// RedisDenylist keeps revoked ids in Redis so every replica, and any other
+// service reading the same keys, sees a revocation immediately.
+type RedisDenylist struct {
+	client *redis.Client
+}
+
+func NewRedisDenylist(cfg config.Config) (*RedisDenylist, error) {
+	client := redis.NewClient(&redis.Options{
+		Addr:     fmt.Sprintf("%s:%d", cfg.REDISHOST, cfg.REDISPORT),
+		Password: cfg.REDISPASSWORD,
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, err
+	}
+	return &RedisDenylist{client: client}, nil
+}
+
+func (d *RedisDenylist) Revoke(ctx context.Context, id string, ttl time.Duration) error {
+	if ttl <= 0 {
+		return nil
+	}
+	return d.client.Set(ctx, redisPrefix+id, 1, ttl).Err()
+}
+
+func (d *RedisDenylist) Revoked(ctx context.Context, ids ...string) (bool, error) {
+	if len(ids) == 0 {
+		return false, nil
+	}
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = redisPrefix + id
+	}
+	n, err := d.client.Exists(ctx, keys...).Result()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
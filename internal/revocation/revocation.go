@@ -0,0 +1,36 @@
+package revocation
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"blog-auth/internal/config"
+)
+
+// Denylist remembers revoked token and session ids until the tokens that
+// carry them would have expired anyway.
+type Denylist interface {
+	// Revoke denies id for ttl. A non-positive ttl is a no-op.
+	Revoke(ctx context.Context, id string, ttl time.Duration) error
+	// Revoked reports whether any of ids is denied.
+	Revoked(ctx context.Context, ids ...string) (bool, error)
+}
+
+// TokenKey, SessionKey and ServiceAccountKey namespace the ids a denylist
+// holds. AssertionKey records client assertions that were already used.
+func TokenKey(jti string) string               { return "jti:" + jti }
+func SessionKey(sessionID string) string       { return "sid:" + sessionID }
+func ServiceAccountKey(clientID string) string { return "svc:" + clientID }
+func AssertionKey(clientID, jti string) string { return "jwt:" + clientID + ":" + jti }
+
+func NewDenylist(cfg config.Config) (Denylist, error) {
+	switch cfg.REVOKEBACKEND {
+	case "", "memory":
+		return NewMemoryDenylist(), nil
+	case "redis":
+		return NewRedisDenylist(cfg)
+	default:
+		return nil, fmt.Errorf("unknown revocation backend %q", cfg.REVOKEBACKEND)
+	}
+}
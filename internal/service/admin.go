@@ -2,10 +2,17 @@ package service
 
 import (
 	"context"
+	"errors"
 
 	pb "blog-auth/genproto/userservice"
+	"blog-auth/internal/keys"
 	logger "blog-auth/internal/logger"
+	"blog-auth/internal/revocation"
 	"blog-auth/internal/storage/postgres"
+	"blog-auth/internal/token"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 type AdminService interface {
@@ -14,26 +21,61 @@ type AdminService interface {
 	ForgetPassword(ctx context.Context, req *pb.ForgetPasswordReq) (*pb.ForgetPasswordRes, error)
 	GetAllUsers(ctx context.Context, req *pb.GetAllUsersReq) (*pb.GetAllUsersRes, error)
 	DeleteUser(ctx context.Context, req *pb.DeleteUserReq) (*pb.DeleteUserRes, error)
+	ListFailedEmails(ctx context.Context, req *pb.ListFailedEmailsReq) (*pb.ListFailedEmailsRes, error)
+	ResendEmail(ctx context.Context, req *pb.ResendEmailReq) (*pb.ResendEmailRes, error)
+	UnlockUser(ctx context.Context, req *pb.UnlockUserReq) (*pb.UnlockUserRes, error)
+	ForceLogout(ctx context.Context, req *pb.ForceLogoutReq) (*pb.ForceLogoutRes, error)
+	RotateSigningKey(ctx context.Context, req *pb.RotateSigningKeyReq) (*pb.RotateSigningKeyRes, error)
+	CreateOAuthClient(ctx context.Context, req *pb.CreateOAuthClientReq) (*pb.CreateOAuthClientRes, error)
+	ListOAuthClients(ctx context.Context, req *pb.ListOAuthClientsReq) (*pb.ListOAuthClientsRes, error)
+	DeleteOAuthClient(ctx context.Context, req *pb.DeleteOAuthClientReq) (*pb.DeleteOAuthClientRes, error)
+	CreateServiceAccount(ctx context.Context, req *pb.CreateServiceAccountReq) (*pb.CreateServiceAccountRes, error)
+	ListServiceAccounts(ctx context.Context, req *pb.ListServiceAccountsReq) (*pb.ListServiceAccountsRes, error)
+	DeleteServiceAccount(ctx context.Context, req *pb.DeleteServiceAccountReq) (*pb.DeleteServiceAccountRes, error)
+	CreateRole(ctx context.Context, req *pb.CreateRoleReq) (*pb.CreateRoleRes, error)
+	DeleteRole(ctx context.Context, req *pb.DeleteRoleReq) (*pb.DeleteRoleRes, error)
+	ListRoles(ctx context.Context, req *pb.ListRolesReq) (*pb.ListRolesRes, error)
+	CreatePermission(ctx context.Context, req *pb.CreatePermissionReq) (*pb.CreatePermissionRes, error)
+	ListPermissions(ctx context.Context, req *pb.ListPermissionsReq) (*pb.ListPermissionsRes, error)
+	GrantPermission(ctx context.Context, req *pb.GrantPermissionReq) (*pb.GrantPermissionRes, error)
+	RevokePermission(ctx context.Context, req *pb.RevokePermissionReq) (*pb.RevokePermissionRes, error)
+	AssignRole(ctx context.Context, req *pb.AssignRoleReq) (*pb.AssignRoleRes, error)
+	UnassignRole(ctx context.Context, req *pb.UnassignRoleReq) (*pb.UnassignRoleRes, error)
 }
 type AdminServiceImpl struct {
-	admin postgres.AdminStorage
+	admin       postgres.AdminStorage
+	permissions postgres.PermissionStorage
+	sessions    postgres.SessionStorage
+	denylist    revocation.Denylist
+	tokens      *token.Manager
+	rotator     *keys.Rotator
+	oauth       postgres.OAuthStorage
+	accounts    postgres.ServiceAccountStorage
 	pb.UnimplementedAdminServiceServer
 }
 
-func NewAdminService(admin AdminService) *AdminServiceImpl {
+func NewAdminService(admin postgres.AdminStorage, permissions postgres.PermissionStorage, sessions postgres.SessionStorage, denylist revocation.Denylist, tokens *token.Manager, rotator *keys.Rotator, oauth postgres.OAuthStorage, accounts postgres.ServiceAccountStorage) *AdminServiceImpl {
 	return &AdminServiceImpl{
-		admin: admin,
+		admin:       admin,
+		permissions: permissions,
+		sessions:    sessions,
+		denylist:    denylist,
+		tokens:      tokens,
+		rotator:     rotator,
+		oauth:       oauth,
+		accounts:    accounts,
 	}
 }
 
-func (s *AdminServiceImpl) CreateAdmin(ctx context.Context, req *pb.CreateUserReq) (*pb.CreateUserRes, error) {
+func (s *AdminServiceImpl) CreateUser(ctx context.Context, req *pb.CreateUserReq) (*pb.CreateUserRes, error) {
 	logs, err := logger.NewLogger()
 	if err != nil {
 		return nil, err
 	}
 	resp , err := s.admin.CreateUser(ctx, req)
 	if err != nil {
-		logs.Error("Error while calling CreateAdmin")
+		logs.Error("Error while calling CreateUser")
+		return nil, err
 	}
 	logs.Info("Successfully create admin")
 	return resp, nil
@@ -46,15 +88,30 @@ func (s *AdminServiceImpl) GetUserByID(ctx context.Context, req *pb.GetUserByIDR
 	}
 	
 	resp , err := s.admin.GetUser(ctx,req)
+	if errors.Is(err, postgres.ErrUserNotFound) {
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
 	if err != nil {
 		logs.Error("Error while calling Get User")
+		return nil, err
 	}
 	logs.Info("Successfully get user")
 	return resp, nil
 }
 
 func (s *AdminServiceImpl) ForgetPassword(ctx context.Context, req *pb.ForgetPasswordReq) (*pb.ForgetPasswordRes, error) {
-	return nil, nil
+	logs, err := logger.NewLogger()
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := s.admin.ForgetPassword(ctx, req)
+	if err != nil {
+		logs.Error("Error while calling ForgetPassword")
+		return nil, err
+	}
+	logs.Info("Successfully handled forget password")
+	return resp, nil
 }
 
 func (s *AdminServiceImpl) GetAllAdmins(ctx context.Context, req *pb.GetAllUsersReq) (*pb.GetAllUsersRes, error) {
@@ -84,3 +141,57 @@ func (s *AdminServiceImpl) DeleteUser(ctx context.Context, req *pb.DeleteUserReq
 	logs.Info("Successfully delete admin")
 	return resp,nil
 }
+
+func (s *AdminServiceImpl) ListFailedEmails(ctx context.Context, req *pb.ListFailedEmailsReq) (*pb.ListFailedEmailsRes, error) {
+	logs, err := logger.NewLogger()
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := s.admin.ListFailedEmails(ctx, req)
+	if err != nil {
+		logs.Error("Error while listing failed emails")
+		return nil, err
+	}
+	logs.Info("Successfully list failed emails")
+	return resp, nil
+}
+
+func (s *AdminServiceImpl) ResendEmail(ctx context.Context, req *pb.ResendEmailReq) (*pb.ResendEmailRes, error) {
+	logs, err := logger.NewLogger()
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := s.admin.ResendEmail(ctx, req)
+	if errors.Is(err, postgres.ErrEmailNotFound) {
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
+	if errors.Is(err, postgres.ErrEmailCleared) {
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	}
+	if err != nil {
+		logs.Error("Error while resending email")
+		return nil, err
+	}
+	logs.Info("Successfully queued email for resend")
+	return resp, nil
+}
+
+func (s *AdminServiceImpl) UnlockUser(ctx context.Context, req *pb.UnlockUserReq) (*pb.UnlockUserRes, error) {
+	logs, err := logger.NewLogger()
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := s.admin.UnlockUser(ctx, req)
+	if errors.Is(err, postgres.ErrUserNotFound) {
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
+	if err != nil {
+		logs.Error("Error while unlocking user")
+		return nil, err
+	}
+	logs.Info("Successfully unlocked user")
+	return resp, nil
+}
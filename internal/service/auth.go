@@ -0,0 +1,20 @@
+package service
+
+import (
+	"context"
+
+	"blog-auth/internal/interceptor"
+	"blog-auth/internal/token"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// currentUser returns the caller the auth interceptor authenticated.
+func currentUser(ctx context.Context) (*token.Claims, error) {
+	claims, ok := interceptor.PrincipalFrom(ctx)
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "missing bearer token")
+	}
+	return claims, nil
+}
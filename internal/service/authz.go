@@ -0,0 +1,55 @@
+package service
+
+import (
+	"context"
+
+	pb "blog-auth/genproto/userservice"
+	"blog-auth/internal/authz"
+	"blog-auth/internal/interceptor"
+	logger "blog-auth/internal/logger"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Enforce evaluates the policy engine for other services. An empty subject
+// checks the caller; checking another user requires an admin token. Rules
+// apply to the user id and to every role the user holds.
+func (s *UserServiceImpl) Enforce(ctx context.Context, req *pb.EnforceReq) (*pb.EnforceRes, error) {
+	logs, err := logger.NewLogger()
+	if err != nil {
+		return nil, err
+	}
+	claims, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if req.Object == "" || req.Action == "" {
+		return nil, status.Error(codes.InvalidArgument, "object and action are required")
+	}
+	subject := claims.UserID
+	if req.Subject != "" && req.Subject != claims.UserID {
+		if !interceptor.HasRole(claims, interceptor.RoleAdmin, interceptor.RoleSuperAdmin) {
+			return nil, status.Error(codes.PermissionDenied, "permission denied")
+		}
+		subject = req.Subject
+	}
+	access, err := s.permissions.UserAccess(ctx, subject)
+	if err != nil {
+		logs.Error("Error while getting user access")
+		return nil, permissionError(err)
+	}
+	allowed, err := s.authz.EnforceRequest(authz.Request{
+		Subject: subject,
+		Roles:   append([]string{access.Role}, access.Roles...),
+		Domain:  req.Domain,
+		Object:  req.Object,
+		Action:  req.Action,
+		Owner:   req.Owner,
+	})
+	if err != nil {
+		logs.Error("Error while calling Enforce")
+		return nil, err
+	}
+	return &pb.EnforceRes{Allowed: allowed}, nil
+}
@@ -0,0 +1,132 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	pb "blog-auth/genproto/userservice"
+	"blog-auth/internal/interceptor"
+	logger "blog-auth/internal/logger"
+	"blog-auth/internal/storage/postgres"
+	"blog-auth/internal/token"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// browserDevice names the sessions of users signed in on the HTTP pages.
+const browserDevice = "Browser"
+
+// signInCodeTTL is how long the app has to send the browser back with a
+// sign-in code.
+const signInCodeTTL = time.Minute
+
+// StartBrowserSession records a session for a user who signed in on one of
+// the HTTP pages and returns the cookie that resumes it. It lasts as long as a
+// refresh token and is listed and revoked like any other session.
+func (s *UserServiceImpl) StartBrowserSession(ctx context.Context, user *pb.UserModel) (string, time.Time, error) {
+	logs, err := logger.NewLogger()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	expires := time.Now().Add(s.tokens.RefreshTTL())
+	sessionID, err := s.sessions.CreateSession(ctx, user.Id, postgres.Device{
+		Name:      browserDevice,
+		UserAgent: interceptor.UserAgent(ctx),
+		IP:        interceptor.ClientIP(ctx),
+	}, expires)
+	if err != nil {
+		logs.Error("Error while creating browser session")
+		return "", time.Time{}, err
+	}
+	return s.tokens.SessionCookie(sessionID, expires), expires, nil
+}
+
+// CreateSignInCode lets the app sign its user in on the HTTP pages, when they
+// signed in to the app in a way the pages do not offer, passkeys and login
+// links included. The code hands the caller's session over once, only to the
+// browser the pages sent to SIGNINURL with handoff, and only for a minute.
+func (s *UserServiceImpl) CreateSignInCode(ctx context.Context, req *pb.CreateSignInCodeReq) (*pb.CreateSignInCodeRes, error) {
+	logs, err := logger.NewLogger()
+	if err != nil {
+		return nil, err
+	}
+	claims, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if claims.SessionID == "" {
+		return nil, status.Error(codes.PermissionDenied, "the token has no session to hand over")
+	}
+	if req.Handoff == "" {
+		return nil, status.Error(codes.InvalidArgument, "handoff is required")
+	}
+	code, hash, err := token.NewOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+	err = s.sessions.CreateSignInCode(ctx, claims.SessionID, hash, token.HashOpaqueToken(req.Handoff), time.Now().Add(signInCodeTTL))
+	if errors.Is(err, postgres.ErrSessionNotFound) {
+		return nil, status.Error(codes.Unauthenticated, "session has been revoked")
+	}
+	if err != nil {
+		logs.Error("Error while creating sign-in code")
+		return nil, err
+	}
+	logs.Info("Successfully created sign-in code")
+	return &pb.CreateSignInCodeRes{Code: code, ExpiresIn: int64(signInCodeTTL.Seconds())}, nil
+}
+
+// RedeemSignInCode returns a cookie for the session a sign-in code hands
+// over, when the code was created for handoff. The cookie expires with the
+// session.
+func (s *UserServiceImpl) RedeemSignInCode(ctx context.Context, code, handoff string) (string, time.Time, error) {
+	logs, err := logger.NewLogger()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	if code == "" || handoff == "" {
+		return "", time.Time{}, status.Error(codes.Unauthenticated, "sign-in code is invalid or expired")
+	}
+	sessionID, expires, err := s.sessions.ConsumeSignInCode(ctx, token.HashOpaqueToken(code), token.HashOpaqueToken(handoff))
+	if errors.Is(err, postgres.ErrSignInCodeInvalid) {
+		return "", time.Time{}, status.Error(codes.Unauthenticated, err.Error())
+	}
+	if err != nil {
+		logs.Error("Error while redeeming sign-in code")
+		return "", time.Time{}, err
+	}
+	return s.tokens.SessionCookie(sessionID, expires), expires, nil
+}
+
+// BrowserSession returns the user a session cookie signs in and when they
+// signed in. A nil user means the cookie is invalid or expired, or its
+// session was revoked.
+func (s *UserServiceImpl) BrowserSession(ctx context.Context, cookie string) (*pb.UserModel, time.Time, error) {
+	logs, err := logger.NewLogger()
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	sessionID, ok := s.tokens.ParseSessionCookie(cookie)
+	if !ok {
+		return nil, time.Time{}, nil
+	}
+	userID, authTime, err := s.sessions.SessionUser(ctx, sessionID)
+	if errors.Is(err, postgres.ErrSessionNotFound) {
+		return nil, time.Time{}, nil
+	}
+	if err != nil {
+		logs.Error("Error while getting browser session")
+		return nil, time.Time{}, err
+	}
+	user, err := s.auth.GetUser(ctx, userID)
+	if errors.Is(err, postgres.ErrUserNotFound) {
+		return nil, time.Time{}, nil
+	}
+	if err != nil {
+		logs.Error("Error while getting browser session user")
+		return nil, time.Time{}, err
+	}
+	return user, authTime, nil
+}
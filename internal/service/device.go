@@ -0,0 +1,175 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+	"time"
+
+	pb "blog-auth/genproto/userservice"
+	logger "blog-auth/internal/logger"
+	"blog-auth/internal/storage/postgres"
+	"blog-auth/internal/token"
+)
+
+// DeviceAuthorizationRequest holds the parameters of the device authorization
+// endpoint.
+type DeviceAuthorizationRequest struct {
+	ClientID     string
+	ClientSecret string
+	Scope        string
+}
+
+// DeviceAuthorizationResponse tells a device what to show the user and how
+// often to poll the token endpoint.
+type DeviceAuthorizationResponse struct {
+	DeviceCode              string `json:"device_code"`
+	UserCode                string `json:"user_code"`
+	VerificationURI         string `json:"verification_uri"`
+	VerificationURIComplete string `json:"verification_uri_complete"`
+	ExpiresIn               int    `json:"expires_in"`
+	Interval                int    `json:"interval"`
+}
+
+// DeviceAuthorization starts the device authorization grant of RFC 8628 for
+// clients that cannot show a browser, such as CLIs and TVs.
+func (s *OAuthService) DeviceAuthorization(ctx context.Context, req DeviceAuthorizationRequest) (*DeviceAuthorizationResponse, error) {
+	logs, err := logger.NewLogger()
+	if err != nil {
+		return nil, err
+	}
+	client, err := s.authenticateClient(ctx, req.ClientID, req.ClientSecret)
+	if err != nil {
+		return nil, err
+	}
+	scopes, err := s.checkScopes(client, req.Scope)
+	if err != nil {
+		return nil, err
+	}
+	deviceCode, hash, err := token.NewOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+	userCode, err := token.NewUserCode()
+	if err != nil {
+		return nil, err
+	}
+	err = s.oauth.CreateDeviceCode(ctx, postgres.DeviceCode{
+		Hash:      hash,
+		UserCode:  userCode,
+		ClientID:  client.ClientId,
+		Scope:     strings.Join(scopes, " "),
+		Interval:  s.deviceInterval,
+		ExpiresAt: time.Now().Add(s.deviceTTL),
+	})
+	if err != nil {
+		logs.Error("Error while creating device code")
+		return nil, err
+	}
+	verificationURI := s.issuer + "/oauth/device"
+	logs.Info("Successfully started device authorization")
+	return &DeviceAuthorizationResponse{
+		DeviceCode:              deviceCode,
+		UserCode:                userCode,
+		VerificationURI:         verificationURI,
+		VerificationURIComplete: verificationURI + "?user_code=" + url.QueryEscape(userCode),
+		ExpiresIn:               int(s.deviceTTL.Seconds()),
+		Interval:                int(s.deviceInterval.Seconds()),
+	}, nil
+}
+
+// DeviceRequest returns the client and scopes waiting for the user who typed
+// userCode on the verification page.
+func (s *OAuthService) DeviceRequest(ctx context.Context, userCode string) (*pb.OAuthClient, []string, error) {
+	code, err := s.oauth.PendingDeviceCode(ctx, token.NormalizeUserCode(userCode))
+	if errors.Is(err, postgres.ErrDeviceCodeInvalid) {
+		return nil, nil, oauthError("invalid_request", "the code is invalid or has expired")
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	client, _, err := s.oauth.GetClient(ctx, code.ClientID)
+	if errors.Is(err, postgres.ErrOAuthClientNotFound) {
+		return nil, nil, oauthError("invalid_request", "the code is invalid or has expired")
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return client, strings.Fields(code.Scope), nil
+}
+
+// ResolveDevice records whether the signed in user approved the device
+// authorization of userCode. Approval counts as consent to its scopes.
+func (s *OAuthService) ResolveDevice(ctx context.Context, user *pb.UserModel, userCode string, approved bool) error {
+	logs, err := logger.NewLogger()
+	if err != nil {
+		return err
+	}
+	userCode = token.NormalizeUserCode(userCode)
+	client, scopes, err := s.DeviceRequest(ctx, userCode)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return errors.New("resolving a device needs a user")
+	}
+	userID := user.Id
+	if approved {
+		if len(scopes) > 0 {
+			if err = s.oauth.SaveConsent(ctx, userID, client.ClientId, scopes); err != nil {
+				logs.Error("Error while saving oauth consent")
+				return err
+			}
+		}
+	}
+	err = s.oauth.ResolveDeviceCode(ctx, userCode, userID, approved)
+	if errors.Is(err, postgres.ErrDeviceCodeInvalid) {
+		return oauthError("invalid_request", "the code is invalid or has expired")
+	}
+	if err != nil {
+		logs.Error("Error while resolving device code")
+		return err
+	}
+	logs.Info("Successfully resolved device authorization")
+	return nil
+}
+
+// pollDevice answers the device's polls with authorization_pending until the
+// user decides, and issues tokens once after approval.
+func (s *OAuthService) pollDevice(ctx context.Context, client *pb.OAuthClient, req TokenRequest) (*TokenResponse, error) {
+	logs, err := logger.NewLogger()
+	if err != nil {
+		return nil, err
+	}
+	code, err := s.oauth.PollDeviceCode(ctx, token.HashOpaqueToken(req.DeviceCode), client.ClientId)
+	switch {
+	case errors.Is(err, postgres.ErrDevicePending):
+		return nil, oauthError("authorization_pending", err.Error())
+	case errors.Is(err, postgres.ErrDeviceSlowDown):
+		return nil, oauthError("slow_down", err.Error())
+	case errors.Is(err, postgres.ErrDeviceDenied):
+		return nil, oauthError("access_denied", err.Error())
+	case errors.Is(err, postgres.ErrDeviceCodeExpired):
+		return nil, oauthError("expired_token", err.Error())
+	case errors.Is(err, postgres.ErrDeviceCodeInvalid):
+		return nil, oauthError("invalid_grant", err.Error())
+	case err != nil:
+		logs.Error("Error while polling device code")
+		return nil, err
+	}
+	user, err := s.users.auth.GetUser(ctx, code.UserID)
+	if errors.Is(err, postgres.ErrUserNotFound) {
+		return nil, oauthError("invalid_grant", "user no longer exists")
+	}
+	if err != nil {
+		logs.Error("Error while getting user")
+		return nil, err
+	}
+	resp, err := s.grant(ctx, user, client, code.Scope, token.IDClaims{})
+	if err != nil {
+		return nil, err
+	}
+	logs.Info("Successfully authorized device")
+	return resp, nil
+}
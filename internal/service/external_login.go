@@ -0,0 +1,128 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	pb "blog-auth/genproto/userservice"
+	"blog-auth/internal/idp"
+	logger "blog-auth/internal/logger"
+	"blog-auth/internal/storage/postgres"
+	"blog-auth/internal/token"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ListIdentityProviders lists the upstream providers users can sign in with.
+func (s *UserServiceImpl) ListIdentityProviders(ctx context.Context, req *pb.ListIdentityProvidersReq) (*pb.ListIdentityProvidersRes, error) {
+	res := &pb.ListIdentityProvidersRes{}
+	for _, p := range s.providers.Providers() {
+		res.Providers = append(res.Providers, &pb.IdentityProvider{Name: p.Name(), DisplayName: p.DisplayName()})
+	}
+	return res, nil
+}
+
+// BeginExternalLogin starts a login with an upstream provider. The state,
+// nonce and PKCE verifier are kept server-side until the callback.
+func (s *UserServiceImpl) BeginExternalLogin(ctx context.Context, req *pb.BeginExternalLoginReq) (*pb.BeginExternalLoginRes, error) {
+	logs, err := logger.NewLogger()
+	if err != nil {
+		return nil, err
+	}
+	provider, ok := s.providers.Get(req.Provider)
+	if !ok {
+		return nil, status.Error(codes.InvalidArgument, "unknown identity provider")
+	}
+	state, stateHash, err := token.NewOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+	nonce, _, err := token.NewOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+	verifier, _, err := token.NewOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+	authURL, err := provider.AuthCodeURL(ctx, state, nonce, verifier)
+	if err != nil {
+		logs.Error("Error while discovering identity provider", zap.String("provider", provider.Name()), zap.Error(err))
+		return nil, status.Error(codes.Unavailable, "identity provider is unavailable")
+	}
+	err = s.identities.CreateLoginState(ctx, postgres.LoginState{
+		Hash:         stateHash,
+		Provider:     provider.Name(),
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+	})
+	if err != nil {
+		logs.Error("Error while creating external login state")
+		return nil, err
+	}
+	logs.Info("Successfully started external login")
+	return &pb.BeginExternalLoginRes{AuthorizationUrl: authURL, State: state}, nil
+}
+
+// FinishExternalLogin redeems the code from the provider's callback and logs
+// in the user its id_token names, linking or creating the account on first
+// use.
+func (s *UserServiceImpl) FinishExternalLogin(ctx context.Context, req *pb.FinishExternalLoginReq) (*pb.LoginRes, error) {
+	logs, err := logger.NewLogger()
+	if err != nil {
+		return nil, err
+	}
+	state, err := s.identities.ConsumeLoginState(ctx, token.HashOpaqueToken(req.State))
+	if errors.Is(err, postgres.ErrExternalLoginInvalid) {
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+	if err != nil {
+		logs.Error("Error while consuming external login state")
+		return nil, err
+	}
+	provider, ok := s.providers.Get(state.Provider)
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, postgres.ErrExternalLoginInvalid.Error())
+	}
+	claims, err := provider.Exchange(ctx, req.Code, state.CodeVerifier, state.Nonce)
+	if errors.Is(err, idp.ErrLoginRejected) {
+		logs.Info("External login rejected", zap.String("provider", provider.Name()), zap.Error(err))
+		return nil, status.Error(codes.Unauthenticated, "identity provider rejected the login")
+	}
+	if err != nil {
+		logs.Error("Error while exchanging external login code", zap.String("provider", provider.Name()), zap.Error(err))
+		return nil, status.Error(codes.Unavailable, "identity provider is unavailable")
+	}
+
+	resp, err := s.identities.ExternalLogin(ctx, postgres.ExternalIdentity{
+		Provider:      provider.Name(),
+		Subject:       claims.Subject,
+		Email:         claims.Email,
+		EmailVerified: claims.EmailVerified,
+		GivenName:     claims.GivenName,
+		FamilyName:    claims.FamilyName,
+		Picture:       claims.Picture,
+	}, provider.Signup())
+	switch {
+	case errors.Is(err, postgres.ErrIdentityNotVerified), errors.Is(err, postgres.ErrIdentitySignupDisabled):
+		return nil, status.Error(codes.PermissionDenied, err.Error())
+	case errors.Is(err, postgres.ErrAccountNotVerified):
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	case err != nil:
+		logs.Error("Error while calling ExternalLogin")
+		return nil, err
+	}
+	if resp.MfaRequired {
+		logs.Info("External login requires second factor")
+		return resp, nil
+	}
+	resp.AccessToken, resp.RefreshToken, resp.ExpiresIn, err = s.issueTokens(ctx, resp.UserRes)
+	if err != nil {
+		logs.Error("Error while issuing tokens")
+		return nil, err
+	}
+	logs.Info("Successfully login with external identity provider")
+	return resp, nil
+}
@@ -0,0 +1,54 @@
+package service
+
+import (
+	"context"
+
+	pb "blog-auth/genproto/userservice"
+	"blog-auth/internal/storage/postgres"
+)
+
+// SecondFactor verifies one kind of proof presented to VerifyMFA for the user
+// the MFA challenge was issued to. New authenticators plug into the login flow
+// by implementing it and being added to UserServiceImpl.factors.
+type SecondFactor interface {
+	// Presented reports whether req carries this kind of proof.
+	Presented(req *pb.VerifyMFAReq) bool
+	Verify(ctx context.Context, userID string, req *pb.VerifyMFAReq) error
+}
+
+type totpFactor struct {
+	mfa postgres.MFAStorage
+}
+
+func (f totpFactor) Presented(req *pb.VerifyMFAReq) bool {
+	return req.Code != ""
+}
+
+func (f totpFactor) Verify(ctx context.Context, userID string, req *pb.VerifyMFAReq) error {
+	return f.mfa.VerifyTOTP(ctx, userID, req.Code)
+}
+
+type recoveryCodeFactor struct {
+	mfa postgres.MFAStorage
+}
+
+func (f recoveryCodeFactor) Presented(req *pb.VerifyMFAReq) bool {
+	return req.RecoveryCode != ""
+}
+
+func (f recoveryCodeFactor) Verify(ctx context.Context, userID string, req *pb.VerifyMFAReq) error {
+	return f.mfa.UseRecoveryCode(ctx, userID, req.RecoveryCode)
+}
+
+type passkeyFactor struct {
+	passkeys *passkeyAuthenticator
+}
+
+func (f passkeyFactor) Presented(req *pb.VerifyMFAReq) bool {
+	return req.PasskeySessionId != "" && req.PasskeyCredential != ""
+}
+
+func (f passkeyFactor) Verify(ctx context.Context, userID string, req *pb.VerifyMFAReq) error {
+	_, err := f.passkeys.verifySecondFactor(ctx, userID, req.PasskeySessionId, req.PasskeyCredential)
+	return err
+}
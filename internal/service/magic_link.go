@@ -0,0 +1,57 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	pb "blog-auth/genproto/userservice"
+	logger "blog-auth/internal/logger"
+	"blog-auth/internal/storage/postgres"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func (s *UserServiceImpl) RequestMagicLink(ctx context.Context, req *pb.RequestMagicLinkReq) (*pb.RequestMagicLinkRes, error) {
+	logs, err := logger.NewLogger()
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.magicLinks.RequestMagicLink(ctx, req)
+	if err != nil {
+		logs.Error("Error while calling RequestMagicLink")
+		return nil, err
+	}
+	logs.Info("Successfully handled magic link request")
+	return resp, nil
+}
+
+func (s *UserServiceImpl) ConsumeMagicLink(ctx context.Context, req *pb.ConsumeMagicLinkReq) (*pb.LoginRes, error) {
+	logs, err := logger.NewLogger()
+	if err != nil {
+		return nil, err
+	}
+	resp, revoked, err := s.magicLinks.ConsumeMagicLink(ctx, req)
+	if errors.Is(err, postgres.ErrMagicLinkInvalid) {
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+	if err != nil {
+		logs.Error("Error while calling ConsumeMagicLink")
+		return nil, err
+	}
+	if err = denySessions(ctx, s.denylist, s.tokens.AccessTTL(), revoked); err != nil {
+		logs.Error("Error while revoking session tokens")
+		return nil, err
+	}
+	if resp.MfaRequired {
+		logs.Info("Magic link login requires second factor")
+		return resp, nil
+	}
+	resp.AccessToken, resp.RefreshToken, resp.ExpiresIn, err = s.issueTokens(ctx, resp.UserRes)
+	if err != nil {
+		logs.Error("Error while issuing tokens")
+		return nil, err
+	}
+	logs.Info("Successfully login with magic link")
+	return resp, nil
+}
@@ -0,0 +1,570 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	pb "blog-auth/genproto/userservice"
+	"blog-auth/internal/config"
+	"blog-auth/internal/interceptor"
+	logger "blog-auth/internal/logger"
+	"blog-auth/internal/storage/postgres"
+	"blog-auth/internal/token"
+
+	"github.com/golang-jwt/jwt/v5"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	ScopeOpenID        = "openid"
+	ScopeProfile       = "profile"
+	ScopeEmail         = "email"
+	ScopeOfflineAccess = "offline_access"
+
+	grantAuthorizationCode = "authorization_code"
+	grantRefreshToken      = "refresh_token"
+	grantClientCredentials = "client_credentials"
+	grantDeviceCode        = "urn:ietf:params:oauth:grant-type:device_code"
+	assertionTypeJWTBearer = "urn:ietf:params:oauth:client-assertion-type:jwt-bearer"
+	pkceMethodS256         = "S256"
+)
+
+var (
+	scopePattern        = regexp.MustCompile(`^[a-z][a-z0-9_.:-]{0,63}$`)
+	codeChallengeFormat = regexp.MustCompile(`^[A-Za-z0-9_-]{43}$`)
+	codeVerifierFormat  = regexp.MustCompile(`^[A-Za-z0-9._~-]{43,128}$`)
+)
+
+// OAuthError is reported to OAuth clients as the error and error_description
+// parameters of RFC 6749.
+type OAuthError struct {
+	Code        string
+	Description string
+}
+
+func (e *OAuthError) Error() string {
+	return e.Code + ": " + e.Description
+}
+
+func oauthError(code, description string) error {
+	return &OAuthError{Code: code, Description: description}
+}
+
+// AuthorizationRequest holds the parameters of the authorization endpoint.
+type AuthorizationRequest struct {
+	ClientID            string
+	RedirectURI         string
+	ResponseType        string
+	Scope               string
+	State               string
+	CodeChallenge       string
+	CodeChallengeMethod string
+	// Nonce and Prompt are OpenID Connect parameters.
+	Nonce  string
+	Prompt string
+}
+
+// TokenRequest holds the parameters of the token endpoint.
+type TokenRequest struct {
+	GrantType    string
+	Code         string
+	RedirectURI  string
+	CodeVerifier string
+	RefreshToken string
+	DeviceCode   string
+	ClientID     string
+	ClientSecret string
+	// Scope, ClientAssertionType and ClientAssertion are used by service
+	// accounts in the client credentials grant.
+	Scope               string
+	ClientAssertionType string
+	ClientAssertion     string
+}
+
+// TokenResponse is the successful answer of the token endpoint.
+type TokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	Scope        string `json:"scope,omitempty"`
+	IDToken      string `json:"id_token,omitempty"`
+}
+
+// UserInfoResponse is the answer of the OpenID Connect userinfo endpoint.
+type UserInfoResponse struct {
+	Subject string `json:"sub"`
+	token.UserInfo
+}
+
+// OAuthService implements the authorization code grant with PKCE on top of
+// the user service: users sign in with the same credential check as Login,
+// and every grant becomes a session scoped to the client. With the openid
+// scope it acts as an OpenID Connect provider.
+type OAuthService struct {
+	users          *UserServiceImpl
+	oauth          postgres.OAuthStorage
+	accounts       postgres.ServiceAccountStorage
+	codeTTL        time.Duration
+	deviceTTL      time.Duration
+	deviceInterval time.Duration
+	issuer         string
+	signInURL      string
+}
+
+func NewOAuthService(users *UserServiceImpl, oauth postgres.OAuthStorage, accounts postgres.ServiceAccountStorage, cfg config.Config) *OAuthService {
+	return &OAuthService{
+		users:          users,
+		oauth:          oauth,
+		accounts:       accounts,
+		codeTTL:        cfg.OAUTHCODETTL,
+		deviceTTL:      cfg.DEVICECODETTL,
+		deviceInterval: cfg.DEVICEINTERVAL,
+		issuer:         strings.TrimSuffix(cfg.OIDCISSUER, "/"),
+		signInURL:      cfg.SIGNINURL,
+	}
+}
+
+// Issuer returns the OpenID Connect issuer, the base URL of the HTTP API.
+func (s *OAuthService) Issuer() string {
+	return s.issuer
+}
+
+// HasSignInURL reports whether SIGNINURL is set, where users can sign in
+// other ways than with a password.
+func (s *OAuthService) HasSignInURL() bool {
+	return s.signInURL != ""
+}
+
+// SignInURL returns the SIGNINURL page, asked to get a sign-in code for
+// handoff and send the browser back with it to returnTo.
+func (s *OAuthService) SignInURL(returnTo, handoff string) string {
+	u, err := url.Parse(s.signInURL)
+	if err != nil {
+		return ""
+	}
+	q := u.Query()
+	q.Set("return_to", s.issuer+returnTo)
+	q.Set("handoff", handoff)
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+// SigningAlgorithm returns the algorithm ID tokens are signed with.
+func (s *OAuthService) SigningAlgorithm() string {
+	return s.users.tokens.Algorithm()
+}
+
+// Client looks up the client of an authorization request and checks its
+// redirect URI. Until both are known good, errors must be shown to the user
+// instead of being sent to the redirect URI.
+func (s *OAuthService) Client(ctx context.Context, req AuthorizationRequest) (*pb.OAuthClient, error) {
+	client, _, err := s.oauth.GetClient(ctx, req.ClientID)
+	if errors.Is(err, postgres.ErrOAuthClientNotFound) {
+		return nil, oauthError("invalid_client", "unknown client")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !contains(client.RedirectUris, req.RedirectURI) {
+		return nil, oauthError("invalid_request", "redirect_uri is not registered for this client")
+	}
+	return client, nil
+}
+
+// CheckAuthorization validates the rest of an authorization request and
+// returns the requested scopes. PKCE with S256 is mandatory for every client.
+func (s *OAuthService) CheckAuthorization(client *pb.OAuthClient, req AuthorizationRequest) ([]string, error) {
+	if req.ResponseType != "code" {
+		return nil, oauthError("unsupported_response_type", "only response_type=code is supported")
+	}
+	if req.CodeChallengeMethod != pkceMethodS256 || !codeChallengeFormat.MatchString(req.CodeChallenge) {
+		return nil, oauthError("invalid_request", "a code_challenge with code_challenge_method=S256 is required")
+	}
+	scopes, err := s.checkScopes(client, req.Scope)
+	if err != nil {
+		return nil, err
+	}
+	if len(req.Nonce) > 255 {
+		return nil, oauthError("invalid_request", "nonce is too long")
+	}
+	return scopes, nil
+}
+
+// checkScopes returns the scopes of a space separated scope parameter if the
+// client may ask for all of them.
+func (s *OAuthService) checkScopes(client *pb.OAuthClient, scope string) ([]string, error) {
+	scopes := strings.Fields(scope)
+	for _, scope := range scopes {
+		if !contains(client.Scopes, scope) {
+			return nil, oauthError("invalid_scope", "scope "+scope+" is not allowed for this client")
+		}
+	}
+	if contains(scopes, ScopeOpenID) && s.SigningAlgorithm() == token.AlgHS256 {
+		return nil, oauthError("invalid_scope", "openid is not available while tokens are signed with a shared secret")
+	}
+	return scopes, nil
+}
+
+// Authorize records the user's consent and issues an authorization code for
+// the request. authTime is when the user signed in, which may be before the
+// request when their session was reused.
+func (s *OAuthService) Authorize(ctx context.Context, user *pb.UserModel, req AuthorizationRequest, scopes []string, authTime time.Time) (string, error) {
+	logs, err := logger.NewLogger()
+	if err != nil {
+		return "", err
+	}
+	if len(scopes) > 0 {
+		if err = s.oauth.SaveConsent(ctx, user.Id, req.ClientID, scopes); err != nil {
+			logs.Error("Error while saving oauth consent")
+			return "", err
+		}
+	}
+	code, hash, err := token.NewOpaqueToken()
+	if err != nil {
+		return "", err
+	}
+	err = s.oauth.CreateAuthorizationCode(ctx, postgres.AuthorizationCode{
+		Hash:          hash,
+		ClientID:      req.ClientID,
+		UserID:        user.Id,
+		RedirectURI:   req.RedirectURI,
+		Scope:         strings.Join(scopes, " "),
+		CodeChallenge: req.CodeChallenge,
+		Nonce:         req.Nonce,
+		ExpiresAt:     time.Now().Add(s.codeTTL),
+		AuthTime:      authTime,
+	})
+	if err != nil {
+		logs.Error("Error while creating authorization code")
+		return "", err
+	}
+	logs.Info("Successfully authorized oauth client")
+	return code, nil
+}
+
+// Token serves the token endpoint for the authorization_code, device_code and
+// refresh_token grants of OAuth clients and the client_credentials grant of
+// service accounts.
+func (s *OAuthService) Token(ctx context.Context, req TokenRequest) (*TokenResponse, error) {
+	if req.GrantType == grantClientCredentials {
+		return s.clientCredentials(ctx, req)
+	}
+	client, err := s.authenticateClient(ctx, req.ClientID, req.ClientSecret)
+	if err != nil {
+		return nil, err
+	}
+	switch req.GrantType {
+	case grantAuthorizationCode:
+		return s.exchangeCode(ctx, client, req)
+	case grantDeviceCode:
+		return s.pollDevice(ctx, client, req)
+	case grantRefreshToken:
+		return s.refresh(ctx, client, req)
+	}
+	return nil, oauthError("unsupported_grant_type", "grant_type must be authorization_code, device_code, refresh_token or client_credentials")
+}
+
+// authenticateClient checks the secret of confidential clients. Public
+// clients have none and rely on PKCE.
+func (s *OAuthService) authenticateClient(ctx context.Context, clientID, secret string) (*pb.OAuthClient, error) {
+	client, secretHash, err := s.oauth.GetClient(ctx, clientID)
+	if errors.Is(err, postgres.ErrOAuthClientNotFound) {
+		return nil, oauthError("invalid_client", "client authentication failed")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if secretHash == "" {
+		if secret != "" {
+			return nil, oauthError("invalid_client", "client authentication failed")
+		}
+		return client, nil
+	}
+	if subtle.ConstantTimeCompare([]byte(token.HashOpaqueToken(secret)), []byte(secretHash)) != 1 {
+		return nil, oauthError("invalid_client", "client authentication failed")
+	}
+	return client, nil
+}
+
+func (s *OAuthService) exchangeCode(ctx context.Context, client *pb.OAuthClient, req TokenRequest) (*TokenResponse, error) {
+	logs, err := logger.NewLogger()
+	if err != nil {
+		return nil, err
+	}
+	if !codeVerifierFormat.MatchString(req.CodeVerifier) {
+		return nil, oauthError("invalid_request", "a valid code_verifier is required")
+	}
+	code, err := s.oauth.ConsumeAuthorizationCode(ctx, token.HashOpaqueToken(req.Code))
+	if errors.Is(err, postgres.ErrAuthorizationCodeInvalid) {
+		return nil, oauthError("invalid_grant", err.Error())
+	}
+	if err != nil {
+		logs.Error("Error while consuming authorization code")
+		return nil, err
+	}
+	if code.ClientID != client.ClientId || code.RedirectURI != req.RedirectURI {
+		return nil, oauthError("invalid_grant", "authorization code was issued for another client or redirect_uri")
+	}
+	sum := sha256.Sum256([]byte(req.CodeVerifier))
+	challenge := base64.RawURLEncoding.EncodeToString(sum[:])
+	if subtle.ConstantTimeCompare([]byte(challenge), []byte(code.CodeChallenge)) != 1 {
+		return nil, oauthError("invalid_grant", "code_verifier does not match the code_challenge")
+	}
+
+	user, err := s.users.auth.GetUser(ctx, code.UserID)
+	if errors.Is(err, postgres.ErrUserNotFound) {
+		return nil, oauthError("invalid_grant", "user no longer exists")
+	}
+	if err != nil {
+		logs.Error("Error while getting user")
+		return nil, err
+	}
+	resp, err := s.grant(ctx, user, client, code.Scope, token.IDClaims{
+		Nonce:    code.Nonce,
+		AuthTime: jwt.NewNumericDate(code.AuthTime),
+	})
+	if err != nil {
+		return nil, err
+	}
+	logs.Info("Successfully exchanged authorization code")
+	return resp, nil
+}
+
+// grant starts a session of the user with the client and issues its tokens:
+// a refresh token with offline_access and an ID token with openid.
+func (s *OAuthService) grant(ctx context.Context, user *pb.UserModel, client *pb.OAuthClient, scope string, claims token.IDClaims) (*TokenResponse, error) {
+	logs, err := logger.NewLogger()
+	if err != nil {
+		return nil, err
+	}
+	device := postgres.Device{Name: client.Name, ClientID: client.ClientId, Scope: scope}
+	offline := contains(strings.Fields(scope), ScopeOfflineAccess)
+	access, refresh, _, err := s.users.startSession(ctx, user, device, offline)
+	if err != nil {
+		logs.Error("Error while issuing tokens")
+		return nil, err
+	}
+	resp := s.tokenResponse(access, refresh, scope)
+	resp.IDToken, err = s.idToken(user, client.ClientId, scope, claims)
+	if err != nil {
+		logs.Error("Error while generating id token")
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (s *OAuthService) refresh(ctx context.Context, client *pb.OAuthClient, req TokenRequest) (*TokenResponse, error) {
+	logs, err := logger.NewLogger()
+	if err != nil {
+		return nil, err
+	}
+	refresh, hash, err := token.NewRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+	tokens := s.users.tokens
+	user, grant, err := s.users.refresh.RotateRefreshToken(ctx, token.HashRefreshToken(req.RefreshToken), hash, client.ClientId, interceptor.ClientIP(ctx), time.Now().Add(tokens.RefreshTTL()))
+	if errors.Is(err, postgres.ErrRefreshTokenInvalid) || errors.Is(err, postgres.ErrRefreshTokenReused) {
+		return nil, oauthError("invalid_grant", err.Error())
+	}
+	if err != nil {
+		logs.Error("Error while rotating refresh token")
+		return nil, err
+	}
+	access, err := s.users.accessToken(ctx, user, grant)
+	if err != nil {
+		logs.Error("Error while generating access token")
+		return nil, err
+	}
+	resp := s.tokenResponse(access, refresh, grant.Scope)
+	resp.IDToken, err = s.idToken(user, client.ClientId, grant.Scope, token.IDClaims{})
+	if err != nil {
+		logs.Error("Error while generating id token")
+		return nil, err
+	}
+	logs.Info("Successfully refreshed oauth token")
+	return resp, nil
+}
+
+// idToken returns an ID token for grants with the openid scope and an empty
+// string for plain OAuth grants.
+func (s *OAuthService) idToken(user *pb.UserModel, clientID, scope string, claims token.IDClaims) (string, error) {
+	scopes := strings.Fields(scope)
+	if !contains(scopes, ScopeOpenID) {
+		return "", nil
+	}
+	claims.UserInfo = userInfo(user, scopes)
+	return s.users.tokens.GenerateIDToken(s.issuer, user.Id, clientID, claims)
+}
+
+// UserInfo serves the userinfo endpoint: it returns the claims of the user an
+// access token with the openid scope was issued for.
+func (s *OAuthService) UserInfo(ctx context.Context, accessToken string) (*UserInfoResponse, error) {
+	logs, err := logger.NewLogger()
+	if err != nil {
+		return nil, err
+	}
+	claims, err := s.users.tokens.ParseAccessToken(accessToken)
+	if err != nil {
+		return nil, oauthError("invalid_token", "the access token is invalid")
+	}
+	revoked, err := interceptor.Revoked(ctx, s.users.denylist, claims)
+	if err != nil {
+		logs.Error("Error while checking token revocation")
+		return nil, err
+	}
+	if revoked {
+		return nil, oauthError("invalid_token", "the access token has been revoked")
+	}
+	if !interceptor.HasScope(claims, ScopeOpenID) {
+		return nil, oauthError("insufficient_scope", "the access token was not granted the openid scope")
+	}
+	user, err := s.users.auth.GetUser(ctx, claims.UserID)
+	if errors.Is(err, postgres.ErrUserNotFound) {
+		return nil, oauthError("invalid_token", "the user no longer exists")
+	}
+	if err != nil {
+		logs.Error("Error while getting user")
+		return nil, err
+	}
+	return &UserInfoResponse{Subject: user.Id, UserInfo: userInfo(user, strings.Fields(claims.Scope))}, nil
+}
+
+// userInfo maps a user to the standard claims the scopes release.
+func userInfo(user *pb.UserModel, scopes []string) token.UserInfo {
+	info := token.UserInfo{}
+	if contains(scopes, ScopeProfile) {
+		info.Name = strings.TrimSpace(user.Fname + " " + user.Lname)
+		info.GivenName = user.Fname
+		info.FamilyName = user.Lname
+		info.Picture = user.ProfileImage
+	}
+	if contains(scopes, ScopeEmail) {
+		verified := user.EmailVerified
+		info.Email = user.Email
+		info.EmailVerified = &verified
+	}
+	return info
+}
+
+func (s *OAuthService) tokenResponse(access, refresh, scope string) *TokenResponse {
+	return &TokenResponse{
+		AccessToken:  access,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(s.users.tokens.AccessTTL().Seconds()),
+		RefreshToken: refresh,
+		Scope:        scope,
+	}
+}
+
+func contains(list []string, v string) bool {
+	for _, item := range list {
+		if item == v {
+			return true
+		}
+	}
+	return false
+}
+
+// validRedirectURI accepts https URLs, http on loopback for development and
+// native apps, and private-use schemes such as com.example.app:/callback.
+func validRedirectURI(raw string) bool {
+	u, err := url.Parse(raw)
+	if err != nil || u.Scheme == "" || u.Fragment != "" {
+		return false
+	}
+	switch u.Scheme {
+	case "https":
+		return u.Host != ""
+	case "http":
+		host := u.Hostname()
+		return host == "localhost" || host == "127.0.0.1" || host == "::1"
+	}
+	return strings.Contains(u.Scheme, ".")
+}
+
+func (s *AdminServiceImpl) CreateOAuthClient(ctx context.Context, req *pb.CreateOAuthClientReq) (*pb.CreateOAuthClientRes, error) {
+	logs, err := logger.NewLogger()
+	if err != nil {
+		return nil, err
+	}
+	if req.Name == "" || len(req.Name) > 100 {
+		return nil, status.Error(codes.InvalidArgument, "name is required")
+	}
+	if len(req.RedirectUris) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "at least one redirect uri is required")
+	}
+	for _, uri := range req.RedirectUris {
+		if !validRedirectURI(uri) {
+			return nil, status.Error(codes.InvalidArgument, "invalid redirect uri "+strconv.Quote(uri))
+		}
+	}
+	for _, scope := range req.Scopes {
+		if !scopePattern.MatchString(scope) {
+			return nil, status.Error(codes.InvalidArgument, "invalid scope "+strconv.Quote(scope))
+		}
+	}
+	clientID, _, err := token.NewOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+	client := &pb.OAuthClient{
+		ClientId:     clientID[:24],
+		Name:         req.Name,
+		RedirectUris: req.RedirectUris,
+		Scopes:       req.Scopes,
+		Confidential: req.Confidential,
+	}
+	var secret, secretHash string
+	if req.Confidential {
+		if secret, secretHash, err = token.NewOpaqueToken(); err != nil {
+			return nil, err
+		}
+	}
+	if err = s.oauth.CreateClient(ctx, client, secretHash); err != nil {
+		logs.Error("Error while creating oauth client")
+		return nil, err
+	}
+	logs.Info("Successfully created oauth client")
+	return &pb.CreateOAuthClientRes{Client: client, ClientSecret: secret}, nil
+}
+
+func (s *AdminServiceImpl) ListOAuthClients(ctx context.Context, req *pb.ListOAuthClientsReq) (*pb.ListOAuthClientsRes, error) {
+	logs, err := logger.NewLogger()
+	if err != nil {
+		return nil, err
+	}
+	clients, err := s.oauth.ListClients(ctx)
+	if err != nil {
+		logs.Error("Error while listing oauth clients")
+		return nil, err
+	}
+	return &pb.ListOAuthClientsRes{Clients: clients}, nil
+}
+
+func (s *AdminServiceImpl) DeleteOAuthClient(ctx context.Context, req *pb.DeleteOAuthClientReq) (*pb.DeleteOAuthClientRes, error) {
+	logs, err := logger.NewLogger()
+	if err != nil {
+		return nil, err
+	}
+	err = s.oauth.DeleteClient(ctx, req.ClientId)
+	if errors.Is(err, postgres.ErrOAuthClientNotFound) {
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
+	if err != nil {
+		logs.Error("Error while deleting oauth client")
+		return nil, err
+	}
+	logs.Info("Successfully deleted oauth client")
+	return &pb.DeleteOAuthClientRes{Message: "OAuth client deleted"}, nil
+}
@@ -0,0 +1,258 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+
+	pb "blog-auth/genproto/userservice"
+	"blog-auth/internal/config"
+	logger "blog-auth/internal/logger"
+	"blog-auth/internal/storage/postgres"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	sessionRegistration = "registration"
+	sessionLogin        = "login"
+)
+
+var errPasskeyInvalid = errors.New("passkey assertion is invalid")
+
+// NewWebAuthn configures the relying party passkeys are bound to.
+func NewWebAuthn(cfg config.Config) (*webauthn.WebAuthn, error) {
+	timeout := webauthn.TimeoutConfig{Enforce: true, Timeout: 5 * time.Minute, TimeoutUVD: 5 * time.Minute}
+	return webauthn.New(&webauthn.Config{
+		RPID:          cfg.WEBAUTHNRPID,
+		RPDisplayName: cfg.WEBAUTHNRPNAME,
+		RPOrigins:     cfg.WEBAUTHNORIGINS,
+		Timeouts:      webauthn.TimeoutsConfig{Login: timeout, Registration: timeout},
+	})
+}
+
+// passkeyAuthenticator runs WebAuthn ceremonies against stored credentials.
+type passkeyAuthenticator struct {
+	web      *webauthn.WebAuthn
+	passkeys postgres.PasskeyStorage
+}
+
+// finishLogin validates an assertion for session and records the new sign
+// count. A sign count that did not increase means the authenticator may have
+// been cloned, so the assertion is rejected.
+func (a *passkeyAuthenticator) finishLogin(ctx context.Context, user *postgres.PasskeyUser, session *webauthn.SessionData, credential string, discoverable bool) (*webauthn.Credential, error) {
+	parsed, err := protocol.ParseCredentialRequestResponseBytes([]byte(credential))
+	if err != nil {
+		return nil, errPasskeyInvalid
+	}
+	var cred *webauthn.Credential
+	if discoverable {
+		cred, err = a.web.ValidateDiscoverableLogin(func(rawID, userHandle []byte) (webauthn.User, error) {
+			if string(userHandle) != user.User.Id {
+				return nil, errPasskeyInvalid
+			}
+			return user, nil
+		}, *session, parsed)
+	} else {
+		cred, err = a.web.ValidateLogin(user, *session, parsed)
+	}
+	if err != nil {
+		return nil, errPasskeyInvalid
+	}
+	if cred.Authenticator.CloneWarning {
+		return nil, errPasskeyInvalid
+	}
+	if err = a.passkeys.UpdatePasskey(ctx, cred); err != nil {
+		return nil, err
+	}
+	return cred, nil
+}
+
+// verifySecondFactor checks a passkey assertion made after a password login.
+// Only credentials the user enabled as a second factor are accepted.
+func (a *passkeyAuthenticator) verifySecondFactor(ctx context.Context, userID, sessionID, credential string) (*webauthn.Credential, error) {
+	session, sessionUser, err := a.passkeys.TakeWebAuthnSession(ctx, sessionID, sessionLogin)
+	if err != nil {
+		return nil, postgres.ErrMFACodeInvalid
+	}
+	if sessionUser != userID {
+		return nil, postgres.ErrMFACodeInvalid
+	}
+	user, err := a.passkeys.GetPasskeyUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	cred, err := a.finishLogin(ctx, user, session, credential, false)
+	if errors.Is(err, errPasskeyInvalid) {
+		return nil, postgres.ErrMFACodeInvalid
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !user.SecondFactor[string(cred.ID)] {
+		return nil, postgres.ErrMFACodeInvalid
+	}
+	return cred, nil
+}
+
+func (s *UserServiceImpl) BeginPasskeyRegistration(ctx context.Context, req *pb.BeginPasskeyRegistrationReq) (*pb.BeginPasskeyRes, error) {
+	logs, err := logger.NewLogger()
+	if err != nil {
+		return nil, err
+	}
+	claims, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	user, err := s.passkeys.passkeys.GetPasskeyUser(ctx, claims.UserID)
+	if err != nil {
+		logs.Error("Error while getting passkey user")
+		return nil, err
+	}
+	exclude := make([]protocol.CredentialDescriptor, 0, len(user.Credentials))
+	for _, c := range user.Credentials {
+		exclude = append(exclude, c.Descriptor())
+	}
+	creation, session, err := s.passkeys.web.BeginRegistration(user,
+		webauthn.WithExclusions(exclude),
+		webauthn.WithResidentKeyRequirement(protocol.ResidentKeyRequirementRequired),
+	)
+	if err != nil {
+		logs.Error("Error while beginning passkey registration")
+		return nil, err
+	}
+	return s.savePasskeySession(ctx, claims.UserID, sessionRegistration, creation, session)
+}
+
+func (s *UserServiceImpl) FinishPasskeyRegistration(ctx context.Context, req *pb.FinishPasskeyRegistrationReq) (*pb.FinishPasskeyRegistrationRes, error) {
+	logs, err := logger.NewLogger()
+	if err != nil {
+		return nil, err
+	}
+	claims, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	session, sessionUser, err := s.passkeys.passkeys.TakeWebAuthnSession(ctx, req.SessionId, sessionRegistration)
+	if err != nil || sessionUser != claims.UserID {
+		return nil, status.Error(codes.InvalidArgument, postgres.ErrWebAuthnSession.Error())
+	}
+	user, err := s.passkeys.passkeys.GetPasskeyUser(ctx, claims.UserID)
+	if err != nil {
+		return nil, err
+	}
+	parsed, err := protocol.ParseCredentialCreationResponseBytes([]byte(req.Credential))
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid passkey credential")
+	}
+	cred, err := s.passkeys.web.CreateCredential(user, *session, parsed)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid passkey credential")
+	}
+	if _, err = s.passkeys.passkeys.CreatePasskey(ctx, claims.UserID, req.Name, cred, req.SecondFactor); err != nil {
+		logs.Error("Error while saving passkey")
+		return nil, err
+	}
+	logs.Info("Successfully registered passkey")
+	return &pb.FinishPasskeyRegistrationRes{
+		CredentialId: base64.RawURLEncoding.EncodeToString(cred.ID),
+		Message:      "Passkey registered",
+	}, nil
+}
+
+func (s *UserServiceImpl) BeginPasskeyLogin(ctx context.Context, req *pb.BeginPasskeyLoginReq) (*pb.BeginPasskeyRes, error) {
+	logs, err := logger.NewLogger()
+	if err != nil {
+		return nil, err
+	}
+	if req.MfaToken == "" {
+		assertion, session, err := s.passkeys.web.BeginDiscoverableLogin(webauthn.WithUserVerification(protocol.VerificationRequired))
+		if err != nil {
+			logs.Error("Error while beginning passkey login")
+			return nil, err
+		}
+		return s.savePasskeySession(ctx, "", sessionLogin, assertion, session)
+	}
+
+	userID, err := s.mfa.MFAChallengeUser(ctx, req.MfaToken)
+	if errors.Is(err, postgres.ErrMFAChallengeInvalid) {
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+	if err != nil {
+		return nil, err
+	}
+	user, err := s.passkeys.passkeys.GetPasskeyUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	var allowed []protocol.CredentialDescriptor
+	for _, c := range user.Credentials {
+		if user.SecondFactor[string(c.ID)] {
+			allowed = append(allowed, c.Descriptor())
+		}
+	}
+	if len(allowed) == 0 {
+		return nil, status.Error(codes.FailedPrecondition, "no passkey is enabled as a second factor")
+	}
+	assertion, session, err := s.passkeys.web.BeginLogin(user, webauthn.WithAllowedCredentials(allowed))
+	if err != nil {
+		logs.Error("Error while beginning passkey login")
+		return nil, err
+	}
+	return s.savePasskeySession(ctx, userID, sessionLogin, assertion, session)
+}
+
+// FinishPasskeyLogin completes a passwordless login. A passkey with user
+// verification already combines possession and a PIN or biometric, so no
+// further factor is asked for.
+func (s *UserServiceImpl) FinishPasskeyLogin(ctx context.Context, req *pb.FinishPasskeyLoginReq) (*pb.LoginRes, error) {
+	logs, err := logger.NewLogger()
+	if err != nil {
+		return nil, err
+	}
+	session, sessionUser, err := s.passkeys.passkeys.TakeWebAuthnSession(ctx, req.SessionId, sessionLogin)
+	if err != nil || sessionUser != "" {
+		return nil, status.Error(codes.Unauthenticated, postgres.ErrWebAuthnSession.Error())
+	}
+	parsed, err := protocol.ParseCredentialRequestResponseBytes([]byte(req.Credential))
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, errPasskeyInvalid.Error())
+	}
+	user, err := s.passkeys.passkeys.GetPasskeyUser(ctx, string(parsed.Response.UserHandle))
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, errPasskeyInvalid.Error())
+	}
+	if _, err = s.passkeys.finishLogin(ctx, user, session, req.Credential, true); err != nil {
+		if errors.Is(err, errPasskeyInvalid) {
+			return nil, status.Error(codes.Unauthenticated, err.Error())
+		}
+		logs.Error("Error while finishing passkey login")
+		return nil, err
+	}
+
+	resp := &pb.LoginRes{UserRes: user.User}
+	resp.AccessToken, resp.RefreshToken, resp.ExpiresIn, err = s.issueTokens(ctx, user.User)
+	if err != nil {
+		logs.Error("Error while issuing tokens")
+		return nil, err
+	}
+	logs.Info("Successfully login with passkey")
+	return resp, nil
+}
+
+func (s *UserServiceImpl) savePasskeySession(ctx context.Context, userID, kind string, options interface{}, session *webauthn.SessionData) (*pb.BeginPasskeyRes, error) {
+	id, err := s.passkeys.passkeys.SaveWebAuthnSession(ctx, userID, kind, session)
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(options)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.BeginPasskeyRes{SessionId: id, Options: string(data)}, nil
+}
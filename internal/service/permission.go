@@ -0,0 +1,195 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"regexp"
+
+	pb "blog-auth/genproto/userservice"
+	"blog-auth/internal/interceptor"
+	logger "blog-auth/internal/logger"
+	"blog-auth/internal/storage/postgres"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	roleNamePattern       = regexp.MustCompile(`^[a-z][a-z0-9_-]{1,49}$`)
+	permissionNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_-]*(:[a-z][a-z0-9_-]*)+$`)
+)
+
+// builtinRoles live in the users.role enum and cannot be created as roles.
+var builtinRoles = map[string]bool{
+	interceptor.RoleUser:       true,
+	interceptor.RoleAdmin:      true,
+	interceptor.RoleSuperAdmin: true,
+}
+
+// permissionError maps storage errors of the role and permission RPCs to gRPC
+// statuses.
+func permissionError(err error) error {
+	switch {
+	case errors.Is(err, postgres.ErrRoleExists), errors.Is(err, postgres.ErrPermissionExists):
+		return status.Error(codes.AlreadyExists, err.Error())
+	case errors.Is(err, postgres.ErrRoleNotFound), errors.Is(err, postgres.ErrPermissionNotFound), errors.Is(err, postgres.ErrUserNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	}
+	return err
+}
+
+func (s *UserServiceImpl) CheckPermission(ctx context.Context, req *pb.CheckPermissionReq) (*pb.CheckPermissionRes, error) {
+	logs, err := logger.NewLogger()
+	if err != nil {
+		return nil, err
+	}
+	claims, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	userID := claims.UserID
+	if req.UserId != "" && req.UserId != claims.UserID {
+		if !interceptor.HasRole(claims, interceptor.RoleAdmin, interceptor.RoleSuperAdmin) {
+			return nil, status.Error(codes.PermissionDenied, "permission denied")
+		}
+		userID = req.UserId
+	}
+	access, err := s.permissions.UserAccess(ctx, userID)
+	if err != nil {
+		logs.Error("Error while calling CheckPermission")
+		return nil, permissionError(err)
+	}
+	return &pb.CheckPermissionRes{Allowed: access.Has(req.Permission), Roles: access.Roles}, nil
+}
+
+func (s *AdminServiceImpl) CreateRole(ctx context.Context, req *pb.CreateRoleReq) (*pb.CreateRoleRes, error) {
+	logs, err := logger.NewLogger()
+	if err != nil {
+		return nil, err
+	}
+	if !roleNamePattern.MatchString(req.Name) || builtinRoles[req.Name] {
+		return nil, status.Error(codes.InvalidArgument, "invalid role name")
+	}
+	resp, err := s.permissions.CreateRole(ctx, req)
+	if err != nil {
+		logs.Error("Error while creating role")
+		return nil, permissionError(err)
+	}
+	logs.Info("Successfully created role")
+	return resp, nil
+}
+
+func (s *AdminServiceImpl) DeleteRole(ctx context.Context, req *pb.DeleteRoleReq) (*pb.DeleteRoleRes, error) {
+	logs, err := logger.NewLogger()
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.permissions.DeleteRole(ctx, req)
+	if err != nil {
+		logs.Error("Error while deleting role")
+		return nil, permissionError(err)
+	}
+	logs.Info("Successfully deleted role")
+	return resp, nil
+}
+
+func (s *AdminServiceImpl) ListRoles(ctx context.Context, req *pb.ListRolesReq) (*pb.ListRolesRes, error) {
+	logs, err := logger.NewLogger()
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.permissions.ListRoles(ctx, req)
+	if err != nil {
+		logs.Error("Error while listing roles")
+		return nil, err
+	}
+	logs.Info("Successfully listed roles")
+	return resp, nil
+}
+
+func (s *AdminServiceImpl) CreatePermission(ctx context.Context, req *pb.CreatePermissionReq) (*pb.CreatePermissionRes, error) {
+	logs, err := logger.NewLogger()
+	if err != nil {
+		return nil, err
+	}
+	if len(req.Name) > 100 || !permissionNamePattern.MatchString(req.Name) {
+		return nil, status.Error(codes.InvalidArgument, "permission name must look like resource:action")
+	}
+	resp, err := s.permissions.CreatePermission(ctx, req)
+	if err != nil {
+		logs.Error("Error while creating permission")
+		return nil, permissionError(err)
+	}
+	logs.Info("Successfully created permission")
+	return resp, nil
+}
+
+func (s *AdminServiceImpl) ListPermissions(ctx context.Context, req *pb.ListPermissionsReq) (*pb.ListPermissionsRes, error) {
+	logs, err := logger.NewLogger()
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.permissions.ListPermissions(ctx, req)
+	if err != nil {
+		logs.Error("Error while listing permissions")
+		return nil, err
+	}
+	logs.Info("Successfully listed permissions")
+	return resp, nil
+}
+
+func (s *AdminServiceImpl) GrantPermission(ctx context.Context, req *pb.GrantPermissionReq) (*pb.GrantPermissionRes, error) {
+	logs, err := logger.NewLogger()
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.permissions.GrantPermission(ctx, req)
+	if err != nil {
+		logs.Error("Error while granting permission")
+		return nil, permissionError(err)
+	}
+	logs.Info("Successfully granted permission")
+	return resp, nil
+}
+
+func (s *AdminServiceImpl) RevokePermission(ctx context.Context, req *pb.RevokePermissionReq) (*pb.RevokePermissionRes, error) {
+	logs, err := logger.NewLogger()
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.permissions.RevokePermission(ctx, req)
+	if err != nil {
+		logs.Error("Error while revoking permission")
+		return nil, permissionError(err)
+	}
+	logs.Info("Successfully revoked permission")
+	return resp, nil
+}
+
+func (s *AdminServiceImpl) AssignRole(ctx context.Context, req *pb.AssignRoleReq) (*pb.AssignRoleRes, error) {
+	logs, err := logger.NewLogger()
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.permissions.AssignRole(ctx, req)
+	if err != nil {
+		logs.Error("Error while assigning role")
+		return nil, permissionError(err)
+	}
+	logs.Info("Successfully assigned role")
+	return resp, nil
+}
+
+func (s *AdminServiceImpl) UnassignRole(ctx context.Context, req *pb.UnassignRoleReq) (*pb.UnassignRoleRes, error) {
+	logs, err := logger.NewLogger()
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.permissions.UnassignRole(ctx, req)
+	if err != nil {
+		logs.Error("Error while unassigning role")
+		return nil, permissionError(err)
+	}
+	logs.Info("Successfully unassigned role")
+	return resp, nil
+}
@@ -0,0 +1,138 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	pb "blog-auth/genproto/userservice"
+	logger "blog-auth/internal/logger"
+	"blog-auth/internal/storage/postgres"
+	"blog-auth/internal/token"
+
+	"github.com/golang-jwt/jwt/v5"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// maxPersonalTokenDays bounds the lifetime of expiring personal access
+// tokens.
+const maxPersonalTokenDays = 366
+
+// CreatePersonalAccessToken creates a token for the caller's scripts. The
+// token is only returned here; it is stored hashed.
+func (s *UserServiceImpl) CreatePersonalAccessToken(ctx context.Context, req *pb.CreatePersonalAccessTokenReq) (*pb.CreatePersonalAccessTokenRes, error) {
+	logs, err := logger.NewLogger()
+	if err != nil {
+		return nil, err
+	}
+	claims, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if req.Name == "" || len(req.Name) > 100 {
+		return nil, status.Error(codes.InvalidArgument, "name is required")
+	}
+	if len(req.Scopes) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "at least one scope is required")
+	}
+	for _, scope := range req.Scopes {
+		if !scopePattern.MatchString(scope) {
+			return nil, status.Error(codes.InvalidArgument, "invalid scope "+strconv.Quote(scope))
+		}
+	}
+	if req.ExpiresInDays < 0 || req.ExpiresInDays > maxPersonalTokenDays {
+		return nil, status.Errorf(codes.InvalidArgument, "expires_in_days must be between 0 and %d", maxPersonalTokenDays)
+	}
+	var expiresAt *time.Time
+	if req.ExpiresInDays > 0 {
+		t := time.Now().AddDate(0, 0, int(req.ExpiresInDays))
+		expiresAt = &t
+	}
+
+	plain, hash, err := token.NewPersonalAccessToken()
+	if err != nil {
+		return nil, err
+	}
+	pat := &pb.PersonalAccessToken{Name: req.Name, Scopes: req.Scopes}
+	if err = s.pats.CreatePersonalToken(ctx, claims.UserID, pat, hash, expiresAt); err != nil {
+		logs.Error("Error while creating personal access token")
+		return nil, err
+	}
+	logs.Info("Successfully created personal access token")
+	return &pb.CreatePersonalAccessTokenRes{PersonalAccessToken: pat, Token: plain}, nil
+}
+
+func (s *UserServiceImpl) ListPersonalAccessTokens(ctx context.Context, req *pb.ListPersonalAccessTokensReq) (*pb.ListPersonalAccessTokensRes, error) {
+	logs, err := logger.NewLogger()
+	if err != nil {
+		return nil, err
+	}
+	claims, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	pats, err := s.pats.ListPersonalTokens(ctx, claims.UserID)
+	if err != nil {
+		logs.Error("Error while listing personal access tokens")
+		return nil, err
+	}
+	return &pb.ListPersonalAccessTokensRes{PersonalAccessTokens: pats}, nil
+}
+
+func (s *UserServiceImpl) RevokePersonalAccessToken(ctx context.Context, req *pb.RevokePersonalAccessTokenReq) (*pb.RevokePersonalAccessTokenRes, error) {
+	logs, err := logger.NewLogger()
+	if err != nil {
+		return nil, err
+	}
+	claims, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	err = s.pats.RevokePersonalToken(ctx, claims.UserID, req.Id)
+	if errors.Is(err, postgres.ErrPersonalTokenNotFound) {
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
+	if err != nil {
+		logs.Error("Error while revoking personal access token")
+		return nil, err
+	}
+	logs.Info("Successfully revoked personal access token")
+	return &pb.RevokePersonalAccessTokenRes{Message: "Personal access token revoked"}, nil
+}
+
+// VerifyPersonalAccessToken returns the claims a personal access token stands
+// for, with the owner's current role. It lets the auth interceptor accept
+// the tokens alongside JWTs.
+func (s *UserServiceImpl) VerifyPersonalAccessToken(ctx context.Context, raw string) (*token.Claims, error) {
+	logs, err := logger.NewLogger()
+	if err != nil {
+		return nil, err
+	}
+	owner, err := s.pats.UsePersonalToken(ctx, token.HashOpaqueToken(raw))
+	if errors.Is(err, postgres.ErrPersonalTokenInvalid) {
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+	if err != nil {
+		logs.Error("Error while verifying personal access token")
+		return nil, status.Error(codes.Unavailable, "cannot verify personal access token")
+	}
+	claims := &token.Claims{
+		UserID:        owner.UserID,
+		Role:          owner.Role,
+		EmailVerified: owner.EmailVerified,
+		Scope:         strings.Join(owner.Scopes, " "),
+		PersonalToken: true,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:       owner.TokenID,
+			Subject:  owner.UserID,
+			IssuedAt: jwt.NewNumericDate(owner.CreatedAt),
+		},
+	}
+	if owner.ExpiresAt != nil {
+		claims.ExpiresAt = jwt.NewNumericDate(*owner.ExpiresAt)
+	}
+	return claims, nil
+}
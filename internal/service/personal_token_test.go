@@ -0,0 +1,218 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	pb "blog-auth/genproto/userservice"
+	"blog-auth/internal/config"
+	"blog-auth/internal/interceptor"
+	"blog-auth/internal/revocation"
+	"blog-auth/internal/storage/postgres"
+	"blog-auth/internal/token"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+type fakePAT struct {
+	userID    string
+	pat       *pb.PersonalAccessToken
+	expiresAt *time.Time
+	revoked   bool
+}
+
+// fakePATStorage keeps tokens in memory and applies the same rules as the
+// database: revoked and expired tokens cannot be used.
+type fakePATStorage struct {
+	role   string
+	tokens map[string]*fakePAT
+}
+
+func newFakePATStorage(role string) *fakePATStorage {
+	return &fakePATStorage{role: role, tokens: map[string]*fakePAT{}}
+}
+
+func (f *fakePATStorage) CreatePersonalToken(ctx context.Context, userID string, pat *pb.PersonalAccessToken, tokenHash string, expiresAt *time.Time) error {
+	pat.Id = uuid.NewString()
+	f.tokens[tokenHash] = &fakePAT{userID: userID, pat: pat, expiresAt: expiresAt}
+	return nil
+}
+
+func (f *fakePATStorage) ListPersonalTokens(ctx context.Context, userID string) ([]*pb.PersonalAccessToken, error) {
+	var pats []*pb.PersonalAccessToken
+	for _, t := range f.tokens {
+		if t.userID == userID && !t.revoked {
+			pats = append(pats, t.pat)
+		}
+	}
+	return pats, nil
+}
+
+func (f *fakePATStorage) RevokePersonalToken(ctx context.Context, userID, tokenID string) error {
+	for _, t := range f.tokens {
+		if t.pat.Id == tokenID && t.userID == userID && !t.revoked {
+			t.revoked = true
+			return nil
+		}
+	}
+	return postgres.ErrPersonalTokenNotFound
+}
+
+func (f *fakePATStorage) UsePersonalToken(ctx context.Context, tokenHash string) (*postgres.PersonalTokenOwner, error) {
+	t, ok := f.tokens[tokenHash]
+	if !ok || t.revoked || (t.expiresAt != nil && !t.expiresAt.After(time.Now())) {
+		return nil, postgres.ErrPersonalTokenInvalid
+	}
+	return &postgres.PersonalTokenOwner{
+		TokenID:   t.pat.Id,
+		UserID:    t.userID,
+		Role:      f.role,
+		Scopes:    t.pat.Scopes,
+		ExpiresAt: t.expiresAt,
+		CreatedAt: time.Now(),
+	}, nil
+}
+
+func signedIn(userID string) context.Context {
+	return interceptor.WithPrincipal(context.Background(), &token.Claims{UserID: userID, Role: interceptor.RoleUser})
+}
+
+func TestCreatePersonalAccessTokenValidation(t *testing.T) {
+	s := &UserServiceImpl{pats: newFakePATStorage(interceptor.RoleUser)}
+	tests := []struct {
+		name string
+		req  *pb.CreatePersonalAccessTokenReq
+	}{
+		{"no name", &pb.CreatePersonalAccessTokenReq{Scopes: []string{"users:read"}}},
+		{"long name", &pb.CreatePersonalAccessTokenReq{Name: string(make([]byte, 101)), Scopes: []string{"users:read"}}},
+		{"no scopes", &pb.CreatePersonalAccessTokenReq{Name: "ci"}},
+		{"invalid scope", &pb.CreatePersonalAccessTokenReq{Name: "ci", Scopes: []string{"Users Read"}}},
+		{"negative lifetime", &pb.CreatePersonalAccessTokenReq{Name: "ci", Scopes: []string{"users:read"}, ExpiresInDays: -1}},
+		{"lifetime too long", &pb.CreatePersonalAccessTokenReq{Name: "ci", Scopes: []string{"users:read"}, ExpiresInDays: maxPersonalTokenDays + 1}},
+	}
+	for _, tt := range tests {
+		if _, err := s.CreatePersonalAccessToken(signedIn("user-1"), tt.req); status.Code(err) != codes.InvalidArgument {
+			t.Errorf("%s: error = %v, want InvalidArgument", tt.name, err)
+		}
+	}
+	_, err := s.CreatePersonalAccessToken(context.Background(), &pb.CreatePersonalAccessTokenReq{Name: "ci", Scopes: []string{"users:read"}})
+	if status.Code(err) != codes.Unauthenticated {
+		t.Errorf("without a caller: error = %v, want Unauthenticated", err)
+	}
+}
+
+func TestPersonalAccessTokenLifecycle(t *testing.T) {
+	store := newFakePATStorage(interceptor.RoleUser)
+	s := &UserServiceImpl{pats: store}
+	ctx := signedIn("user-1")
+
+	created, err := s.CreatePersonalAccessToken(ctx, &pb.CreatePersonalAccessTokenReq{Name: "ci", Scopes: []string{"users:read", "posts:write"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !token.IsPersonalAccessToken(created.Token) {
+		t.Errorf("token %q is not a personal access token", created.Token)
+	}
+	if _, ok := store.tokens[created.Token]; ok {
+		t.Error("the token was stored in plaintext")
+	}
+
+	claims, err := s.VerifyPersonalAccessToken(context.Background(), created.Token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.UserID != "user-1" || claims.Role != interceptor.RoleUser || !claims.PersonalToken || claims.ExpiresAt != nil {
+		t.Errorf("claims = %+v", claims)
+	}
+	if !interceptor.HasScope(claims, "posts:write") || interceptor.HasScope(claims, "users:write") {
+		t.Errorf("scope = %q", claims.Scope)
+	}
+	if claims.ID != created.PersonalAccessToken.Id {
+		t.Errorf("token id = %q, want %q", claims.ID, created.PersonalAccessToken.Id)
+	}
+
+	if _, err = s.VerifyPersonalAccessToken(context.Background(), token.PersonalTokenPrefix+"unknown"); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("unknown token: error = %v, want Unauthenticated", err)
+	}
+
+	revoke := &pb.RevokePersonalAccessTokenReq{Id: created.PersonalAccessToken.Id}
+	if _, err = s.RevokePersonalAccessToken(signedIn("user-2"), revoke); status.Code(err) != codes.NotFound {
+		t.Errorf("revoked by another user: error = %v, want NotFound", err)
+	}
+	if _, err = s.RevokePersonalAccessToken(ctx, revoke); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = s.VerifyPersonalAccessToken(context.Background(), created.Token); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("revoked token: error = %v, want Unauthenticated", err)
+	}
+	if _, err = s.RevokePersonalAccessToken(ctx, revoke); status.Code(err) != codes.NotFound {
+		t.Errorf("revoked twice: error = %v, want NotFound", err)
+	}
+}
+
+func TestExpiringPersonalAccessToken(t *testing.T) {
+	store := newFakePATStorage(interceptor.RoleUser)
+	s := &UserServiceImpl{pats: store}
+	created, err := s.CreatePersonalAccessToken(signedIn("user-1"), &pb.CreatePersonalAccessTokenReq{Name: "ci", Scopes: []string{"users:read"}, ExpiresInDays: 30})
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, err := s.VerifyPersonalAccessToken(context.Background(), created.Token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := time.Now().AddDate(0, 0, 30)
+	if claims.ExpiresAt == nil || claims.ExpiresAt.Sub(want).Abs() > time.Minute {
+		t.Errorf("expires at %v, want about %v", claims.ExpiresAt, want)
+	}
+
+	expired := time.Now().Add(-time.Second)
+	store.tokens[token.HashOpaqueToken(created.Token)].expiresAt = &expired
+	if _, err = s.VerifyPersonalAccessToken(context.Background(), created.Token); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("expired token: error = %v, want Unauthenticated", err)
+	}
+}
+
+func TestPersonalAccessTokenScopes(t *testing.T) {
+	s := &UserServiceImpl{pats: newFakePATStorage(interceptor.RoleAdmin)}
+	ctx := signedIn("admin-1")
+	withScope, err := s.CreatePersonalAccessToken(ctx, &pb.CreatePersonalAccessTokenReq{Name: "reader", Scopes: []string{interceptor.ScopeUsersRead}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	withoutScope, err := s.CreatePersonalAccessToken(ctx, &pb.CreatePersonalAccessTokenReq{Name: "other", Scopes: []string{"posts:write"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tokens := token.NewManager(config.Config{TOKENKEY: "test-key", TOKENALGO: token.AlgHS256, ACCESSTOKENTTL: time.Minute})
+	auth := interceptor.Auth(tokens, revocation.NewMemoryDenylist(), s, interceptor.Policies)
+	call := func(raw, method string) codes.Code {
+		ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer "+raw))
+		_, err := auth(ctx, nil, &grpc.UnaryServerInfo{FullMethod: method}, func(ctx context.Context, req interface{}) (interface{}, error) {
+			return nil, nil
+		})
+		return status.Code(err)
+	}
+	tests := []struct {
+		name   string
+		raw    string
+		method string
+		want   codes.Code
+	}{
+		{"granted scope", withScope.Token, "/userservice.AdminService/GetUserByID", codes.OK},
+		{"missing scope", withoutScope.Token, "/userservice.AdminService/GetUserByID", codes.PermissionDenied},
+		{"method without scopes", withScope.Token, "/userservice.AdminService/GetAllUsers", codes.PermissionDenied},
+		{"first-party method", withScope.Token, "/userservice.UserService/CreatePersonalAccessToken", codes.PermissionDenied},
+	}
+	for _, tt := range tests {
+		if got := call(tt.raw, tt.method); got != tt.want {
+			t.Errorf("%s: code = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
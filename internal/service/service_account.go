@@ -0,0 +1,187 @@
+package service
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	pb "blog-auth/genproto/userservice"
+	logger "blog-auth/internal/logger"
+	"blog-auth/internal/revocation"
+	"blog-auth/internal/storage/postgres"
+	"blog-auth/internal/token"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// clientCredentials issues a service account an access token for the
+// requested scopes, or all of its scopes if none are requested. There is no
+// refresh token; the account asks again.
+func (s *OAuthService) clientCredentials(ctx context.Context, req TokenRequest) (*TokenResponse, error) {
+	logs, err := logger.NewLogger()
+	if err != nil {
+		return nil, err
+	}
+	account, err := s.authenticateServiceAccount(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	scopes := strings.Fields(req.Scope)
+	if len(scopes) == 0 {
+		scopes = account.Scopes
+	}
+	for _, scope := range scopes {
+		if !contains(account.Scopes, scope) {
+			return nil, oauthError("invalid_scope", "scope "+scope+" is not allowed for this service account")
+		}
+	}
+	scope := strings.Join(scopes, " ")
+	access, err := s.users.tokens.GenerateAccessToken(token.Claims{
+		UserID:   account.ClientId,
+		ClientID: account.ClientId,
+		Scope:    scope,
+		Service:  true,
+	})
+	if err != nil {
+		logs.Error("Error while generating access token")
+		return nil, err
+	}
+	logs.Info("Successfully issued service account token")
+	return s.tokenResponse(access, "", scope), nil
+}
+
+// authenticateServiceAccount checks the secret or the private_key_jwt
+// assertion of a service account. Assertions can only be used once.
+func (s *OAuthService) authenticateServiceAccount(ctx context.Context, req TokenRequest) (*pb.ServiceAccount, error) {
+	logs, err := logger.NewLogger()
+	if err != nil {
+		return nil, err
+	}
+	clientID := req.ClientID
+	if clientID == "" && req.ClientAssertion != "" {
+		clientID = token.AssertionIssuer(req.ClientAssertion)
+	}
+	account, credential, err := s.accounts.GetServiceAccount(ctx, clientID)
+	if errors.Is(err, postgres.ErrServiceAccountNotFound) {
+		return nil, oauthError("invalid_client", "client authentication failed")
+	}
+	if err != nil {
+		logs.Error("Error while getting service account")
+		return nil, err
+	}
+
+	if credential.PublicKey == "" {
+		if req.ClientSecret == "" || subtle.ConstantTimeCompare([]byte(token.HashOpaqueToken(req.ClientSecret)), []byte(credential.SecretHash)) != 1 {
+			return nil, oauthError("invalid_client", "client authentication failed")
+		}
+		return account, nil
+	}
+	if req.ClientAssertionType != assertionTypeJWTBearer || req.ClientAssertion == "" {
+		return nil, oauthError("invalid_client", "a private_key_jwt client assertion is required")
+	}
+	key, err := token.ParsePublicKey(credential.PublicKey)
+	if err != nil {
+		logs.Error("Error while parsing service account key")
+		return nil, err
+	}
+	claims, err := token.ParseClientAssertion(req.ClientAssertion, key, account.ClientId, s.issuer+"/oauth/token")
+	if err != nil {
+		return nil, oauthError("invalid_client", err.Error())
+	}
+	id := revocation.AssertionKey(account.ClientId, claims.ID)
+	used, err := s.users.denylist.Revoked(ctx, id)
+	if err != nil {
+		logs.Error("Error while checking client assertion replay")
+		return nil, err
+	}
+	if used {
+		return nil, oauthError("invalid_client", "client assertion was already used")
+	}
+	if err = s.users.denylist.Revoke(ctx, id, time.Until(claims.ExpiresAt.Time)); err != nil {
+		logs.Error("Error while recording client assertion")
+		return nil, err
+	}
+	return account, nil
+}
+
+func (s *AdminServiceImpl) CreateServiceAccount(ctx context.Context, req *pb.CreateServiceAccountReq) (*pb.CreateServiceAccountRes, error) {
+	logs, err := logger.NewLogger()
+	if err != nil {
+		return nil, err
+	}
+	if req.Name == "" || len(req.Name) > 100 {
+		return nil, status.Error(codes.InvalidArgument, "name is required")
+	}
+	if len(req.Scopes) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "at least one scope is required")
+	}
+	for _, scope := range req.Scopes {
+		if !scopePattern.MatchString(scope) {
+			return nil, status.Error(codes.InvalidArgument, "invalid scope "+strconv.Quote(scope))
+		}
+	}
+	credential := postgres.ServiceAccountCredential{PublicKey: strings.TrimSpace(req.PublicKey)}
+	var secret string
+	if credential.PublicKey != "" {
+		if _, err = token.ParsePublicKey(credential.PublicKey); err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+	} else if secret, credential.SecretHash, err = token.NewOpaqueToken(); err != nil {
+		return nil, err
+	}
+	clientID, _, err := token.NewOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+	account := &pb.ServiceAccount{
+		ClientId: "svc-" + clientID[:24],
+		Name:     req.Name,
+		Scopes:   req.Scopes,
+	}
+	if err = s.accounts.CreateServiceAccount(ctx, account, credential); err != nil {
+		logs.Error("Error while creating service account")
+		return nil, err
+	}
+	logs.Info("Successfully created service account")
+	return &pb.CreateServiceAccountRes{Account: account, ClientSecret: secret}, nil
+}
+
+func (s *AdminServiceImpl) ListServiceAccounts(ctx context.Context, req *pb.ListServiceAccountsReq) (*pb.ListServiceAccountsRes, error) {
+	logs, err := logger.NewLogger()
+	if err != nil {
+		return nil, err
+	}
+	accounts, err := s.accounts.ListServiceAccounts(ctx)
+	if err != nil {
+		logs.Error("Error while listing service accounts")
+		return nil, err
+	}
+	return &pb.ListServiceAccountsRes{Accounts: accounts}, nil
+}
+
+// DeleteServiceAccount removes the account and denies the tokens it still
+// holds.
+func (s *AdminServiceImpl) DeleteServiceAccount(ctx context.Context, req *pb.DeleteServiceAccountReq) (*pb.DeleteServiceAccountRes, error) {
+	logs, err := logger.NewLogger()
+	if err != nil {
+		return nil, err
+	}
+	err = s.accounts.DeleteServiceAccount(ctx, req.ClientId)
+	if errors.Is(err, postgres.ErrServiceAccountNotFound) {
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
+	if err != nil {
+		logs.Error("Error while deleting service account")
+		return nil, err
+	}
+	if err = s.denylist.Revoke(ctx, revocation.ServiceAccountKey(req.ClientId), s.tokens.AccessTTL()); err != nil {
+		logs.Error("Error while revoking service account tokens")
+		return nil, err
+	}
+	logs.Info("Successfully deleted service account")
+	return &pb.DeleteServiceAccountRes{Message: "Service account deleted"}, nil
+}
@@ -0,0 +1,106 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	pb "blog-auth/genproto/userservice"
+	logger "blog-auth/internal/logger"
+	"blog-auth/internal/storage/postgres"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func (s *UserServiceImpl) ListSessions(ctx context.Context, req *pb.ListSessionsReq) (*pb.ListSessionsRes, error) {
+	logs, err := logger.NewLogger()
+	if err != nil {
+		return nil, err
+	}
+	claims, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sessions, err := s.sessions.ListSessions(ctx, claims.UserID)
+	if err != nil {
+		logs.Error("Error while listing sessions")
+		return nil, err
+	}
+	for _, session := range sessions {
+		session.Current = session.Id == claims.SessionID
+	}
+	return &pb.ListSessionsRes{Sessions: sessions}, nil
+}
+
+func (s *UserServiceImpl) RevokeSession(ctx context.Context, req *pb.RevokeSessionReq) (*pb.RevokeSessionRes, error) {
+	logs, err := logger.NewLogger()
+	if err != nil {
+		return nil, err
+	}
+	claims, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	err = s.sessions.RevokeSession(ctx, claims.UserID, req.SessionId)
+	if errors.Is(err, postgres.ErrSessionNotFound) {
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
+	if err != nil {
+		logs.Error("Error while revoking session")
+		return nil, err
+	}
+	if err = denySessions(ctx, s.denylist, s.tokens.AccessTTL(), []string{req.SessionId}); err != nil {
+		logs.Error("Error while revoking session tokens")
+		return nil, err
+	}
+	logs.Info("Successfully revoked session")
+	return &pb.RevokeSessionRes{Message: "Session revoked", Revoked: 1}, nil
+}
+
+func (s *UserServiceImpl) RevokeAllOtherSessions(ctx context.Context, req *pb.RevokeAllOtherSessionsReq) (*pb.RevokeSessionRes, error) {
+	logs, err := logger.NewLogger()
+	if err != nil {
+		return nil, err
+	}
+	claims, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if claims.SessionID == "" {
+		return nil, status.Error(codes.FailedPrecondition, "access token is not bound to a session, log in again")
+	}
+	ids, err := s.sessions.RevokeOtherSessions(ctx, claims.UserID, claims.SessionID)
+	if err != nil {
+		logs.Error("Error while revoking other sessions")
+		return nil, err
+	}
+	if err = denySessions(ctx, s.denylist, s.tokens.AccessTTL(), ids); err != nil {
+		logs.Error("Error while revoking session tokens")
+		return nil, err
+	}
+	logs.Info("Successfully revoked other sessions")
+	return &pb.RevokeSessionRes{Message: "Other sessions revoked", Revoked: int32(len(ids))}, nil
+}
+
+// ForceLogout ends every session of a user. Access tokens issued before
+// sessions were tracked stay valid until they expire.
+func (s *AdminServiceImpl) ForceLogout(ctx context.Context, req *pb.ForceLogoutReq) (*pb.ForceLogoutRes, error) {
+	logs, err := logger.NewLogger()
+	if err != nil {
+		return nil, err
+	}
+	ids, err := s.sessions.RevokeUserSessions(ctx, req.UserId)
+	if errors.Is(err, postgres.ErrUserNotFound) {
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
+	if err != nil {
+		logs.Error("Error while forcing logout")
+		return nil, err
+	}
+	if err = denySessions(ctx, s.denylist, s.tokens.AccessTTL(), ids); err != nil {
+		logs.Error("Error while revoking session tokens")
+		return nil, err
+	}
+	logs.Info("Successfully forced logout")
+	return &pb.ForceLogoutRes{Message: "User logged out", Revoked: int32(len(ids))}, nil
+}
@@ -0,0 +1,67 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	pb "blog-auth/genproto/userservice"
+	"blog-auth/internal/idp"
+	logger "blog-auth/internal/logger"
+	"blog-auth/internal/storage/postgres"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// LoginWithTelegram logs in with the payload of the Telegram Login Widget,
+// creating an account linked to the Telegram id on first use.
+func (s *UserServiceImpl) LoginWithTelegram(ctx context.Context, req *pb.LoginWithTelegramReq) (*pb.LoginRes, error) {
+	logs, err := logger.NewLogger()
+	if err != nil {
+		return nil, err
+	}
+	telegram, ok := s.providers.Telegram()
+	if !ok {
+		return nil, status.Error(codes.FailedPrecondition, "telegram login is not configured")
+	}
+	err = telegram.Verify(idp.TelegramLogin{
+		ID:        req.Id,
+		FirstName: req.FirstName,
+		LastName:  req.LastName,
+		Username:  req.Username,
+		PhotoURL:  req.PhotoUrl,
+		AuthDate:  req.AuthDate,
+		Hash:      req.Hash,
+		Other:     req.OtherFields,
+	})
+	if errors.Is(err, idp.ErrTelegramInvalid) {
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := s.identities.TelegramLogin(ctx, postgres.ExternalIdentity{
+		Provider:   idp.TelegramProvider,
+		Subject:    strconv.FormatInt(req.Id, 10),
+		GivenName:  req.FirstName,
+		FamilyName: req.LastName,
+		Picture:    req.PhotoUrl,
+	})
+	if err != nil {
+		logs.Error("Error while calling TelegramLogin")
+		return nil, err
+	}
+	if resp.MfaRequired {
+		logs.Info("Telegram login requires second factor")
+		return resp, nil
+	}
+	resp.AccessToken, resp.RefreshToken, resp.ExpiresIn, err = s.issueTokens(ctx, resp.UserRes)
+	if err != nil {
+		logs.Error("Error while issuing tokens")
+		return nil, err
+	}
+	logs.Info("Successfully login with telegram")
+	return resp, nil
+}
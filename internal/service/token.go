@@ -0,0 +1,190 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	pb "blog-auth/genproto/userservice"
+	"blog-auth/internal/interceptor"
+	"blog-auth/internal/keys"
+	logger "blog-auth/internal/logger"
+	"blog-auth/internal/revocation"
+	"blog-auth/internal/storage/postgres"
+	"blog-auth/internal/token"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	hintAccessToken  = "access_token"
+	hintRefreshToken = "refresh_token"
+)
+
+// denySessions puts sessions on the denylist for as long as access tokens
+// issued for them can live, so they stop working before they expire.
+func denySessions(ctx context.Context, denylist revocation.Denylist, ttl time.Duration, ids []string) error {
+	for _, id := range ids {
+		if err := denylist.Revoke(ctx, revocation.SessionKey(id), ttl); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// IntrospectToken reports whether an access token is active and what it
+// carries. Invalid, expired and revoked tokens are just inactive. Only service
+// accounts with the tokens:introspect scope may call it, so it cannot be used
+// to probe tokens.
+func (s *UserServiceImpl) IntrospectToken(ctx context.Context, req *pb.IntrospectTokenReq) (*pb.IntrospectTokenRes, error) {
+	logs, err := logger.NewLogger()
+	if err != nil {
+		return nil, err
+	}
+	if req.TokenTypeHint == hintRefreshToken {
+		return &pb.IntrospectTokenRes{}, nil
+	}
+	if token.IsPersonalAccessToken(req.Token) {
+		claims, err := s.VerifyPersonalAccessToken(ctx, req.Token)
+		if status.Code(err) == codes.Unauthenticated {
+			return &pb.IntrospectTokenRes{}, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		return introspection(claims), nil
+	}
+	claims, err := s.tokens.ParseAccessToken(req.Token)
+	if err != nil {
+		return &pb.IntrospectTokenRes{}, nil
+	}
+	revoked, err := interceptor.Revoked(ctx, s.denylist, claims)
+	if err != nil {
+		logs.Error("Error while checking token revocation")
+		return nil, err
+	}
+	if revoked {
+		return &pb.IntrospectTokenRes{}, nil
+	}
+	return introspection(claims), nil
+}
+
+// introspection describes an active token. Tokens issued to OAuth clients and
+// personal access tokens carry scopes; the service's own carry permissions.
+func introspection(claims *token.Claims) *pb.IntrospectTokenRes {
+	scope := claims.Scope
+	if scope == "" {
+		scope = strings.Join(claims.Permissions, " ")
+	}
+	res := &pb.IntrospectTokenRes{
+		Active:        true,
+		Sub:           claims.UserID,
+		Scope:         scope,
+		Sid:           claims.SessionID,
+		Jti:           claims.ID,
+		Iss:           claims.Issuer,
+		TokenType:     "Bearer",
+		Role:          claims.Role,
+		EmailVerified: claims.EmailVerified,
+	}
+	if claims.ExpiresAt != nil {
+		res.Exp = claims.ExpiresAt.Unix()
+	}
+	if claims.IssuedAt != nil {
+		res.Iat = claims.IssuedAt.Unix()
+	}
+	return res
+}
+
+// RevokeToken revokes an access token until it expires, or the whole session
+// of a refresh token. Unknown tokens are not an error, as in RFC 7009.
+func (s *UserServiceImpl) RevokeToken(ctx context.Context, req *pb.RevokeTokenReq) (*pb.RevokeTokenRes, error) {
+	logs, err := logger.NewLogger()
+	if err != nil {
+		return nil, err
+	}
+	resp := &pb.RevokeTokenRes{Message: "Token revoked"}
+	if token.IsPersonalAccessToken(req.Token) {
+		// Whoever holds a personal access token may revoke it, so that a
+		// leaked one can be disabled without signing in as its owner.
+		claims, err := s.VerifyPersonalAccessToken(ctx, req.Token)
+		if status.Code(err) == codes.Unauthenticated {
+			return resp, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		err = s.pats.RevokePersonalToken(ctx, claims.UserID, claims.ID)
+		if err != nil && !errors.Is(err, postgres.ErrPersonalTokenNotFound) {
+			logs.Error("Error while revoking personal access token")
+			return nil, err
+		}
+		logs.Info("Successfully revoked personal access token")
+		return resp, nil
+	}
+	if req.TokenTypeHint != hintRefreshToken {
+		if claims, err := s.tokens.ParseAccessToken(req.Token); err == nil {
+			if err = s.denylist.Revoke(ctx, revocation.TokenKey(claims.ID), time.Until(claims.ExpiresAt.Time)); err != nil {
+				logs.Error("Error while revoking access token")
+				return nil, err
+			}
+			logs.Info("Successfully revoked access token")
+			return resp, nil
+		}
+	}
+	sessionID, err := s.refresh.RevokeRefreshToken(ctx, token.HashRefreshToken(req.Token))
+	if errors.Is(err, postgres.ErrRefreshTokenInvalid) {
+		return resp, nil
+	}
+	if err != nil {
+		logs.Error("Error while revoking refresh token")
+		return nil, err
+	}
+	if err = denySessions(ctx, s.denylist, s.tokens.AccessTTL(), []string{sessionID}); err != nil {
+		logs.Error("Error while revoking session tokens")
+		return nil, err
+	}
+	logs.Info("Successfully revoked refresh token")
+	return resp, nil
+}
+
+// GetJWKS returns the public keys access tokens can be verified with.
+func (s *UserServiceImpl) GetJWKS(ctx context.Context, req *pb.GetJWKSReq) (*pb.GetJWKSRes, error) {
+	res := &pb.GetJWKSRes{}
+	for _, key := range s.tokens.JWKS().Keys {
+		res.Keys = append(res.Keys, &pb.JWK{
+			Kty: key.Kty,
+			Kid: key.Kid,
+			Use: key.Use,
+			Alg: key.Alg,
+			N:   key.N,
+			E:   key.E,
+			Crv: key.Crv,
+			X:   key.X,
+		})
+	}
+	return res, nil
+}
+
+func (s *AdminServiceImpl) RotateSigningKey(ctx context.Context, req *pb.RotateSigningKeyReq) (*pb.RotateSigningKeyRes, error) {
+	logs, err := logger.NewLogger()
+	if err != nil {
+		return nil, err
+	}
+	key, err := s.rotator.Rotate(ctx, req.Emergency)
+	if errors.Is(err, keys.ErrSymmetricSigning) {
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	}
+	if err != nil {
+		logs.Error("Error while rotating signing key")
+		return nil, err
+	}
+	if req.Emergency {
+		logs.Warn("Emergency signing key rotation", zap.String("kid", key.ID))
+	}
+	logs.Info("Successfully rotated signing key")
+	return &pb.RotateSigningKeyRes{Message: "Signing key rotated", Kid: key.ID}, nil
+}
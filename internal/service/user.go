@@ -2,9 +2,22 @@ package service
 
 import (
 	"context"
+	"errors"
+	"strconv"
+	"time"
+
 	pb "blog-auth/genproto/userservice"
+	"blog-auth/internal/authz"
+	"blog-auth/internal/idp"
+	"blog-auth/internal/interceptor"
 	logger "blog-auth/internal/logger"
+	"blog-auth/internal/revocation"
 	"blog-auth/internal/storage/postgres"
+	"blog-auth/internal/token"
+
+	"github.com/go-webauthn/webauthn/webauthn"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 type UserService interface {
@@ -13,15 +26,145 @@ type UserService interface {
 	ForgotPassword(ctx context.Context, req *pb.ForgotPasswordReq) (*pb.ForgotPasswordRes, error)
 	UpdateUser(ctx context.Context, req *pb.UpdateUserReq) (*pb.UpdateUserRes, error)
 	VerifyEmail(ctx context.Context, req *pb.VerifyEmailReq) (*pb.VerifyEmailRes, error)
+	ResetPassword(ctx context.Context, req *pb.ResetPasswordReq) (*pb.ResetPasswordRes, error)
+	EnrollTOTP(ctx context.Context, req *pb.EnrollTOTPReq) (*pb.EnrollTOTPRes, error)
+	ConfirmTOTP(ctx context.Context, req *pb.ConfirmTOTPReq) (*pb.ConfirmTOTPRes, error)
+	VerifyMFA(ctx context.Context, req *pb.VerifyMFAReq) (*pb.LoginRes, error)
+	BeginPasskeyRegistration(ctx context.Context, req *pb.BeginPasskeyRegistrationReq) (*pb.BeginPasskeyRes, error)
+	FinishPasskeyRegistration(ctx context.Context, req *pb.FinishPasskeyRegistrationReq) (*pb.FinishPasskeyRegistrationRes, error)
+	BeginPasskeyLogin(ctx context.Context, req *pb.BeginPasskeyLoginReq) (*pb.BeginPasskeyRes, error)
+	FinishPasskeyLogin(ctx context.Context, req *pb.FinishPasskeyLoginReq) (*pb.LoginRes, error)
+	RequestMagicLink(ctx context.Context, req *pb.RequestMagicLinkReq) (*pb.RequestMagicLinkRes, error)
+	ConsumeMagicLink(ctx context.Context, req *pb.ConsumeMagicLinkReq) (*pb.LoginRes, error)
+	CheckPermission(ctx context.Context, req *pb.CheckPermissionReq) (*pb.CheckPermissionRes, error)
+	Enforce(ctx context.Context, req *pb.EnforceReq) (*pb.EnforceRes, error)
+	ListSessions(ctx context.Context, req *pb.ListSessionsReq) (*pb.ListSessionsRes, error)
+	RevokeSession(ctx context.Context, req *pb.RevokeSessionReq) (*pb.RevokeSessionRes, error)
+	RevokeAllOtherSessions(ctx context.Context, req *pb.RevokeAllOtherSessionsReq) (*pb.RevokeSessionRes, error)
+	IntrospectToken(ctx context.Context, req *pb.IntrospectTokenReq) (*pb.IntrospectTokenRes, error)
+	RevokeToken(ctx context.Context, req *pb.RevokeTokenReq) (*pb.RevokeTokenRes, error)
+	GetJWKS(ctx context.Context, req *pb.GetJWKSReq) (*pb.GetJWKSRes, error)
+	CreatePersonalAccessToken(ctx context.Context, req *pb.CreatePersonalAccessTokenReq) (*pb.CreatePersonalAccessTokenRes, error)
+	ListPersonalAccessTokens(ctx context.Context, req *pb.ListPersonalAccessTokensReq) (*pb.ListPersonalAccessTokensRes, error)
+	RevokePersonalAccessToken(ctx context.Context, req *pb.RevokePersonalAccessTokenReq) (*pb.RevokePersonalAccessTokenRes, error)
+	ListIdentityProviders(ctx context.Context, req *pb.ListIdentityProvidersReq) (*pb.ListIdentityProvidersRes, error)
+	BeginExternalLogin(ctx context.Context, req *pb.BeginExternalLoginReq) (*pb.BeginExternalLoginRes, error)
+	FinishExternalLogin(ctx context.Context, req *pb.FinishExternalLoginReq) (*pb.LoginRes, error)
+	LoginWithTelegram(ctx context.Context, req *pb.LoginWithTelegramReq) (*pb.LoginRes, error)
 }
 
+const minPasswordLength = 8
+
 type UserServiceImpl struct {
-	auth postgres.UsersStorage
+	auth        postgres.UsersStorage
+	refresh     postgres.RefreshTokenStorage
+	sessions    postgres.SessionStorage
+	mfa         postgres.MFAStorage
+	passkeys    *passkeyAuthenticator
+	magicLinks  postgres.MagicLinkStorage
+	throttle    postgres.LoginThrottleStorage
+	permissions postgres.PermissionStorage
+	authz       *authz.Engine
+	factors     []SecondFactor
+	denylist    revocation.Denylist
+	tokens      *token.Manager
+	pats        postgres.PersonalTokenStorage
+	identities  postgres.IdentityStorage
+	providers   *idp.Registry
 	pb.UnimplementedUserServiceServer
 }
 
-func NewUserService(auth UserService) *UserServiceImpl {
-	return &UserServiceImpl{auth: auth}
+func NewUserService(auth postgres.UsersStorage, refresh postgres.RefreshTokenStorage, sessions postgres.SessionStorage, mfa postgres.MFAStorage, passkeys postgres.PasskeyStorage, web *webauthn.WebAuthn, magicLinks postgres.MagicLinkStorage, throttle postgres.LoginThrottleStorage, permissions postgres.PermissionStorage, engine *authz.Engine, denylist revocation.Denylist, tokens *token.Manager, pats postgres.PersonalTokenStorage, identities postgres.IdentityStorage, providers *idp.Registry) *UserServiceImpl {
+	authenticator := &passkeyAuthenticator{web: web, passkeys: passkeys}
+	return &UserServiceImpl{
+		auth:        auth,
+		refresh:     refresh,
+		sessions:    sessions,
+		mfa:         mfa,
+		passkeys:    authenticator,
+		magicLinks:  magicLinks,
+		throttle:    throttle,
+		permissions: permissions,
+		authz:       engine,
+		factors: []SecondFactor{
+			totpFactor{mfa: mfa},
+			recoveryCodeFactor{mfa: mfa},
+			passkeyFactor{passkeys: authenticator},
+		},
+		denylist:   denylist,
+		tokens:     tokens,
+		pats:       pats,
+		identities: identities,
+		providers:  providers,
+	}
+}
+
+// issueTokens starts a new first-party session for the user and mints its
+// access token and first refresh token.
+func (s *UserServiceImpl) issueTokens(ctx context.Context, user *pb.UserModel) (access, refresh, expiresIn string, err error) {
+	return s.startSession(ctx, user, postgres.Device{}, true)
+}
+
+// startSession records a session for the user on the calling device and mints
+// its access token, and a refresh token if withRefresh is set. device carries
+// the OAuth client and scope, if any; the rest is filled in from ctx.
+func (s *UserServiceImpl) startSession(ctx context.Context, user *pb.UserModel, device postgres.Device, withRefresh bool) (access, refresh, expiresIn string, err error) {
+	if device.Name == "" {
+		device.Name = interceptor.DeviceName(ctx)
+	}
+	device.UserAgent = interceptor.UserAgent(ctx)
+	device.IP = interceptor.ClientIP(ctx)
+	// Without a refresh token the session ends with its access token.
+	expires := time.Now().Add(s.tokens.AccessTTL())
+	if withRefresh {
+		expires = time.Now().Add(s.tokens.RefreshTTL())
+	}
+	sessionID, err := s.sessions.CreateSession(ctx, user.Id, device, expires)
+	if err != nil {
+		return "", "", "", err
+	}
+	grant := postgres.Grant{SessionID: sessionID, ClientID: device.ClientID, Scope: device.Scope}
+	access, err = s.accessToken(ctx, user, grant)
+	if err != nil {
+		return "", "", "", err
+	}
+	if !withRefresh {
+		return access, "", s.expiresIn(), nil
+	}
+	refresh, hash, err := token.NewRefreshToken()
+	if err != nil {
+		return "", "", "", err
+	}
+	err = s.refresh.CreateRefreshToken(ctx, user.Id, sessionID, hash, expires)
+	if err != nil {
+		return "", "", "", err
+	}
+	return access, refresh, s.expiresIn(), nil
+}
+
+// accessToken signs an access token for a session. First-party sessions carry
+// the user's current permissions, OAuth sessions only their granted scope.
+func (s *UserServiceImpl) accessToken(ctx context.Context, user *pb.UserModel, grant postgres.Grant) (string, error) {
+	claims := token.Claims{
+		UserID:        user.Id,
+		Role:          user.Role,
+		EmailVerified: user.EmailVerified,
+		SessionID:     grant.SessionID,
+		ClientID:      grant.ClientID,
+		Scope:         grant.Scope,
+	}
+	if grant.ClientID == "" {
+		access, err := s.permissions.UserAccess(ctx, user.Id)
+		if err != nil {
+			return "", err
+		}
+		claims.Permissions = access.Permissions
+	}
+	return s.tokens.GenerateAccessToken(claims)
+}
+
+func (s *UserServiceImpl) expiresIn() string {
+	return strconv.Itoa(int(s.tokens.AccessTTL().Seconds()))
 }
 
 func (s *UserServiceImpl) Login(ctx context.Context, req *pb.LoginReq) (*pb.LoginRes, error) {
@@ -29,12 +172,55 @@ func (s *UserServiceImpl) Login(ctx context.Context, req *pb.LoginReq) (*pb.Logi
 	if err != nil {
 		return nil, err
 	}
+	resp, err := s.Authenticate(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.MfaRequired {
+		logs.Info("Login requires second factor")
+		return resp, nil
+	}
+	resp.AccessToken, resp.RefreshToken, resp.ExpiresIn, err = s.issueTokens(ctx, resp.UserRes)
+	if err != nil {
+		logs.Error("Error while issuing tokens")
+		return nil, err
+	}
+	logs.Info("Successfully login!")
+	return resp, nil
+}
+
+// Authenticate checks an email and password under the login throttle without
+// issuing tokens. If the user has a second factor, the result only carries an
+// MFA challenge for AuthenticateMFA.
+func (s *UserServiceImpl) Authenticate(ctx context.Context, req *pb.LoginReq) (*pb.LoginRes, error) {
+	logs, err := logger.NewLogger()
+	if err != nil {
+		return nil, err
+	}
+	ip := interceptor.ClientIP(ctx)
+	wait, err := s.throttle.CheckLogin(ctx, req.Email, ip)
+	switch {
+	case errors.Is(err, postgres.ErrAccountLocked), errors.Is(err, postgres.ErrLoginDelayed):
+		// Unknown emails are never locked, so a lock must look like a delay.
+		return nil, status.Errorf(codes.ResourceExhausted, "%s, retry in %s", postgres.ErrLoginDelayed, wait.Round(time.Second))
+	case err != nil:
+		logs.Error("Error while checking login throttle")
+		return nil, err
+	}
 	resp, err := s.auth.Login(ctx, req)
+	if errors.Is(err, postgres.ErrInvalidCredentials) {
+		if err := s.throttle.LoginFailed(ctx, req.Email, ip); err != nil {
+			logs.Error("Error while recording failed login")
+		}
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
 	if err != nil {
 		logs.Error("Error while calling Login")
 		return nil, err
 	}
-	logs.Info("Successfully login!")
+	if err = s.throttle.LoginSucceeded(ctx, req.Email, ip); err != nil {
+		logs.Error("Error while clearing failed logins")
+	}
 	return resp, nil
 }
 func (s *UserServiceImpl) RegisterUser(ctx context.Context, req *pb.RegisterUserReq) (*pb.RegisterUserRes, error) {
@@ -69,6 +255,9 @@ func (s *UserServiceImpl) UpdateUser(ctx context.Context, req *pb.UpdateUserReq)
 		return nil, err
 	}
 	resp, err := s.auth.UpdateUser(ctx, req)
+	if errors.Is(err, postgres.ErrUserNotFound) {
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
 	if err != nil {
 		logs.Error("Error while calling UpdateUser")
 		return nil, err
@@ -81,11 +270,178 @@ func (s *UserServiceImpl) VerifyEmail(ctx context.Context, req *pb.VerifyEmailRe
 	if err != nil {
 		return nil, err
 	}
-	resp, err := s.auth.VerifyEmail(ctx, req)
+	user, err := s.auth.VerifyEmail(ctx, req)
+	if errors.Is(err, postgres.ErrVerificationInvalid) || errors.Is(err, postgres.ErrVerificationAttempts) {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
 	if err != nil {
 		logs.Error("Error while calling VerifyEmail")
 		return nil, err
 	}
+	resp := &pb.VerifyEmailRes{Message: "Email verified"}
+	resp.AccessToken, resp.RefreshToken, resp.ExpiresIn, err = s.issueTokens(ctx, user)
+	if err != nil {
+		logs.Error("Error while issuing tokens")
+		return nil, err
+	}
 	logs.Info("Successfully verified the email")
 	return resp, nil
 }
+
+func (s *UserServiceImpl) RefreshToken(ctx context.Context, req *pb.RefreshTokenReq) (*pb.RefreshTokenRes, error) {
+	logs, err := logger.NewLogger()
+	if err != nil {
+		return nil, err
+	}
+	refresh, hash, err := token.NewRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+	user, grant, err := s.refresh.RotateRefreshToken(ctx, token.HashRefreshToken(req.RefreshToken), hash, "", interceptor.ClientIP(ctx), time.Now().Add(s.tokens.RefreshTTL()))
+	if errors.Is(err, postgres.ErrRefreshTokenInvalid) || errors.Is(err, postgres.ErrRefreshTokenReused) {
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+	if err != nil {
+		logs.Error("Error while calling RefreshToken")
+		return nil, err
+	}
+	access, err := s.accessToken(ctx, user, grant)
+	if err != nil {
+		logs.Error("Error while generating access token")
+		return nil, err
+	}
+	logs.Info("Successfully refreshed token")
+	return &pb.RefreshTokenRes{AccessToken: access, RefreshToken: refresh, ExpiresIn: s.expiresIn()}, nil
+}
+
+func (s *UserServiceImpl) ResetPassword(ctx context.Context, req *pb.ResetPasswordReq) (*pb.ResetPasswordRes, error) {
+	logs, err := logger.NewLogger()
+	if err != nil {
+		return nil, err
+	}
+	if len(req.NewPassword) < minPasswordLength {
+		return nil, status.Errorf(codes.InvalidArgument, "password must be at least %d characters", minPasswordLength)
+	}
+	ids, err := s.auth.ResetPassword(ctx, req)
+	if errors.Is(err, postgres.ErrPasswordResetInvalid) {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if err != nil {
+		logs.Error("Error while calling ResetPassword")
+		return nil, err
+	}
+	if err = denySessions(ctx, s.denylist, s.tokens.AccessTTL(), ids); err != nil {
+		logs.Error("Error while revoking session tokens")
+		return nil, err
+	}
+	logs.Info("Successfully reset the password")
+	return &pb.ResetPasswordRes{Message: "Password has been reset"}, nil
+}
+
+func (s *UserServiceImpl) EnrollTOTP(ctx context.Context, req *pb.EnrollTOTPReq) (*pb.EnrollTOTPRes, error) {
+	logs, err := logger.NewLogger()
+	if err != nil {
+		return nil, err
+	}
+	claims, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.mfa.EnrollTOTP(ctx, claims.UserID)
+	if errors.Is(err, postgres.ErrMFAAlreadyEnabled) {
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	}
+	if err != nil {
+		logs.Error("Error while calling EnrollTOTP")
+		return nil, err
+	}
+	logs.Info("Successfully started totp enrollment")
+	return resp, nil
+}
+
+func (s *UserServiceImpl) ConfirmTOTP(ctx context.Context, req *pb.ConfirmTOTPReq) (*pb.ConfirmTOTPRes, error) {
+	logs, err := logger.NewLogger()
+	if err != nil {
+		return nil, err
+	}
+	claims, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.mfa.ConfirmTOTP(ctx, claims.UserID, req.Code)
+	switch {
+	case errors.Is(err, postgres.ErrMFAAlreadyEnabled), errors.Is(err, postgres.ErrMFANotEnrolled):
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, postgres.ErrMFACodeInvalid):
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	case err != nil:
+		logs.Error("Error while calling ConfirmTOTP")
+		return nil, err
+	}
+	logs.Info("Successfully enabled totp")
+	return resp, nil
+}
+
+func (s *UserServiceImpl) VerifyMFA(ctx context.Context, req *pb.VerifyMFAReq) (*pb.LoginRes, error) {
+	logs, err := logger.NewLogger()
+	if err != nil {
+		return nil, err
+	}
+	user, err := s.AuthenticateMFA(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	resp := &pb.LoginRes{UserRes: user}
+	resp.AccessToken, resp.RefreshToken, resp.ExpiresIn, err = s.issueTokens(ctx, user)
+	if err != nil {
+		logs.Error("Error while issuing tokens")
+		return nil, err
+	}
+	logs.Info("Successfully login with second factor")
+	return resp, nil
+}
+
+// AuthenticateMFA completes the MFA challenge of Authenticate with one of the
+// user's second factors and returns the user, without issuing tokens.
+func (s *UserServiceImpl) AuthenticateMFA(ctx context.Context, req *pb.VerifyMFAReq) (*pb.UserModel, error) {
+	logs, err := logger.NewLogger()
+	if err != nil {
+		return nil, err
+	}
+	var factor SecondFactor
+	for _, f := range s.factors {
+		if f.Presented(req) {
+			factor = f
+			break
+		}
+	}
+	if factor == nil {
+		return nil, status.Error(codes.InvalidArgument, "no second factor presented")
+	}
+
+	challengeID, userID, err := s.mfa.StartMFAAttempt(ctx, req.MfaToken)
+	if errors.Is(err, postgres.ErrMFAChallengeInvalid) {
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+	if err != nil {
+		logs.Error("Error while starting mfa attempt")
+		return nil, err
+	}
+	err = factor.Verify(ctx, userID, req)
+	if errors.Is(err, postgres.ErrMFACodeInvalid) {
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+	if err != nil {
+		logs.Error("Error while verifying second factor")
+		return nil, err
+	}
+	user, err := s.mfa.CompleteMFAChallenge(ctx, challengeID)
+	if errors.Is(err, postgres.ErrMFAChallengeInvalid) {
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+	if err != nil {
+		logs.Error("Error while completing mfa challenge")
+		return nil, err
+	}
+	return user, nil
+}
@@ -3,6 +3,7 @@ package postgres
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"strconv"
 	"time"
 
@@ -10,50 +11,67 @@ import (
 	"github.com/spf13/cast"
 
 	pb "blog-auth/genproto/userservice"
+	"blog-auth/internal/config"
 	logger "blog-auth/internal/logger"
+	"blog-auth/internal/password"
 
 	"go.uber.org/zap"
 )
 
+var (
+	ErrEmailNotFound = errors.New("failed email not found")
+	ErrEmailCleared  = errors.New("failed email content has been cleared, the user must request it again")
+	ErrUserNotFound  = errors.New("user not found")
+)
+
 type AdminStorage interface {
 	CreateUser(ctx context.Context, req *pb.CreateUserReq) (*pb.CreateUserRes, error)
 	GetUser(ctx context.Context, req *pb.GetUserByIDReq) (*pb.GetUserByIDRes, error)
 	ForgetPassword(ctx context.Context, req *pb.ForgetPasswordReq) (*pb.ForgetPasswordRes, error)
 	GetAllUsers(ctx context.Context, req *pb.GetAllUsersReq) (*pb.GetAllUsersRes, error)
 	DeleteUser(ctx context.Context, req *pb.DeleteUserReq) (*pb.DeleteUserRes, error)
+	ListFailedEmails(ctx context.Context, req *pb.ListFailedEmailsReq) (*pb.ListFailedEmailsRes, error)
+	ResendEmail(ctx context.Context, req *pb.ResendEmailReq) (*pb.ResendEmailRes, error)
+	UnlockUser(ctx context.Context, req *pb.UnlockUserReq) (*pb.UnlockUserRes, error)
 }
 type AdminStorageImpl struct {
-	db *sql.DB
+	db     *sql.DB
+	cfg    config.Config
+	hasher *password.Hasher
 }
 
-func NewAdminStorage(db *sql.DB) AdminStorage {
+func NewAdminStorage(db *sql.DB, cfg config.Config) AdminStorage {
 	return &AdminStorageImpl{
-		db: db,
+		db:     db,
+		cfg:    cfg,
+		hasher: password.NewHasher(cfg),
 	}
 }
 
 func (s *AdminStorageImpl) CreateUser(ctx context.Context, req *pb.CreateUserReq) (*pb.CreateUserRes, error) {
 	id := uuid.New().String()
-	query := `INSERT INTO Users (id, fname, lname, email, password) VALUES ($1, $2, $3, $4, $5, $6)`
+	query := `INSERT INTO users (id, name, lastname, email, password) VALUES ($1, $2, $3, $4, $5)`
 	logs, err := logger.NewLogger()
 	if err != nil {
 		return nil, err
 	}
 
-	password, err := hashPassword(req.Password)
+	password, err := s.hasher.Hash(req.Password)
 	if err != nil {
 		logs.Error("Error hashing password", zap.Error(err))
+		return nil, err
 	}
 	_, err = s.db.ExecContext(ctx, query, id, req.Fname, req.Lname, req.Email, password)
 	if err != nil {
-		logs.Error("Error creating admin", zap.Error(err))
+		logs.Error("Error creating user", zap.Error(err))
+		return nil, err
 	}
 	logs.Info("Successfully created user")
 	user, err := s.GetUser(ctx, &pb.GetUserByIDReq{UserId: id})
 	if err != nil {
 		logs.Error("Error getting user", zap.Error(err))
+		return nil, err
 	}
-	logs.Info("Successfully got user")
 	return &pb.CreateUserRes{AdminRes: user.UserRes}, nil
 }
 
@@ -62,9 +80,12 @@ func (s *AdminStorageImpl) GetUser(ctx context.Context, req *pb.GetUserByIDReq)
 	if err != nil {
 		return nil, err
 	}
-	query := `select id, fname, lname, email,bio, phone, role, created_at, updated_at from users where id = $1`
+	query := `select id, name, lastname, email, bio, phone, profile_image, role, email_verified, created_at, updated_at from users where id = $1 and deleted_at = 0`
 	user := pb.UserModel{}
-	err = s.db.QueryRowContext(ctx, query, req.UserId).Scan(&user.Id, &user.Fname, &user.Lname, &user.Email, &user.Bio, &user.Phone, &user.Role, &user.CreatedAt, &user.UpdatedAt)
+	err = s.db.QueryRowContext(ctx, query, req.UserId).Scan(&user.Id, &user.Fname, &user.Lname, &user.Email, &user.Bio, &user.Phone, &user.ProfileImage, &user.Role, &user.EmailVerified, &user.CreatedAt, &user.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrUserNotFound
+	}
 	if err != nil {
 		logs.Error("Error getting user", zap.Error(err))
 		return nil, err
@@ -77,7 +98,8 @@ func (s *AdminStorageImpl) GetUser(ctx context.Context, req *pb.GetUserByIDReq)
 }
 
 func (s *AdminStorageImpl) ForgetPassword(ctx context.Context, req *pb.ForgetPasswordReq) (*pb.ForgetPasswordRes, error) {
-	return nil, nil
+	requestPasswordReset(ctx, s.db, s.cfg, req.AdminEmail)
+	return &pb.ForgetPasswordRes{Message: forgotPasswordMessage}, nil
 }
 
 func (s *AdminStorageImpl) GetAllUsers(ctx context.Context, req *pb.GetAllUsersReq) (*pb.GetAllUsersRes, error) {
@@ -194,3 +216,130 @@ func (s *AdminStorageImpl) DeleteUser(ctx context.Context, req *pb.DeleteUserReq
 	return &pb.DeleteUserRes{Message: "Success"}, nil
 
 }
+
+func (s *AdminStorageImpl) ListFailedEmails(ctx context.Context, req *pb.ListFailedEmailsReq) (*pb.ListFailedEmailsRes, error) {
+	logs, err := logger.NewLogger()
+	if err != nil {
+		return nil, err
+	}
+	limit := req.Limit
+	if limit <= 0 {
+		limit = 20
+	}
+	query := `
+		SELECT
+			id,
+			recipient,
+			subject,
+			attempts,
+			last_error,
+			created_at,
+			updated_at
+		FROM
+			email_queue
+		WHERE
+			status = 'dead'
+		ORDER BY
+			updated_at DESC
+		LIMIT $1 OFFSET $2
+	`
+	rows, err := s.db.QueryContext(ctx, query, limit, req.Offset)
+	if err != nil {
+		logs.Error("Error listing failed emails", zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	resp := pb.ListFailedEmailsRes{}
+	for rows.Next() {
+		email := pb.FailedEmail{}
+		err = rows.Scan(&email.Id, &email.Recipient, &email.Subject, &email.Attempts, &email.LastError, &email.CreatedAt, &email.UpdatedAt)
+		if err != nil {
+			logs.Error("Error scanning failed email", zap.Error(err))
+			return nil, err
+		}
+		resp.Emails = append(resp.Emails, &email)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	err = s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM email_queue WHERE status = 'dead'`).Scan(&resp.TotalCount)
+	if err != nil {
+		logs.Error("Error counting failed emails", zap.Error(err))
+		return nil, err
+	}
+	return &resp, nil
+}
+
+func (s *AdminStorageImpl) ResendEmail(ctx context.Context, req *pb.ResendEmailReq) (*pb.ResendEmailRes, error) {
+	logs, err := logger.NewLogger()
+	if err != nil {
+		return nil, err
+	}
+	query := `
+		UPDATE email_queue SET
+			status = 'pending',
+			attempts = 0,
+			last_error = '',
+			next_attempt_at = now(),
+			updated_at = now()
+		WHERE
+			id = $1 AND status = 'dead' AND (text_body <> '' OR html_body <> '')
+	`
+	res, err := s.db.ExecContext(ctx, query, req.Id)
+	if err != nil {
+		logs.Error("Error requeueing email", zap.Error(err))
+		return nil, err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		var exists bool
+		err = s.db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM email_queue WHERE id = $1 AND status = 'dead')`, req.Id).Scan(&exists)
+		if err != nil {
+			logs.Error("Error getting failed email", zap.Error(err))
+			return nil, err
+		}
+		if exists {
+			return nil, ErrEmailCleared
+		}
+		return nil, ErrEmailNotFound
+	}
+	return &pb.ResendEmailRes{Message: "Email queued for delivery"}, nil
+}
+
+// UnlockUser lifts a lockout and clears the account's failed login counter.
+func (s *AdminStorageImpl) UnlockUser(ctx context.Context, req *pb.UnlockUserReq) (*pb.UnlockUserRes, error) {
+	logs, err := logger.NewLogger()
+	if err != nil {
+		return nil, err
+	}
+	if _, err = uuid.Parse(req.UserId); err != nil {
+		return nil, ErrUserNotFound
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `UPDATE users SET locked_until = NULL WHERE id = $1 AND deleted_at = 0`, req.UserId)
+	if err != nil {
+		logs.Error("Error unlocking user", zap.Error(err))
+		return nil, err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return nil, ErrUserNotFound
+	}
+	_, err = tx.ExecContext(ctx, `DELETE FROM login_failures WHERE scope = $1 AND subject = $2`, scopeAccount, req.UserId)
+	if err != nil {
+		logs.Error("Error clearing login failures", zap.Error(err))
+		return nil, err
+	}
+	if err = writeAudit(ctx, tx, req.UserId, eventAccountUnlocked, "unlocked by admin"); err != nil {
+		return nil, err
+	}
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+	return &pb.UnlockUserRes{Message: "User unlocked"}, nil
+}
@@ -0,0 +1,35 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/google/uuid"
+)
+
+const (
+	eventRefreshTokenReused  = "refresh_token_reused"
+	eventMFAEnabled          = "mfa_enabled"
+	eventMFARecoveryCodeUsed = "mfa_recovery_code_used"
+	eventAccountLocked       = "account_locked"
+	eventAccountUnlocked     = "account_unlocked"
+	eventRoleAssigned        = "role_assigned"
+	eventRoleUnassigned      = "role_unassigned"
+	eventSessionRevoked      = "session_revoked"
+)
+
+// execer is satisfied by both *sql.DB and *sql.Tx, so audit entries can be
+// written inside the transaction that triggered them.
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+func writeAudit(ctx context.Context, db execer, userID, event, details string) error {
+	query := `INSERT INTO audit_logs (id, user_id, event, details) VALUES ($1, $2, $3, $4)`
+	var uid interface{}
+	if userID != "" {
+		uid = userID
+	}
+	_, err := db.ExecContext(ctx, query, uuid.NewString(), uid, event, details)
+	return err
+}
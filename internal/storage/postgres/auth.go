@@ -4,42 +4,41 @@ import (
 	"context"
 	"database/sql"
 	"errors"
-	"fmt"
 	"strconv"
 	"strings"
 
 	pb "blog-auth/genproto/userservice"
+	"blog-auth/internal/config"
 	logger "blog-auth/internal/logger"
+	"blog-auth/internal/password"
+	"blog-auth/internal/token"
 
 	"github.com/google/uuid"
 	"go.uber.org/zap"
-	"golang.org/x/crypto/bcrypt"
 )
 
+// ErrInvalidCredentials is returned for both unknown emails and wrong
+// passwords, so Login cannot be used to enumerate users.
+var ErrInvalidCredentials = errors.New("email or password is not correct")
+
 type UsersStorage interface {
 	Login(ctx context.Context, req *pb.LoginReq) (*pb.LoginRes, error)
 	RegisterUser(ctx context.Context, req *pb.RegisterUserReq) (*pb.RegisterUserRes, error)
 	ForgotPassword(ctx context.Context, req *pb.ForgotPasswordReq) (*pb.ForgotPasswordRes, error)
 	UpdateUser(ctx context.Context, req *pb.UpdateUserReq) (*pb.UpdateUserRes, error)
-	VerifyEmail(ctx context.Context, req *pb.VerifyEmailReq) (*pb.VerifyEmailRes, error)
-}
-
-func hashPassword(password string) (string, error) {
-	bytes, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
-	return string(bytes), err
-}
-
-func checkPasswordHash(password, hash string) bool {
-	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
-	return err == nil
+	VerifyEmail(ctx context.Context, req *pb.VerifyEmailReq) (*pb.UserModel, error)
+	ResetPassword(ctx context.Context, req *pb.ResetPasswordReq) ([]string, error)
+	GetUser(ctx context.Context, userID string) (*pb.UserModel, error)
 }
 
 type userStorage struct {
-	db *sql.DB
+	db     *sql.DB
+	cfg    config.Config
+	hasher *password.Hasher
 }
 
-func NewUserStorage(db *sql.DB) UsersStorage {
-	return &userStorage{db: db}
+func NewUserStorage(db *sql.DB, cfg config.Config) UsersStorage {
+	return &userStorage{db: db, cfg: cfg, hasher: password.NewHasher(cfg)}
 }
 
 func (s *userStorage) Login(ctx context.Context, req *pb.LoginReq) (*pb.LoginRes, error) {
@@ -55,8 +54,9 @@ func (s *userStorage) Login(ctx context.Context, req *pb.LoginReq) (*pb.LoginRes
 			email,
 			password,
 			role,
+			email_verified,
 			created_at,
-			updated_at		
+			updated_at
 		FROM
 			users
 		WHERE
@@ -65,30 +65,66 @@ func (s *userStorage) Login(ctx context.Context, req *pb.LoginReq) (*pb.LoginRes
 	res := pb.LoginRes{
 		UserRes: &pb.UserModel{},
 	}
-	var password string
+	var hash string
 	err = s.db.QueryRow(query, req.Email).Scan(
 		&res.UserRes.Id,
 		&res.UserRes.Fname,
 		&res.UserRes.Lname,
 		&res.UserRes.Email,
-		&password,
+		&hash,
 		&res.UserRes.Role,
+		&res.UserRes.EmailVerified,
 		&res.UserRes.CreatedAt,
 		&res.UserRes.UpdatedAt,
 	)
+	if errors.Is(err, sql.ErrNoRows) {
+		s.hasher.VerifyDummy(req.Password)
+		return nil, ErrInvalidCredentials
+	}
 	if err != nil {
 		logs.Error("Error with scan:", zap.Error(err))
 		return nil, err
 	}
-	ok := checkPasswordHash(req.Password, password)
+	if hash == "" {
+		// Passwordless accounts must not answer faster than wrong passwords.
+		s.hasher.VerifyDummy(req.Password)
+		return nil, ErrInvalidCredentials
+	}
+	ok, needsRehash, err := s.hasher.Verify(req.Password, hash)
+	if err != nil {
+		logs.Error("Error verifying password", zap.Error(err))
+		return nil, err
+	}
 	if !ok {
-		return nil, fmt.Errorf("password not correct")
+		return nil, ErrInvalidCredentials
+	}
+	if needsRehash {
+		s.rehashPassword(ctx, res.UserRes.Id, hash, req.Password)
 	}
 
-	return &pb.LoginRes{UserRes: res.UserRes}, err
+	return firstFactorLogin(ctx, s.db, s.cfg, res.UserRes)
 
 }
 
+// rehashPassword upgrades a hash made with outdated parameters. It only
+// replaces the hash the login was checked against, and a failure does not
+// fail the login.
+func (s *userStorage) rehashPassword(ctx context.Context, userID, oldHash, plain string) {
+	logs, err := logger.NewLogger()
+	if err != nil {
+		return
+	}
+	hash, err := s.hasher.Hash(plain)
+	if err != nil {
+		logs.Error("Error rehashing password", zap.Error(err))
+		return
+	}
+	query := `UPDATE users SET password = $1 WHERE id = $2 AND password = $3`
+	if _, err = s.db.ExecContext(ctx, query, hash, userID, oldHash); err != nil {
+		logs.Error("Error updating password hash", zap.Error(err))
+	}
+}
+
 func (s *userStorage) RegisterUser(ctx context.Context, req *pb.RegisterUserReq) (*pb.RegisterUserRes, error) {
 	logs, err := logger.NewLogger()
 	if err != nil {
@@ -97,56 +133,68 @@ func (s *userStorage) RegisterUser(ctx context.Context, req *pb.RegisterUserReq)
 
 	query := `
 		INSERT INTO users (
-			id, fname, lname, email, password
+			id, name, lastname, email, password
 		) VALUES (
-			$1, $2, $3, $4, $5, $6, $7, $8, 
-			CURRENT_TIMESTAMP, CURRENT_TIMESTAMP, 0
+			$1, $2, $3, $4, $5
 		);`
 
-	hashpass, err := hashPassword(req.Password)
+	hashpass, err := s.hasher.Hash(req.Password)
 	if err != nil {
 		logs.Error("Error with create user")
 		return nil, err
 	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
 	id := uuid.NewString()
-	_, err = s.db.ExecContext(ctx, query, id, req.Fname, req.Lname, req.Email, hashpass)
+	_, err = tx.ExecContext(ctx, query, id, req.Fname, req.Lname, req.Email, hashpass)
 	if err != nil {
 		logs.Error("Error with create user")
 		return nil, err
 	}
 
-	query1 := `select id, fname, lname, email,bio, phone, role, created_at, updated_at from users where id = $1`
+	code, err := createEmailVerification(ctx, tx, s.cfg, id)
+	if err != nil {
+		logs.Error("Error creating email verification", zap.Error(err))
+		return nil, err
+	}
+
+	query1 := `select id, name, lastname, email, role, email_verified, created_at, updated_at from users where id = $1`
 	user := pb.UserModel{}
-	err = s.db.QueryRowContext(ctx, query1, id).Scan(&user.Id, &user.Fname, &user.Lname, &user.Email,&user.Bio,&user.Phone, &user.Role, &user.CreatedAt, &user.UpdatedAt)
+	err = tx.QueryRowContext(ctx, query1, id).Scan(&user.Id, &user.Fname, &user.Lname, &user.Email, &user.Role, &user.EmailVerified, &user.CreatedAt, &user.UpdatedAt)
 	if err != nil {
 		logs.Error("Error getting user", zap.Error(err))
 		return nil, err
 	}
-	
-	userM := &pb.UserModel{
-		Id:        user.Id,
-		Fname:     user.Fname,
-		Lname:     user.Lname,
-		Email:     user.Email,
-		Bio:       user.Bio,
-		Phone:     user.Phone,
-		Role:      user.Role,
-		CreatedAt: user.CreatedAt,
-		UpdatedAt: user.UpdatedAt,
+	err = enqueueCode(ctx, tx, user.Email, user.Fname, "Your email verification code", code)
+	if err != nil {
+		logs.Error("Error queueing verification email", zap.Error(err))
+		return nil, err
+	}
+	if err = tx.Commit(); err != nil {
+		return nil, err
 	}
 
-	return &pb.RegisterUserRes{UserRes: userM}, nil
+	return &pb.RegisterUserRes{UserRes: &user}, nil
 }
 
 func (s *userStorage) ForgotPassword(ctx context.Context, req *pb.ForgotPasswordReq) (*pb.ForgotPasswordRes, error) {
-	//logs, err := logger.NewLogger()
-	//if err != nil {
-	//
-	//	return nil, err
-	//}
-	return nil, nil
+	requestPasswordReset(ctx, s.db, s.cfg, req.Email)
+	return &pb.ForgotPasswordRes{Message: forgotPasswordMessage}, nil
 }
 
+// ResetPassword sets a new password with a reset token and returns the ids of
+// the sessions it logged out.
+func (s *userStorage) ResetPassword(ctx context.Context, req *pb.ResetPasswordReq) ([]string, error) {
+	return resetPassword(ctx, s.db, s.hasher, token.HashOpaqueToken(req.Token), req.NewPassword)
+}
+
+// UpdateUser changes the profile fields that are set. A new email is not
+// trusted until it is verified again, since magic links, password resets and
+// upstream logins all rely on it, so a code is sent to it.
 func (s *userStorage) UpdateUser(ctx context.Context, req *pb.UpdateUserReq) (*pb.UpdateUserRes, error) {
 	query := "UPDATE users SET"
 	var args []interface{}
@@ -158,23 +206,17 @@ func (s *userStorage) UpdateUser(ctx context.Context, req *pb.UpdateUserReq) (*p
 	}
 	// Check for fields and build query
 	if req.UserReq.Fname != "string" && req.UserReq.Fname != "" {
-		updates = append(updates, " fname = $"+strconv.Itoa(argCounter))
+		updates = append(updates, " name = $"+strconv.Itoa(argCounter))
 		args = append(args, req.UserReq.Fname)
 		argCounter++
 	}
 
 	if req.UserReq.Lname != "string" && req.UserReq.Lname != "" {
-		updates = append(updates, " lname = $"+strconv.Itoa(argCounter))
+		updates = append(updates, " lastname = $"+strconv.Itoa(argCounter))
 		args = append(args, req.UserReq.Lname)
 		argCounter++
 	}
 
-	if req.UserReq.Email != "string" && req.UserReq.Email != "" {
-		updates = append(updates, " email = $"+strconv.Itoa(argCounter))
-		args = append(args, req.UserReq.Email)
-		argCounter++
-	}
-
 	if req.UserReq.Bio != "string" && req.UserReq.Bio != "" {
 		updates = append(updates, " bio = $"+strconv.Itoa(argCounter))
 		args = append(args, req.UserReq.Bio)
@@ -187,22 +229,88 @@ func (s *userStorage) UpdateUser(ctx context.Context, req *pb.UpdateUserReq) (*p
 		argCounter++
 	}
 
+	if req.UserReq.ProfileImage != "string" && req.UserReq.ProfileImage != "" {
+		updates = append(updates, " profile_image = $"+strconv.Itoa(argCounter))
+		args = append(args, req.UserReq.ProfileImage)
+		argCounter++
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var currentEmail string
+	err = tx.QueryRowContext(ctx, `SELECT email FROM users WHERE id = $1 AND deleted_at = 0 FOR UPDATE`, req.UserReq.Id).Scan(&currentEmail)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		logs.Error("Error getting user", zap.Error(err))
+		return nil, err
+	}
+	emailChanged := req.UserReq.Email != "string" && req.UserReq.Email != "" && req.UserReq.Email != currentEmail
+	if emailChanged {
+		updates = append(updates, " email = $"+strconv.Itoa(argCounter), " email_verified = false")
+		args = append(args, req.UserReq.Email)
+		argCounter++
+	}
+
 	if len(updates) == 0 {
 		return nil, errors.New("no fields to update")
 	}
 
-	query += " " + strings.Join(updates, ", ") + " WHERE id = $" + strconv.Itoa(argCounter)
+	query += " " + strings.Join(updates, ", ") + ", updated_at = now() WHERE id = $" + strconv.Itoa(argCounter) + `
+		RETURNING id, name, lastname, email, bio, phone, profile_image, role, email_verified, created_at, updated_at`
 	args = append(args, req.UserReq.Id)
 
-	_, err = s.db.ExecContext(ctx, query, args...)
+	user := pb.UserModel{}
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&user.Id, &user.Fname, &user.Lname, &user.Email, &user.Bio, &user.Phone, &user.ProfileImage, &user.Role, &user.EmailVerified, &user.CreatedAt, &user.UpdatedAt)
 	if err != nil {
 		logs.Error("Error updating user: ", zap.Error(err))
 		return nil, err
 	}
+	if emailChanged {
+		code, err := createEmailVerification(ctx, tx, s.cfg, user.Id)
+		if err != nil {
+			logs.Error("Error creating email verification", zap.Error(err))
+			return nil, err
+		}
+		err = enqueueCode(ctx, tx, user.Email, user.Fname, "Your email verification code", code)
+		if err != nil {
+			logs.Error("Error queueing verification email", zap.Error(err))
+			return nil, err
+		}
+	}
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+	return &pb.UpdateUserRes{UserRes: &user}, nil
+}
 
-	return &pb.UpdateUserRes{UserRes: req.UserReq}, nil
+func (s *userStorage) VerifyEmail(ctx context.Context, req *pb.VerifyEmailReq) (*pb.UserModel, error) {
+	return verifyEmail(ctx, s.db, s.cfg, req.Email, req.Code)
 }
 
-func (s *userStorage) VerifyEmail(ctx context.Context, req *pb.VerifyEmailReq) (*pb.VerifyEmailRes, error) {
-	return nil, nil
+// GetUser returns an active user by id.
+func (s *userStorage) GetUser(ctx context.Context, userID string) (*pb.UserModel, error) {
+	logs, err := logger.NewLogger()
+	if err != nil {
+		return nil, err
+	}
+	if _, err = uuid.Parse(userID); err != nil {
+		return nil, ErrUserNotFound
+	}
+	query := `select id, name, lastname, email, bio, phone, profile_image, role, email_verified, created_at, updated_at from users where id = $1 and deleted_at = 0`
+	user := pb.UserModel{}
+	err = s.db.QueryRowContext(ctx, query, userID).Scan(&user.Id, &user.Fname, &user.Lname, &user.Email, &user.Bio, &user.Phone, &user.ProfileImage, &user.Role, &user.EmailVerified, &user.CreatedAt, &user.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		logs.Error("Error getting user", zap.Error(err))
+		return nil, err
+	}
+	return &user, nil
 }
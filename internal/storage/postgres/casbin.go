@@ -0,0 +1,106 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/casbin/casbin/v2/model"
+	"github.com/casbin/casbin/v2/persist"
+)
+
+const casbinFields = 6
+
+// casbinAdapter stores authorization policy rules in the casbin_rules table,
+// one rule per row with unused fields left empty.
+type casbinAdapter struct {
+	db *sql.DB
+}
+
+func NewCasbinAdapter(db *sql.DB) persist.Adapter {
+	return &casbinAdapter{db: db}
+}
+
+func casbinValues(ptype string, rule []string) []interface{} {
+	values := []interface{}{ptype}
+	for i := 0; i < casbinFields; i++ {
+		v := ""
+		if i < len(rule) {
+			v = rule[i]
+		}
+		values = append(values, v)
+	}
+	return values
+}
+
+func (a *casbinAdapter) LoadPolicy(m model.Model) error {
+	rows, err := a.db.Query(`SELECT ptype, v0, v1, v2, v3, v4, v5 FROM casbin_rules ORDER BY id`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		rule := make([]string, casbinFields+1)
+		if err = rows.Scan(&rule[0], &rule[1], &rule[2], &rule[3], &rule[4], &rule[5], &rule[6]); err != nil {
+			return err
+		}
+		for len(rule) > 1 && rule[len(rule)-1] == "" {
+			rule = rule[:len(rule)-1]
+		}
+		if err = persist.LoadPolicyArray(rule, m); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+func (a *casbinAdapter) SavePolicy(m model.Model) error {
+	ctx := context.Background()
+	tx, err := a.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err = tx.ExecContext(ctx, `DELETE FROM casbin_rules`); err != nil {
+		return err
+	}
+	query := `INSERT INTO casbin_rules (ptype, v0, v1, v2, v3, v4, v5) VALUES ($1, $2, $3, $4, $5, $6, $7) ON CONFLICT DO NOTHING`
+	for _, sec := range []string{"p", "g"} {
+		for ptype, ast := range m[sec] {
+			for _, rule := range ast.Policy {
+				if _, err = tx.ExecContext(ctx, query, casbinValues(ptype, rule)...); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return tx.Commit()
+}
+
+func (a *casbinAdapter) AddPolicy(sec string, ptype string, rule []string) error {
+	query := `INSERT INTO casbin_rules (ptype, v0, v1, v2, v3, v4, v5) VALUES ($1, $2, $3, $4, $5, $6, $7) ON CONFLICT DO NOTHING`
+	_, err := a.db.Exec(query, casbinValues(ptype, rule)...)
+	return err
+}
+
+func (a *casbinAdapter) RemovePolicy(sec string, ptype string, rule []string) error {
+	query := `DELETE FROM casbin_rules WHERE ptype = $1 AND v0 = $2 AND v1 = $3 AND v2 = $4 AND v3 = $5 AND v4 = $6 AND v5 = $7`
+	_, err := a.db.Exec(query, casbinValues(ptype, rule)...)
+	return err
+}
+
+func (a *casbinAdapter) RemoveFilteredPolicy(sec string, ptype string, fieldIndex int, fieldValues ...string) error {
+	where := []string{"ptype = $1"}
+	args := []interface{}{ptype}
+	for i, v := range fieldValues {
+		if v == "" || fieldIndex+i >= casbinFields {
+			continue
+		}
+		args = append(args, v)
+		where = append(where, fmt.Sprintf("v%d = $%d", fieldIndex+i, len(args)))
+	}
+	_, err := a.db.Exec(`DELETE FROM casbin_rules WHERE `+strings.Join(where, " AND "), args...)
+	return err
+}
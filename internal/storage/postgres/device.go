@@ -0,0 +1,208 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	logger "blog-auth/internal/logger"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrDeviceCodeInvalid = errors.New("device code is invalid")
+	ErrDeviceCodeExpired = errors.New("device code has expired")
+	ErrDevicePending     = errors.New("authorization is pending")
+	ErrDeviceSlowDown    = errors.New("polling too fast")
+	ErrDeviceDenied      = errors.New("the user denied the request")
+)
+
+const (
+	deviceStatusPending  = "pending"
+	deviceStatusApproved = "approved"
+	deviceStatusDenied   = "denied"
+
+	// deviceSlowDownStep is added to the interval of a device that polls too
+	// fast, as RFC 8628 asks.
+	deviceSlowDownStep = 5 * time.Second
+)
+
+// DeviceCode is a pending device authorization. The device polls with the
+// device code; the user approves it by entering the user code.
+type DeviceCode struct {
+	Hash      string
+	UserCode  string
+	ClientID  string
+	Scope     string
+	UserID    string
+	Interval  time.Duration
+	ExpiresAt time.Time
+}
+
+func (s *oauthStorage) CreateDeviceCode(ctx context.Context, code DeviceCode) error {
+	logs, err := logger.NewLogger()
+	if err != nil {
+		return err
+	}
+	query := `
+		INSERT INTO oauth_device_codes (device_code_hash, user_code, client_id, scope, poll_interval, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`
+	_, err = s.db.ExecContext(ctx, query, code.Hash, code.UserCode, code.ClientID, code.Scope, int(code.Interval.Seconds()), code.ExpiresAt)
+	if err != nil {
+		logs.Error("Error creating device code", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// PendingDeviceCode returns the unexpired device authorization waiting for
+// the user to enter userCode.
+func (s *oauthStorage) PendingDeviceCode(ctx context.Context, userCode string) (*DeviceCode, error) {
+	logs, err := logger.NewLogger()
+	if err != nil {
+		return nil, err
+	}
+	query := `
+		SELECT
+			device_code_hash,
+			user_code,
+			client_id,
+			scope,
+			poll_interval,
+			expires_at
+		FROM
+			oauth_device_codes
+		WHERE
+			user_code = $1 AND status = $2 AND expires_at > now()
+	`
+	code := DeviceCode{}
+	var interval int
+	err = s.db.QueryRowContext(ctx, query, userCode, deviceStatusPending).Scan(&code.Hash, &code.UserCode, &code.ClientID, &code.Scope, &interval, &code.ExpiresAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrDeviceCodeInvalid
+	}
+	if err != nil {
+		logs.Error("Error getting device code", zap.Error(err))
+		return nil, err
+	}
+	code.Interval = time.Duration(interval) * time.Second
+	return &code, nil
+}
+
+// ResolveDeviceCode records the answer of the signed in user to a pending
+// device authorization, whether they approved or denied it.
+func (s *oauthStorage) ResolveDeviceCode(ctx context.Context, userCode, userID string, approved bool) error {
+	logs, err := logger.NewLogger()
+	if err != nil {
+		return err
+	}
+	if _, err := uuid.Parse(userID); err != nil {
+		return ErrUserNotFound
+	}
+	status := deviceStatusDenied
+	if approved {
+		status = deviceStatusApproved
+	}
+	query := `
+		UPDATE oauth_device_codes SET status = $1, user_id = $2::uuid
+		WHERE user_code = $3 AND status = $4 AND expires_at > now()
+	`
+	res, err := s.db.ExecContext(ctx, query, status, userID, userCode, deviceStatusPending)
+	if err != nil {
+		logs.Error("Error resolving device code", zap.Error(err))
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrDeviceCodeInvalid
+	}
+	return nil
+}
+
+// PollDeviceCode answers a poll of the client's device. Once approved, the
+// code is returned a single time with the user who approved it; before that
+// the error says why not. Polling faster than the interval slows it down.
+func (s *oauthStorage) PollDeviceCode(ctx context.Context, codeHash, clientID string) (*DeviceCode, error) {
+	logs, err := logger.NewLogger()
+	if err != nil {
+		return nil, err
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	query := `
+		SELECT
+			user_code,
+			client_id,
+			scope,
+			COALESCE(user_id::text, ''),
+			status,
+			poll_interval,
+			last_polled_at IS NOT NULL AND last_polled_at + poll_interval * interval '1 second' > now(),
+			expires_at <= now(),
+			used_at IS NOT NULL,
+			expires_at
+		FROM
+			oauth_device_codes
+		WHERE
+			device_code_hash = $1
+		FOR UPDATE
+	`
+	code := DeviceCode{Hash: codeHash}
+	var (
+		status               string
+		interval             int
+		early, expired, used bool
+	)
+	err = tx.QueryRowContext(ctx, query, codeHash).Scan(&code.UserCode, &code.ClientID, &code.Scope, &code.UserID, &status, &interval, &early, &expired, &used, &code.ExpiresAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrDeviceCodeInvalid
+	}
+	if err != nil {
+		logs.Error("Error polling device code", zap.Error(err))
+		return nil, err
+	}
+	if code.ClientID != clientID || used {
+		return nil, ErrDeviceCodeInvalid
+	}
+	if expired {
+		return nil, ErrDeviceCodeExpired
+	}
+	if early {
+		_, err = tx.ExecContext(ctx, `UPDATE oauth_device_codes SET poll_interval = poll_interval + $1, last_polled_at = now() WHERE device_code_hash = $2`, int(deviceSlowDownStep.Seconds()), codeHash)
+		if err == nil {
+			err = tx.Commit()
+		}
+		if err != nil {
+			logs.Error("Error slowing down device", zap.Error(err))
+			return nil, err
+		}
+		return nil, ErrDeviceSlowDown
+	}
+
+	update := `UPDATE oauth_device_codes SET last_polled_at = now() WHERE device_code_hash = $1`
+	if status == deviceStatusApproved {
+		update = `UPDATE oauth_device_codes SET last_polled_at = now(), used_at = now() WHERE device_code_hash = $1`
+	}
+	if _, err = tx.ExecContext(ctx, update, codeHash); err != nil {
+		logs.Error("Error updating device code", zap.Error(err))
+		return nil, err
+	}
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+	switch status {
+	case deviceStatusApproved:
+		code.Interval = time.Duration(interval) * time.Second
+		return &code, nil
+	case deviceStatusDenied:
+		return nil, ErrDeviceDenied
+	}
+	return nil, ErrDevicePending
+}
@@ -0,0 +1,153 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"blog-auth/internal/helper"
+	logger "blog-auth/internal/logger"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// QueuedEmail is a message claimed from the outbound email queue.
+type QueuedEmail struct {
+	ID       string
+	Attempts int
+	Message  helper.Message
+}
+
+type EmailQueueStorage interface {
+	ClaimEmails(ctx context.Context, limit int, lease time.Duration) ([]QueuedEmail, error)
+	MarkEmailSent(ctx context.Context, id string) error
+	MarkEmailFailed(ctx context.Context, id string, sendErr error, nextAttempt time.Time, dead bool) error
+	PurgeEmails(ctx context.Context, before time.Time) (int64, error)
+}
+
+type emailQueueStorage struct {
+	db *sql.DB
+}
+
+func NewEmailQueueStorage(db *sql.DB) EmailQueueStorage {
+	return &emailQueueStorage{db: db}
+}
+
+// enqueueEmail stores msg in the outbound queue. Pass the transaction of the
+// action that triggers the email so both commit or roll back together. The
+// bodies carry codes and links that work until used, so they are cleared as
+// soon as the email is sent or dead-lettered.
+func enqueueEmail(ctx context.Context, db execer, msg helper.Message) error {
+	query := `INSERT INTO email_queue (id, recipient, subject, text_body, html_body) VALUES ($1, $2, $3, $4, $5)`
+	_, err := db.ExecContext(ctx, query, uuid.NewString(), msg.To, msg.Subject, msg.Text, msg.HTML)
+	return err
+}
+
+// enqueueCode renders the code email template and queues it.
+func enqueueCode(ctx context.Context, db execer, to, userName, message, code string) error {
+	msg, err := helper.RenderCode(helper.Params{
+		To:       to,
+		Message:  message,
+		Code:     code,
+		UserName: userName,
+	})
+	if err != nil {
+		return err
+	}
+	return enqueueEmail(ctx, db, msg)
+}
+
+// ClaimEmails leases up to limit due emails to the caller. Emails whose lease
+// ran out while "sending" (for example because a worker crashed) are claimed
+// again.
+func (s *emailQueueStorage) ClaimEmails(ctx context.Context, limit int, lease time.Duration) ([]QueuedEmail, error) {
+	logs, err := logger.NewLogger()
+	if err != nil {
+		return nil, err
+	}
+	query := `
+		UPDATE email_queue SET
+			status = 'sending',
+			attempts = attempts + 1,
+			locked_until = $2,
+			updated_at = now()
+		WHERE id IN (
+			SELECT
+				id
+			FROM
+				email_queue
+			WHERE
+				(status = 'pending' AND next_attempt_at <= now())
+				OR (status = 'sending' AND locked_until < now())
+			ORDER BY
+				next_attempt_at
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, attempts, recipient, subject, text_body, html_body
+	`
+	rows, err := s.db.QueryContext(ctx, query, limit, time.Now().Add(lease))
+	if err != nil {
+		logs.Error("Error claiming emails", zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	var emails []QueuedEmail
+	for rows.Next() {
+		e := QueuedEmail{}
+		err = rows.Scan(&e.ID, &e.Attempts, &e.Message.To, &e.Message.Subject, &e.Message.Text, &e.Message.HTML)
+		if err != nil {
+			logs.Error("Error scanning email", zap.Error(err))
+			return nil, err
+		}
+		emails = append(emails, e)
+	}
+	return emails, rows.Err()
+}
+
+func (s *emailQueueStorage) MarkEmailSent(ctx context.Context, id string) error {
+	query := `UPDATE email_queue SET status = 'sent', sent_at = now(), locked_until = NULL, text_body = '', html_body = '', updated_at = now() WHERE id = $1`
+	_, err := s.db.ExecContext(ctx, query, id)
+	return err
+}
+
+// MarkEmailFailed schedules another attempt, or with dead gives up on the
+// email and clears its bodies. A dead email cannot be resent; the user has to
+// ask for a new code or link.
+func (s *emailQueueStorage) MarkEmailFailed(ctx context.Context, id string, sendErr error, nextAttempt time.Time, dead bool) error {
+	status := "pending"
+	if dead {
+		status = "dead"
+	}
+	query := `
+		UPDATE email_queue SET
+			status = $2,
+			last_error = $3,
+			next_attempt_at = $4,
+			locked_until = NULL,
+			text_body = CASE WHEN $5 THEN '' ELSE text_body END,
+			html_body = CASE WHEN $5 THEN '' ELSE html_body END,
+			updated_at = now()
+		WHERE
+			id = $1
+	`
+	_, err := s.db.ExecContext(ctx, query, id, status, sendErr.Error(), nextAttempt, dead)
+	return err
+}
+
+// PurgeEmails deletes sent and dead emails last updated before before and
+// returns how many it deleted.
+func (s *emailQueueStorage) PurgeEmails(ctx context.Context, before time.Time) (int64, error) {
+	logs, err := logger.NewLogger()
+	if err != nil {
+		return 0, err
+	}
+	res, err := s.db.ExecContext(ctx, `DELETE FROM email_queue WHERE status IN ('sent', 'dead') AND updated_at < $1`, before)
+	if err != nil {
+		logs.Error("Error purging emails", zap.Error(err))
+		return 0, err
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,131 @@
+package postgres
+
+import (
+	"context"
+	"crypto/subtle"
+	"database/sql"
+	"errors"
+	"time"
+
+	pb "blog-auth/genproto/userservice"
+	"blog-auth/internal/config"
+	logger "blog-auth/internal/logger"
+	"blog-auth/internal/token"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrVerificationInvalid  = errors.New("verification code is invalid or expired")
+	ErrVerificationAttempts = errors.New("too many verification attempts, request a new code")
+)
+
+// createEmailVerification replaces any pending code of the user with a new one
+// and returns it in plain text so it can be mailed.
+func createEmailVerification(ctx context.Context, db execer, cfg config.Config, userID string) (string, error) {
+	code, err := token.NewVerificationCode()
+	if err != nil {
+		return "", err
+	}
+	_, err = db.ExecContext(ctx, `UPDATE email_verifications SET used_at = now() WHERE user_id = $1 AND used_at IS NULL`, userID)
+	if err != nil {
+		return "", err
+	}
+	query := `INSERT INTO email_verifications (id, user_id, code_hash, expires_at) VALUES ($1, $2, $3, $4)`
+	_, err = db.ExecContext(ctx, query, uuid.NewString(), userID, token.HashVerificationCode(token.Subkey(cfg.TOKENKEY, token.KeyVerificationCode), code), time.Now().Add(cfg.VERIFYCODETTL))
+	if err != nil {
+		return "", err
+	}
+	return code, nil
+}
+
+// verifyEmail checks the code against the pending verification of the user and
+// marks the account verified. Every wrong guess counts towards the attempt
+// limit, after which the code can no longer be used.
+func verifyEmail(ctx context.Context, db *sql.DB, cfg config.Config, email, code string) (*pb.UserModel, error) {
+	logs, err := logger.NewLogger()
+	if err != nil {
+		return nil, err
+	}
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	query := `
+		SELECT
+			v.id,
+			v.code_hash,
+			v.attempts,
+			v.expires_at,
+			u.id,
+			u.name,
+			u.lastname,
+			u.email,
+			u.role,
+			u.created_at,
+			u.updated_at
+		FROM
+			email_verifications v
+		JOIN
+			users u ON u.id = v.user_id
+		WHERE
+			u.email = $1 AND u.deleted_at = 0 AND v.used_at IS NULL
+		ORDER BY
+			v.created_at DESC
+		LIMIT 1
+		FOR UPDATE OF v
+	`
+	var (
+		id, codeHash string
+		attempts     int
+		expires      time.Time
+	)
+	user := pb.UserModel{}
+	err = tx.QueryRowContext(ctx, query, email).Scan(&id, &codeHash, &attempts, &expires,
+		&user.Id, &user.Fname, &user.Lname, &user.Email, &user.Role, &user.CreatedAt, &user.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrVerificationInvalid
+	}
+	if err != nil {
+		logs.Error("Error getting email verification", zap.Error(err))
+		return nil, err
+	}
+	if time.Now().After(expires) {
+		return nil, ErrVerificationInvalid
+	}
+	if attempts >= cfg.VERIFYATTEMPTS {
+		return nil, ErrVerificationAttempts
+	}
+
+	given := token.HashVerificationCode(token.Subkey(cfg.TOKENKEY, token.KeyVerificationCode), code)
+	if subtle.ConstantTimeCompare([]byte(given), []byte(codeHash)) != 1 {
+		_, err = tx.ExecContext(ctx, `UPDATE email_verifications SET attempts = attempts + 1 WHERE id = $1`, id)
+		if err != nil {
+			logs.Error("Error updating verification attempts", zap.Error(err))
+			return nil, err
+		}
+		if err = tx.Commit(); err != nil {
+			return nil, err
+		}
+		return nil, ErrVerificationInvalid
+	}
+
+	_, err = tx.ExecContext(ctx, `UPDATE email_verifications SET used_at = now() WHERE id = $1`, id)
+	if err != nil {
+		logs.Error("Error consuming email verification", zap.Error(err))
+		return nil, err
+	}
+	_, err = tx.ExecContext(ctx, `UPDATE users SET email_verified = true, updated_at = now() WHERE id = $1`, user.Id)
+	if err != nil {
+		logs.Error("Error marking email verified", zap.Error(err))
+		return nil, err
+	}
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+	user.EmailVerified = true
+	return &user, nil
+}
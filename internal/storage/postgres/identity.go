@@ -0,0 +1,270 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	pb "blog-auth/genproto/userservice"
+	"blog-auth/internal/config"
+	logger "blog-auth/internal/logger"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrExternalLoginInvalid   = errors.New("external login is invalid or expired")
+	ErrIdentityNotVerified    = errors.New("the identity provider has not verified this email")
+	ErrAccountNotVerified     = errors.New("an account with this email exists but its email is not verified; sign in with your password and verify it first")
+	ErrIdentitySignupDisabled = errors.New("no account uses this email and signing up through this provider is disabled")
+)
+
+// LoginState is what a login with an upstream provider has to remember
+// between sending the user there and the callback.
+type LoginState struct {
+	Hash         string
+	Provider     string
+	Nonce        string
+	CodeVerifier string
+}
+
+// ExternalIdentity is a user as an upstream provider vouches for them.
+// Subject is the provider's stable id for the user.
+type ExternalIdentity struct {
+	Provider      string
+	Subject       string
+	Email         string
+	EmailVerified bool
+	GivenName     string
+	FamilyName    string
+	Picture       string
+}
+
+type IdentityStorage interface {
+	CreateLoginState(ctx context.Context, state LoginState) error
+	ConsumeLoginState(ctx context.Context, stateHash string) (*LoginState, error)
+	ExternalLogin(ctx context.Context, identity ExternalIdentity, signup bool) (*pb.LoginRes, error)
+	TelegramLogin(ctx context.Context, identity ExternalIdentity) (*pb.LoginRes, error)
+}
+
+type identityStorage struct {
+	db  *sql.DB
+	cfg config.Config
+}
+
+func NewIdentityStorage(db *sql.DB, cfg config.Config) IdentityStorage {
+	return &identityStorage{db: db, cfg: cfg}
+}
+
+func (s *identityStorage) CreateLoginState(ctx context.Context, state LoginState) error {
+	logs, err := logger.NewLogger()
+	if err != nil {
+		return err
+	}
+	query := `
+		INSERT INTO external_login_states (state_hash, provider, nonce, code_verifier, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+	`
+	_, err = s.db.ExecContext(ctx, query, state.Hash, state.Provider, state.Nonce, state.CodeVerifier, time.Now().Add(s.cfg.IDPLOGINTTL))
+	if err != nil {
+		logs.Error("Error creating external login state", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// ConsumeLoginState burns a login state, so a callback can only be finished
+// once.
+func (s *identityStorage) ConsumeLoginState(ctx context.Context, stateHash string) (*LoginState, error) {
+	logs, err := logger.NewLogger()
+	if err != nil {
+		return nil, err
+	}
+	state := LoginState{Hash: stateHash}
+	query := `
+		UPDATE external_login_states SET
+			used_at = now()
+		WHERE
+			state_hash = $1 AND used_at IS NULL AND expires_at > now()
+		RETURNING provider, nonce, code_verifier
+	`
+	err = s.db.QueryRowContext(ctx, query, stateHash).Scan(&state.Provider, &state.Nonce, &state.CodeVerifier)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrExternalLoginInvalid
+	}
+	if err != nil {
+		logs.Error("Error consuming external login state", zap.Error(err))
+		return nil, err
+	}
+	return &state, nil
+}
+
+// ExternalLogin signs in the user an upstream identity belongs to. An identity
+// seen for the first time is linked to the account with the same email, but
+// only when both the provider and this service have verified that email,
+// so nobody can take over an account by registering its address first. With
+// signup set and no such account, one is created.
+func (s *identityStorage) ExternalLogin(ctx context.Context, identity ExternalIdentity, signup bool) (*pb.LoginRes, error) {
+	logs, err := logger.NewLogger()
+	if err != nil {
+		return nil, err
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	userID, err := linkedUser(ctx, tx, identity)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		logs.Error("Error getting user identity", zap.Error(err))
+		return nil, err
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		var verified bool
+		query := `SELECT id, email_verified FROM users WHERE email = $1 AND deleted_at = 0`
+		err = tx.QueryRowContext(ctx, query, identity.Email).Scan(&userID, &verified)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			logs.Error("Error getting user", zap.Error(err))
+			return nil, err
+		}
+		create, err := linkByEmail(identity, !errors.Is(err, sql.ErrNoRows), verified, signup)
+		if err != nil {
+			return nil, err
+		}
+		if create {
+			if userID, err = s.createUser(ctx, tx, identity, true); err != nil {
+				logs.Error("Error creating user", zap.Error(err))
+				return nil, err
+			}
+		}
+	}
+	return s.login(ctx, tx, userID, identity)
+}
+
+// TelegramLogin signs in the user a Telegram account is linked to, creating
+// the account on first use. Telegram shares no email, so the new account
+// gets a placeholder under the reserved .invalid domain that nothing can be
+// delivered to.
+func (s *identityStorage) TelegramLogin(ctx context.Context, identity ExternalIdentity) (*pb.LoginRes, error) {
+	logs, err := logger.NewLogger()
+	if err != nil {
+		return nil, err
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	userID, err := linkedUser(ctx, tx, identity)
+	if errors.Is(err, sql.ErrNoRows) {
+		identity.Email = fmt.Sprintf("telegram-%s@telegram.invalid", identity.Subject)
+		userID, err = s.createUser(ctx, tx, identity, false)
+		if err != nil {
+			logs.Error("Error creating user", zap.Error(err))
+			return nil, err
+		}
+		identity.Email = ""
+	}
+	if err != nil {
+		logs.Error("Error getting user identity", zap.Error(err))
+		return nil, err
+	}
+	return s.login(ctx, tx, userID, identity)
+}
+
+// login links identity to the user, refreshes it and finishes the login in
+// tx.
+func (s *identityStorage) login(ctx context.Context, tx *sql.Tx, userID string, identity ExternalIdentity) (*pb.LoginRes, error) {
+	logs, err := logger.NewLogger()
+	if err != nil {
+		return nil, err
+	}
+	// The identity row of a deleted account moves to the new one.
+	query := `
+		INSERT INTO user_identities (
+			id, user_id, provider, subject, email, last_login_at
+		) VALUES (
+			$1, $2, $3, $4, $5, now()
+		)
+		ON CONFLICT (provider, subject) DO UPDATE SET
+			user_id = EXCLUDED.user_id,
+			email = EXCLUDED.email,
+			last_login_at = now()
+	`
+	_, err = tx.ExecContext(ctx, query, uuid.NewString(), userID, identity.Provider, identity.Subject, identity.Email)
+	if err != nil {
+		logs.Error("Error saving user identity", zap.Error(err))
+		return nil, err
+	}
+
+	user := pb.UserModel{}
+	query = `SELECT id, name, lastname, email, role, email_verified, created_at, updated_at FROM users WHERE id = $1`
+	err = tx.QueryRowContext(ctx, query, userID).Scan(&user.Id, &user.Fname, &user.Lname, &user.Email, &user.Role, &user.EmailVerified, &user.CreatedAt, &user.UpdatedAt)
+	if err != nil {
+		logs.Error("Error getting user", zap.Error(err))
+		return nil, err
+	}
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+	return firstFactorLogin(ctx, s.db, s.cfg, &user)
+}
+
+// linkByEmail decides what happens to an identity seen for the first time,
+// given whether an account uses its email and whether the account has
+// verified it. create is true when a new account has to be made for it.
+func linkByEmail(identity ExternalIdentity, exists, verified, signup bool) (create bool, err error) {
+	switch {
+	case identity.Email == "" || !identity.EmailVerified:
+		return false, ErrIdentityNotVerified
+	case !exists && !signup:
+		return false, ErrIdentitySignupDisabled
+	case !exists:
+		return true, nil
+	case !verified:
+		return false, ErrAccountNotVerified
+	}
+	return false, nil
+}
+
+// linkedUser returns the id of the live account identity is linked to.
+func linkedUser(ctx context.Context, tx *sql.Tx, identity ExternalIdentity) (string, error) {
+	var userID string
+	query := `
+		SELECT
+			u.id
+		FROM
+			user_identities i
+		JOIN
+			users u ON u.id = i.user_id
+		WHERE
+			i.provider = $1 AND i.subject = $2 AND u.deleted_at = 0
+	`
+	err := tx.QueryRowContext(ctx, query, identity.Provider, identity.Subject).Scan(&userID)
+	return userID, err
+}
+
+// createUser signs up the person behind identity. As with passwordless
+// signups the password stays empty, which never matches a hash.
+func (s *identityStorage) createUser(ctx context.Context, tx *sql.Tx, identity ExternalIdentity, emailVerified bool) (string, error) {
+	name := identity.GivenName
+	if name == "" {
+		name, _, _ = strings.Cut(identity.Email, "@")
+	}
+	id := uuid.NewString()
+	query := `
+		INSERT INTO users (
+			id, name, lastname, email, password, email_verified, profile_image
+		) VALUES (
+			$1, $2, $3, $4, '', $5, $6
+		)
+	`
+	_, err := tx.ExecContext(ctx, query, id, name, identity.FamilyName, identity.Email, emailVerified, identity.Picture)
+	return id, err
+}
@@ -0,0 +1,43 @@
+package postgres
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestLinkByEmail(t *testing.T) {
+	verified := ExternalIdentity{Provider: "mock", Subject: "user-1", Email: "ann@example.com", EmailVerified: true}
+	unverified := verified
+	unverified.EmailVerified = false
+	noEmail := verified
+	noEmail.Email = ""
+
+	tests := []struct {
+		name       string
+		identity   ExternalIdentity
+		exists     bool
+		verified   bool
+		signup     bool
+		wantCreate bool
+		wantErr    error
+	}{
+		{"links verified account", verified, true, true, false, false, nil},
+		{"links verified account with signup", verified, true, true, true, false, nil},
+		{"creates account", verified, false, false, true, true, nil},
+		{"signup disabled", verified, false, false, false, false, ErrIdentitySignupDisabled},
+		{"account not verified", verified, true, false, true, false, ErrAccountNotVerified},
+		{"provider did not verify email", unverified, true, true, true, false, ErrIdentityNotVerified},
+		{"no email", noEmail, false, false, true, false, ErrIdentityNotVerified},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			create, err := linkByEmail(tt.identity, tt.exists, tt.verified, tt.signup)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if create != tt.wantCreate {
+				t.Errorf("create = %v, want %v", create, tt.wantCreate)
+			}
+		})
+	}
+}
@@ -0,0 +1,256 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"blog-auth/internal/config"
+	"blog-auth/internal/helper"
+	logger "blog-auth/internal/logger"
+
+	"go.uber.org/zap"
+)
+
+const (
+	scopeAccount = "account"
+	scopeIP      = "ip"
+
+	// failureWindow is how long a counter is kept after its last failure.
+	failureWindow = 24 * time.Hour
+)
+
+var (
+	ErrAccountLocked = errors.New("account is temporarily locked")
+	ErrLoginDelayed  = errors.New("too many failed login attempts, try again later")
+)
+
+// LoginThrottleStorage counts failed password logins per account and per
+// source IP. Every failure past the free ones doubles the delay before the next
+// attempt is accepted, and an account is locked for LOCKDURATION once it
+// reaches LOCKTHRESHOLD failures.
+type LoginThrottleStorage interface {
+	CheckLogin(ctx context.Context, email, ip string) (time.Duration, error)
+	LoginFailed(ctx context.Context, email, ip string) error
+	LoginSucceeded(ctx context.Context, email, ip string) error
+}
+
+type loginThrottleStorage struct {
+	db  *sql.DB
+	cfg config.Config
+}
+
+func NewLoginThrottleStorage(db *sql.DB, cfg config.Config) LoginThrottleStorage {
+	return &loginThrottleStorage{db: db, cfg: cfg}
+}
+
+// failureDelay doubles LOGINDELAY for every failure past free, capped at
+// LOGINMAXDELAY.
+func failureDelay(cfg config.Config, failures, free int) time.Duration {
+	if failures <= free {
+		return 0
+	}
+	d := cfg.LOGINDELAY
+	for i := free + 1; i < failures && d < cfg.LOGINMAXDELAY; i++ {
+		d *= 2
+	}
+	if d > cfg.LOGINMAXDELAY {
+		d = cfg.LOGINMAXDELAY
+	}
+	return d
+}
+
+// CheckLogin returns ErrAccountLocked or ErrLoginDelayed, together with how long
+// the caller has to wait, if a login for email from ip must not be attempted
+// yet. Otherwise it reserves the attempt by counting it as a failure up front,
+// so parallel attempts cannot all get past the check before any of them
+// fails. LoginFailed and LoginSucceeded settle the reservation.
+func (s *loginThrottleStorage) CheckLogin(ctx context.Context, email, ip string) (time.Duration, error) {
+	logs, err := logger.NewLogger()
+	if err != nil {
+		return 0, err
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var userID string
+	var lockedUntil sql.NullTime
+	query := `SELECT id, locked_until FROM users WHERE email = $1 AND deleted_at = 0 FOR UPDATE`
+	err = tx.QueryRowContext(ctx, query, email).Scan(&userID, &lockedUntil)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		logs.Error("Error getting user", zap.Error(err))
+		return 0, err
+	}
+	now := time.Now()
+	if lockedUntil.Valid && lockedUntil.Time.After(now) {
+		return lockedUntil.Time.Sub(now), ErrAccountLocked
+	}
+
+	type counter struct {
+		scope, subject string
+		free           int
+	}
+	var counters []counter
+	if userID != "" {
+		counters = append(counters, counter{scopeAccount, userID, s.cfg.LOGINFREEFAILS})
+	}
+	if ip != "" {
+		counters = append(counters, counter{scopeIP, ip, s.cfg.IPFREEFAILS})
+	}
+	var wait time.Duration
+	for _, c := range counters {
+		var blockedUntil sql.NullTime
+		query = `SELECT blocked_until FROM login_failures WHERE scope = $1 AND subject = $2 FOR UPDATE`
+		err = tx.QueryRowContext(ctx, query, c.scope, c.subject).Scan(&blockedUntil)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			logs.Error("Error getting login failures", zap.Error(err), zap.String("scope", c.scope))
+			return 0, err
+		}
+		if blockedUntil.Valid && blockedUntil.Time.Sub(now) > wait {
+			wait = blockedUntil.Time.Sub(now)
+		}
+	}
+	if wait > 0 {
+		return wait, ErrLoginDelayed
+	}
+	for _, c := range counters {
+		if _, err = s.countFailure(ctx, tx, c.scope, c.subject, c.free); err != nil {
+			logs.Error("Error reserving login attempt", zap.Error(err), zap.String("scope", c.scope))
+			return 0, err
+		}
+	}
+	return 0, tx.Commit()
+}
+
+// LoginFailed settles a failed login that CheckLogin already counted, locking
+// the account once it reaches LOCKTHRESHOLD failures. Unknown emails only
+// count against the source IP, so there is nothing left to do for them.
+func (s *loginThrottleStorage) LoginFailed(ctx context.Context, email, ip string) error {
+	logs, err := logger.NewLogger()
+	if err != nil {
+		return err
+	}
+	if s.cfg.LOCKTHRESHOLD <= 0 {
+		return nil
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var userID, name string
+	var lockedUntil sql.NullTime
+	query := `SELECT id, name, locked_until FROM users WHERE email = $1 AND deleted_at = 0 FOR UPDATE`
+	err = tx.QueryRowContext(ctx, query, email).Scan(&userID, &name, &lockedUntil)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		logs.Error("Error getting user", zap.Error(err))
+		return err
+	}
+	var failures int
+	query = `SELECT failures FROM login_failures WHERE scope = $1 AND subject = $2`
+	err = tx.QueryRowContext(ctx, query, scopeAccount, userID).Scan(&failures)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		logs.Error("Error getting account failures", zap.Error(err))
+		return err
+	}
+	if failures < s.cfg.LOCKTHRESHOLD || (lockedUntil.Valid && lockedUntil.Time.After(time.Now())) {
+		return nil
+	}
+
+	until := time.Now().Add(s.cfg.LOCKDURATION)
+	_, err = tx.ExecContext(ctx, `UPDATE users SET locked_until = $2 WHERE id = $1`, userID, until)
+	if err != nil {
+		logs.Error("Error locking account", zap.Error(err))
+		return err
+	}
+	// The lock replaces the delays, so the user gets their free attempts back
+	// once it expires.
+	_, err = tx.ExecContext(ctx, `DELETE FROM login_failures WHERE scope = $1 AND subject = $2`, scopeAccount, userID)
+	if err != nil {
+		return err
+	}
+	details := fmt.Sprintf("%d failed logins, last from %s", failures, ip)
+	if err = writeAudit(ctx, tx, userID, eventAccountLocked, details); err != nil {
+		return err
+	}
+	msg, err := helper.RenderCode(helper.Params{
+		To:       email,
+		Message:  "There were too many failed sign-in attempts on your account. It is locked until",
+		Code:     until.UTC().Format("2006-01-02 15:04 MST"),
+		UserName: name,
+	})
+	if err != nil {
+		return err
+	}
+	msg.Subject = "Your account has been temporarily locked"
+	if err = enqueueEmail(ctx, tx, msg); err != nil {
+		logs.Error("Error queueing account locked email", zap.Error(err))
+		return err
+	}
+	logs.Warn("Account locked", zap.String("user_id", userID), zap.Int("failures", failures))
+	return tx.Commit()
+}
+
+// countFailure increments a counter, starting over if its last failure is
+// older than failureWindow, and pushes out the time of the next allowed
+// attempt. It returns the new number of failures.
+func (s *loginThrottleStorage) countFailure(ctx context.Context, tx *sql.Tx, scope, subject string, free int) (int, error) {
+	var failures int
+	query := `
+		INSERT INTO login_failures (scope, subject, failures, updated_at) VALUES ($1, $2, 1, now())
+		ON CONFLICT (scope, subject) DO UPDATE SET
+			failures = CASE WHEN login_failures.updated_at < $3 THEN 1 ELSE login_failures.failures + 1 END,
+			updated_at = now()
+		RETURNING failures
+	`
+	err := tx.QueryRowContext(ctx, query, scope, subject, time.Now().Add(-failureWindow)).Scan(&failures)
+	if err != nil {
+		return 0, err
+	}
+	var blockedUntil interface{}
+	if d := failureDelay(s.cfg, failures, free); d > 0 {
+		blockedUntil = time.Now().Add(d)
+	}
+	query = `UPDATE login_failures SET blocked_until = $3 WHERE scope = $1 AND subject = $2`
+	if _, err = tx.ExecContext(ctx, query, scope, subject, blockedUntil); err != nil {
+		return 0, err
+	}
+	return failures, nil
+}
+
+// LoginSucceeded clears the account's failures and takes back the attempt
+// CheckLogin reserved on the IP counter. The rest of the IP counter is left
+// alone so one valid account cannot be used to reset it.
+func (s *loginThrottleStorage) LoginSucceeded(ctx context.Context, email, ip string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `DELETE FROM login_failures WHERE scope = $1 AND subject IN (SELECT id::text FROM users WHERE email = $2)`
+	if _, err = tx.ExecContext(ctx, query, scopeAccount, email); err != nil {
+		return err
+	}
+	if ip != "" {
+		query = `
+			UPDATE login_failures SET
+				failures = failures - 1,
+				blocked_until = CASE WHEN failures - 1 <= $3 THEN NULL ELSE blocked_until END
+			WHERE scope = $1 AND subject = $2 AND failures > 0
+		`
+		if _, err = tx.ExecContext(ctx, query, scopeIP, ip, s.cfg.IPFREEFAILS); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,210 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/url"
+	"strings"
+	"time"
+
+	pb "blog-auth/genproto/userservice"
+	"blog-auth/internal/config"
+	"blog-auth/internal/helper"
+	logger "blog-auth/internal/logger"
+	"blog-auth/internal/token"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// magicLinkMessage is returned whether or not a link was sent, so the endpoint
+// cannot be used to enumerate users.
+const magicLinkMessage = "If this email can be used to sign in, a login link has been sent"
+
+var ErrMagicLinkInvalid = errors.New("login link is invalid or expired")
+
+type MagicLinkStorage interface {
+	RequestMagicLink(ctx context.Context, req *pb.RequestMagicLinkReq) (*pb.RequestMagicLinkRes, error)
+	ConsumeMagicLink(ctx context.Context, req *pb.ConsumeMagicLinkReq) (*pb.LoginRes, []string, error)
+}
+
+type magicLinkStorage struct {
+	db  *sql.DB
+	cfg config.Config
+}
+
+func NewMagicLinkStorage(db *sql.DB, cfg config.Config) MagicLinkStorage {
+	return &magicLinkStorage{db: db, cfg: cfg}
+}
+
+// RequestMagicLink queues a single-use login link for email. Unknown emails only
+// get a link when MAGICSIGNUP allows creating the account on first use.
+func (s *magicLinkStorage) RequestMagicLink(ctx context.Context, req *pb.RequestMagicLinkReq) (*pb.RequestMagicLinkRes, error) {
+	logs, err := logger.NewLogger()
+	if err != nil {
+		return nil, err
+	}
+	email := strings.TrimSpace(req.Email)
+	res := &pb.RequestMagicLinkRes{Message: magicLinkMessage}
+	if email == "" {
+		return res, nil
+	}
+
+	var userID sql.NullString
+	var name string
+	query := `SELECT id, name FROM users WHERE email = $1 AND deleted_at = 0`
+	err = s.db.QueryRowContext(ctx, query, email).Scan(&userID, &name)
+	if errors.Is(err, sql.ErrNoRows) && !s.cfg.MAGICSIGNUP {
+		return res, nil
+	}
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		logs.Error("Error getting user", zap.Error(err))
+		return nil, err
+	}
+
+	plain, hash, err := token.NewMagicLinkToken(token.Subkey(s.cfg.TOKENKEY, token.KeyMagicLink))
+	if err != nil {
+		return nil, err
+	}
+	link, err := url.Parse(s.cfg.MAGICLINKURL)
+	if err != nil {
+		return nil, err
+	}
+	q := link.Query()
+	q.Set("token", plain)
+	link.RawQuery = q.Encode()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	query = `INSERT INTO magic_links (id, user_id, email, token_hash, expires_at) VALUES ($1, $2, $3, $4, $5)`
+	_, err = tx.ExecContext(ctx, query, uuid.NewString(), userID, email, hash, time.Now().Add(s.cfg.MAGICLINKTTL))
+	if err != nil {
+		logs.Error("Error creating magic link", zap.Error(err))
+		return nil, err
+	}
+	msg, err := helper.RenderCode(helper.Params{
+		To:       email,
+		Message:  "Use this link to sign in",
+		Link:     link.String(),
+		UserName: name,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err = enqueueEmail(ctx, tx, msg); err != nil {
+		logs.Error("Error queueing magic link email", zap.Error(err))
+		return nil, err
+	}
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// ConsumeMagicLink burns a login link and logs its owner in. Following the link
+// proves the address, so the email is marked verified, and a link sent to an
+// unknown email creates the account.
+//
+// Until then anyone could have registered the address and set a password or
+// signed in, so verifying an existing account for the first time clears its
+// password and revokes its sessions. The revoked session ids are returned so
+// their access tokens can be denied.
+func (s *magicLinkStorage) ConsumeMagicLink(ctx context.Context, req *pb.ConsumeMagicLinkReq) (*pb.LoginRes, []string, error) {
+	logs, err := logger.NewLogger()
+	if err != nil {
+		return nil, nil, err
+	}
+	hash, ok := token.VerifyMagicLinkToken(token.Subkey(s.cfg.TOKENKEY, token.KeyMagicLink), req.Token)
+	if !ok {
+		return nil, nil, ErrMagicLinkInvalid
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer tx.Rollback()
+
+	var email string
+	query := `
+		UPDATE magic_links SET
+			used_at = now()
+		WHERE
+			token_hash = $1 AND used_at IS NULL AND expires_at > now()
+		RETURNING email
+	`
+	err = tx.QueryRowContext(ctx, query, hash).Scan(&email)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil, ErrMagicLinkInvalid
+	}
+	if err != nil {
+		logs.Error("Error consuming magic link", zap.Error(err))
+		return nil, nil, err
+	}
+
+	if s.cfg.MAGICSIGNUP {
+		// The password is left empty, which never matches a hash, so the
+		// account can only sign in passwordless until a password is set.
+		query = `
+			INSERT INTO users (
+				id, name, lastname, email, password
+			) VALUES (
+				$1, $2, '', $3, ''
+			)
+			ON CONFLICT (email) DO NOTHING
+		`
+		name, _, _ := strings.Cut(email, "@")
+		_, err = tx.ExecContext(ctx, query, uuid.NewString(), name, email)
+		if err != nil {
+			logs.Error("Error creating user", zap.Error(err))
+			return nil, nil, err
+		}
+	}
+
+	var wasVerified bool
+	query = `SELECT email_verified FROM users WHERE email = $1 AND deleted_at = 0 FOR UPDATE`
+	err = tx.QueryRowContext(ctx, query, email).Scan(&wasVerified)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil, ErrMagicLinkInvalid
+	}
+	if err != nil {
+		logs.Error("Error getting user", zap.Error(err))
+		return nil, nil, err
+	}
+
+	user := pb.UserModel{}
+	query = `
+		UPDATE users SET
+			email_verified = true,
+			password = CASE WHEN email_verified THEN password ELSE '' END
+		WHERE
+			email = $1 AND deleted_at = 0
+		RETURNING id, name, lastname, email, role, email_verified, created_at, updated_at
+	`
+	err = tx.QueryRowContext(ctx, query, email).Scan(&user.Id, &user.Fname, &user.Lname, &user.Email, &user.Role, &user.EmailVerified, &user.CreatedAt, &user.UpdatedAt)
+	if err != nil {
+		logs.Error("Error verifying user email", zap.Error(err))
+		return nil, nil, err
+	}
+	var revoked []string
+	if !wasVerified {
+		query = `UPDATE sessions SET revoked_at = now() WHERE user_id = $1 AND revoked_at IS NULL RETURNING id`
+		revoked, err = revokeSessions(ctx, tx, user.Id, query, []interface{}{user.Id}, true, "email verified by login link")
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	_, err = tx.ExecContext(ctx, `UPDATE magic_links SET used_at = now() WHERE email = $1 AND used_at IS NULL`, email)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err = tx.Commit(); err != nil {
+		return nil, nil, err
+	}
+	resp, err := firstFactorLogin(ctx, s.db, s.cfg, &user)
+	return resp, revoked, err
+}
@@ -0,0 +1,366 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	pb "blog-auth/genproto/userservice"
+	"blog-auth/internal/config"
+	logger "blog-auth/internal/logger"
+	"blog-auth/internal/mfa"
+	"blog-auth/internal/token"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrMFAAlreadyEnabled   = errors.New("two-factor authentication is already enabled")
+	ErrMFANotEnrolled      = errors.New("two-factor enrollment has not been started")
+	ErrMFACodeInvalid      = errors.New("two-factor code is invalid")
+	ErrMFAChallengeInvalid = errors.New("mfa token is invalid or expired")
+)
+
+type MFAStorage interface {
+	EnrollTOTP(ctx context.Context, userID string) (*pb.EnrollTOTPRes, error)
+	ConfirmTOTP(ctx context.Context, userID, code string) (*pb.ConfirmTOTPRes, error)
+	MFAChallengeUser(ctx context.Context, mfaToken string) (string, error)
+	StartMFAAttempt(ctx context.Context, mfaToken string) (string, string, error)
+	CompleteMFAChallenge(ctx context.Context, challengeID string) (*pb.UserModel, error)
+	VerifyTOTP(ctx context.Context, userID, code string) error
+	UseRecoveryCode(ctx context.Context, userID, code string) error
+}
+
+type mfaStorage struct {
+	db  *sql.DB
+	cfg config.Config
+}
+
+func NewMFAStorage(db *sql.DB, cfg config.Config) MFAStorage {
+	return &mfaStorage{db: db, cfg: cfg}
+}
+
+// createMFAChallenge starts the second step of a login for a user with 2FA
+// enabled and returns the token the client must present to VerifyMFA.
+func createMFAChallenge(ctx context.Context, db execer, cfg config.Config, userID string) (string, error) {
+	plain, hash, err := token.NewOpaqueToken()
+	if err != nil {
+		return "", err
+	}
+	query := `INSERT INTO mfa_challenges (id, user_id, token_hash, expires_at) VALUES ($1, $2, $3, $4)`
+	_, err = db.ExecContext(ctx, query, uuid.NewString(), userID, hash, time.Now().Add(cfg.MFATOKENTTL))
+	if err != nil {
+		return "", err
+	}
+	return plain, nil
+}
+
+// firstFactorLogin finishes a password or passwordless login. With 2FA enabled
+// the first factor only gets the client an MFA challenge; tokens are issued by
+// VerifyMFA.
+func firstFactorLogin(ctx context.Context, db *sql.DB, cfg config.Config, user *pb.UserModel) (*pb.LoginRes, error) {
+	logs, err := logger.NewLogger()
+	if err != nil {
+		return nil, err
+	}
+	var totpEnabled, passkeyEnabled bool
+	query := `
+		SELECT
+			EXISTS(SELECT 1 FROM user_totp WHERE user_id = $1 AND enabled),
+			EXISTS(SELECT 1 FROM webauthn_credentials WHERE user_id = $1 AND second_factor)
+	`
+	err = db.QueryRowContext(ctx, query, user.Id).Scan(&totpEnabled, &passkeyEnabled)
+	if err != nil {
+		logs.Error("Error getting second factors", zap.Error(err))
+		return nil, err
+	}
+	var methods []string
+	if totpEnabled {
+		methods = append(methods, "totp", "recovery_code")
+	}
+	if passkeyEnabled {
+		methods = append(methods, "passkey")
+	}
+	if len(methods) == 0 {
+		return &pb.LoginRes{UserRes: user}, nil
+	}
+	mfaToken, err := createMFAChallenge(ctx, db, cfg, user.Id)
+	if err != nil {
+		logs.Error("Error creating mfa challenge", zap.Error(err))
+		return nil, err
+	}
+	return &pb.LoginRes{MfaRequired: true, MfaToken: mfaToken, MfaMethods: methods}, nil
+}
+
+// EnrollTOTP generates a new secret for the user. It only takes effect once
+// ConfirmTOTP is called with a valid code.
+func (s *mfaStorage) EnrollTOTP(ctx context.Context, userID string) (*pb.EnrollTOTPRes, error) {
+	logs, err := logger.NewLogger()
+	if err != nil {
+		return nil, err
+	}
+	var email string
+	var enabled bool
+	query := `
+		SELECT
+			u.email,
+			COALESCE(t.enabled, false)
+		FROM
+			users u
+		LEFT JOIN
+			user_totp t ON t.user_id = u.id
+		WHERE
+			u.id = $1 AND u.deleted_at = 0
+	`
+	err = s.db.QueryRowContext(ctx, query, userID).Scan(&email, &enabled)
+	if err != nil {
+		logs.Error("Error getting user", zap.Error(err))
+		return nil, err
+	}
+	if enabled {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	secret, err := mfa.NewTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+	encrypted, err := mfa.EncryptSecret(token.Subkey(s.cfg.TOKENKEY, token.KeyTOTPSecret), secret)
+	if err != nil {
+		return nil, err
+	}
+	query = `
+		INSERT INTO user_totp (user_id, secret) VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE SET
+			secret = EXCLUDED.secret,
+			last_used_step = 0,
+			created_at = now()
+		WHERE
+			user_totp.enabled = false
+	`
+	_, err = s.db.ExecContext(ctx, query, userID, encrypted)
+	if err != nil {
+		logs.Error("Error saving totp secret", zap.Error(err))
+		return nil, err
+	}
+	return &pb.EnrollTOTPRes{
+		Secret:     secret,
+		OtpauthUri: mfa.TOTPURI(s.cfg.TOTPISSUER, email, secret),
+	}, nil
+}
+
+// ConfirmTOTP enables 2FA once the user proves the authenticator works and
+// returns a fresh set of recovery codes. Only their hashes are stored.
+func (s *mfaStorage) ConfirmTOTP(ctx context.Context, userID, code string) (*pb.ConfirmTOTPRes, error) {
+	logs, err := logger.NewLogger()
+	if err != nil {
+		return nil, err
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var encrypted string
+	var enabled bool
+	err = tx.QueryRowContext(ctx, `SELECT secret, enabled FROM user_totp WHERE user_id = $1 FOR UPDATE`, userID).Scan(&encrypted, &enabled)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrMFANotEnrolled
+	}
+	if err != nil {
+		logs.Error("Error getting totp secret", zap.Error(err))
+		return nil, err
+	}
+	if enabled {
+		return nil, ErrMFAAlreadyEnabled
+	}
+	secret, err := s.decryptSecret(encrypted)
+	if err != nil {
+		return nil, err
+	}
+	step, ok := mfa.ValidateTOTP(secret, code, time.Now())
+	if !ok {
+		return nil, ErrMFACodeInvalid
+	}
+
+	query := `UPDATE user_totp SET enabled = true, confirmed_at = now(), last_used_step = $2 WHERE user_id = $1`
+	_, err = tx.ExecContext(ctx, query, userID, step)
+	if err != nil {
+		logs.Error("Error enabling totp", zap.Error(err))
+		return nil, err
+	}
+
+	codes, err := mfa.NewRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	_, err = tx.ExecContext(ctx, `DELETE FROM mfa_recovery_codes WHERE user_id = $1`, userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range codes {
+		query = `INSERT INTO mfa_recovery_codes (id, user_id, code_hash) VALUES ($1, $2, $3)`
+		_, err = tx.ExecContext(ctx, query, uuid.NewString(), userID, token.HashOpaqueToken(mfa.NormalizeRecoveryCode(c)))
+		if err != nil {
+			logs.Error("Error saving recovery code", zap.Error(err))
+			return nil, err
+		}
+	}
+	if err = writeAudit(ctx, tx, userID, eventMFAEnabled, "totp"); err != nil {
+		return nil, err
+	}
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+	return &pb.ConfirmTOTPRes{
+		RecoveryCodes: codes,
+		Message:       "Two-factor authentication enabled. Store the recovery codes somewhere safe.",
+	}, nil
+}
+
+// MFAChallengeUser returns the user an open challenge belongs to without
+// counting it as an attempt.
+func (s *mfaStorage) MFAChallengeUser(ctx context.Context, mfaToken string) (string, error) {
+	var userID string
+	query := `SELECT user_id FROM mfa_challenges WHERE token_hash = $1 AND used_at IS NULL AND expires_at > now() AND attempts < $2`
+	err := s.db.QueryRowContext(ctx, query, token.HashOpaqueToken(mfaToken), s.cfg.MFAATTEMPTS).Scan(&userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", ErrMFAChallengeInvalid
+	}
+	return userID, err
+}
+
+// StartMFAAttempt counts an attempt against the challenge before any factor is
+// checked, so concurrent guesses cannot exceed MFAATTEMPTS. It returns the
+// challenge and user id.
+func (s *mfaStorage) StartMFAAttempt(ctx context.Context, mfaToken string) (string, string, error) {
+	logs, err := logger.NewLogger()
+	if err != nil {
+		return "", "", err
+	}
+	var challengeID, userID string
+	query := `
+		UPDATE mfa_challenges SET
+			attempts = attempts + 1
+		WHERE
+			token_hash = $1 AND used_at IS NULL AND expires_at > now() AND attempts < $2
+		RETURNING id, user_id
+	`
+	err = s.db.QueryRowContext(ctx, query, token.HashOpaqueToken(mfaToken), s.cfg.MFAATTEMPTS).Scan(&challengeID, &userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", "", ErrMFAChallengeInvalid
+	}
+	if err != nil {
+		logs.Error("Error updating mfa challenge", zap.Error(err))
+		return "", "", err
+	}
+	return challengeID, userID, nil
+}
+
+// CompleteMFAChallenge consumes the challenge once a factor was verified and
+// returns the user to issue tokens for.
+func (s *mfaStorage) CompleteMFAChallenge(ctx context.Context, challengeID string) (*pb.UserModel, error) {
+	logs, err := logger.NewLogger()
+	if err != nil {
+		return nil, err
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var userID string
+	query := `UPDATE mfa_challenges SET used_at = now() WHERE id = $1 AND used_at IS NULL RETURNING user_id`
+	err = tx.QueryRowContext(ctx, query, challengeID).Scan(&userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrMFAChallengeInvalid
+	}
+	if err != nil {
+		logs.Error("Error consuming mfa challenge", zap.Error(err))
+		return nil, err
+	}
+	user := pb.UserModel{}
+	query = `select id, name, lastname, email, role, email_verified, created_at, updated_at from users where id = $1 and deleted_at = 0`
+	err = tx.QueryRowContext(ctx, query, userID).Scan(&user.Id, &user.Fname, &user.Lname, &user.Email, &user.Role, &user.EmailVerified, &user.CreatedAt, &user.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrMFAChallengeInvalid
+	}
+	if err != nil {
+		logs.Error("Error getting user", zap.Error(err))
+		return nil, err
+	}
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// VerifyTOTP checks a code from the user's authenticator app. A code from a
+// time step at or before the last accepted one is rejected as a replay.
+func (s *mfaStorage) VerifyTOTP(ctx context.Context, userID, code string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var encrypted string
+	var lastStep int64
+	query := `SELECT secret, last_used_step FROM user_totp WHERE user_id = $1 AND enabled FOR UPDATE`
+	err = tx.QueryRowContext(ctx, query, userID).Scan(&encrypted, &lastStep)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrMFACodeInvalid
+	}
+	if err != nil {
+		return err
+	}
+	secret, err := s.decryptSecret(encrypted)
+	if err != nil {
+		return err
+	}
+	step, ok := mfa.ValidateTOTP(secret, code, time.Now())
+	if !ok || step <= lastStep {
+		return ErrMFACodeInvalid
+	}
+	_, err = tx.ExecContext(ctx, `UPDATE user_totp SET last_used_step = $2 WHERE user_id = $1`, userID, step)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// UseRecoveryCode burns one of the user's recovery codes.
+func (s *mfaStorage) UseRecoveryCode(ctx context.Context, userID, code string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `UPDATE mfa_recovery_codes SET used_at = now() WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL`
+	res, err := tx.ExecContext(ctx, query, userID, token.HashOpaqueToken(mfa.NormalizeRecoveryCode(code)))
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n != 1 {
+		return ErrMFACodeInvalid
+	}
+	if err = writeAudit(ctx, tx, userID, eventMFARecoveryCodeUsed, ""); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// decryptSecret opens a TOTP secret. Secrets enrolled before TOKENKEY was
+// split into subkeys were sealed with TOKENKEY itself and still open with it.
+func (s *mfaStorage) decryptSecret(encrypted string) (string, error) {
+	secret, err := mfa.DecryptSecret(token.Subkey(s.cfg.TOKENKEY, token.KeyTOTPSecret), encrypted)
+	if err != nil {
+		return mfa.DecryptSecret(s.cfg.TOKENKEY, encrypted)
+	}
+	return secret, nil
+}
@@ -0,0 +1,255 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	pb "blog-auth/genproto/userservice"
+	logger "blog-auth/internal/logger"
+
+	"github.com/lib/pq"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrOAuthClientNotFound      = errors.New("oauth client not found")
+	ErrAuthorizationCodeInvalid = errors.New("authorization code is invalid or expired")
+)
+
+// AuthorizationCode is a one-time code handed to a client at its redirect URI
+// and exchanged for tokens, bound to the PKCE challenge of the request.
+type AuthorizationCode struct {
+	Hash          string
+	ClientID      string
+	UserID        string
+	RedirectURI   string
+	Scope         string
+	CodeChallenge string
+	Nonce         string
+	ExpiresAt     time.Time
+	// AuthTime is when the user signed in to get the code.
+	AuthTime time.Time
+}
+
+type OAuthStorage interface {
+	CreateClient(ctx context.Context, client *pb.OAuthClient, secretHash string) error
+	GetClient(ctx context.Context, clientID string) (*pb.OAuthClient, string, error)
+	ListClients(ctx context.Context) ([]*pb.OAuthClient, error)
+	DeleteClient(ctx context.Context, clientID string) error
+	CreateAuthorizationCode(ctx context.Context, code AuthorizationCode) error
+	ConsumeAuthorizationCode(ctx context.Context, codeHash string) (*AuthorizationCode, error)
+	SaveConsent(ctx context.Context, userID, clientID string, scopes []string) error
+	CreateDeviceCode(ctx context.Context, code DeviceCode) error
+	PendingDeviceCode(ctx context.Context, userCode string) (*DeviceCode, error)
+	ResolveDeviceCode(ctx context.Context, userCode, userID string, approved bool) error
+	PollDeviceCode(ctx context.Context, codeHash, clientID string) (*DeviceCode, error)
+}
+
+type oauthStorage struct {
+	db *sql.DB
+}
+
+func NewOAuthStorage(db *sql.DB) OAuthStorage {
+	return &oauthStorage{db: db}
+}
+
+// CreateClient registers client. secretHash is empty for public clients.
+func (s *oauthStorage) CreateClient(ctx context.Context, client *pb.OAuthClient, secretHash string) error {
+	logs, err := logger.NewLogger()
+	if err != nil {
+		return err
+	}
+	var secret interface{}
+	if secretHash != "" {
+		secret = secretHash
+	}
+	query := `
+		INSERT INTO oauth_clients (id, name, secret_hash, redirect_uris, scopes)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING created_at
+	`
+	err = s.db.QueryRowContext(ctx, query, client.ClientId, client.Name, secret, pq.Array(client.RedirectUris), pq.Array(client.Scopes)).Scan(&client.CreatedAt)
+	if err != nil {
+		logs.Error("Error creating oauth client", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// GetClient returns a client and its secret hash, empty for public clients.
+func (s *oauthStorage) GetClient(ctx context.Context, clientID string) (*pb.OAuthClient, string, error) {
+	logs, err := logger.NewLogger()
+	if err != nil {
+		return nil, "", err
+	}
+	query := `
+		SELECT
+			id,
+			name,
+			COALESCE(secret_hash, ''),
+			redirect_uris,
+			scopes,
+			created_at
+		FROM
+			oauth_clients
+		WHERE
+			id = $1 AND deleted_at = 0
+	`
+	client := pb.OAuthClient{}
+	var secretHash string
+	err = s.db.QueryRowContext(ctx, query, clientID).Scan(&client.ClientId, &client.Name, &secretHash, pq.Array(&client.RedirectUris), pq.Array(&client.Scopes), &client.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, "", ErrOAuthClientNotFound
+	}
+	if err != nil {
+		logs.Error("Error getting oauth client", zap.Error(err))
+		return nil, "", err
+	}
+	client.Confidential = secretHash != ""
+	return &client, secretHash, nil
+}
+
+func (s *oauthStorage) ListClients(ctx context.Context) ([]*pb.OAuthClient, error) {
+	logs, err := logger.NewLogger()
+	if err != nil {
+		return nil, err
+	}
+	query := `
+		SELECT
+			id,
+			name,
+			secret_hash IS NOT NULL,
+			redirect_uris,
+			scopes,
+			created_at
+		FROM
+			oauth_clients
+		WHERE
+			deleted_at = 0
+		ORDER BY
+			created_at
+	`
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		logs.Error("Error listing oauth clients", zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	var clients []*pb.OAuthClient
+	for rows.Next() {
+		client := pb.OAuthClient{}
+		err = rows.Scan(&client.ClientId, &client.Name, &client.Confidential, pq.Array(&client.RedirectUris), pq.Array(&client.Scopes), &client.CreatedAt)
+		if err != nil {
+			logs.Error("Error scanning oauth client", zap.Error(err))
+			return nil, err
+		}
+		clients = append(clients, &client)
+	}
+	return clients, rows.Err()
+}
+
+// DeleteClient removes a client and ends the sessions granted to it.
+func (s *oauthStorage) DeleteClient(ctx context.Context, clientID string) error {
+	logs, err := logger.NewLogger()
+	if err != nil {
+		return err
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `UPDATE oauth_clients SET deleted_at = date_part('epoch', now())::bigint WHERE id = $1 AND deleted_at = 0`, clientID)
+	if err != nil {
+		logs.Error("Error deleting oauth client", zap.Error(err))
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrOAuthClientNotFound
+	}
+	query := `
+		UPDATE refresh_tokens SET revoked_at = now()
+		WHERE revoked_at IS NULL AND family_id IN (SELECT id FROM sessions WHERE client_id = $1)
+	`
+	if _, err = tx.ExecContext(ctx, query, clientID); err != nil {
+		logs.Error("Error revoking refresh tokens", zap.Error(err))
+		return err
+	}
+	if _, err = tx.ExecContext(ctx, `UPDATE sessions SET revoked_at = now() WHERE client_id = $1 AND revoked_at IS NULL`, clientID); err != nil {
+		logs.Error("Error revoking sessions", zap.Error(err))
+		return err
+	}
+	if _, err = tx.ExecContext(ctx, `DELETE FROM oauth_consents WHERE client_id = $1`, clientID); err != nil {
+		logs.Error("Error deleting oauth consents", zap.Error(err))
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *oauthStorage) CreateAuthorizationCode(ctx context.Context, code AuthorizationCode) error {
+	logs, err := logger.NewLogger()
+	if err != nil {
+		return err
+	}
+	query := `
+		INSERT INTO oauth_authorization_codes (code_hash, client_id, user_id, redirect_uri, scope, code_challenge, nonce, expires_at, auth_time)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	`
+	_, err = s.db.ExecContext(ctx, query, code.Hash, code.ClientID, code.UserID, code.RedirectURI, code.Scope, code.CodeChallenge, code.Nonce, code.ExpiresAt, code.AuthTime)
+	if err != nil {
+		logs.Error("Error creating authorization code", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// ConsumeAuthorizationCode marks a code as used and returns it. A code can
+// only be consumed once, before it expires.
+func (s *oauthStorage) ConsumeAuthorizationCode(ctx context.Context, codeHash string) (*AuthorizationCode, error) {
+	logs, err := logger.NewLogger()
+	if err != nil {
+		return nil, err
+	}
+	query := `
+		UPDATE oauth_authorization_codes
+		SET used_at = now()
+		WHERE code_hash = $1 AND used_at IS NULL AND expires_at > now()
+		RETURNING client_id, user_id, redirect_uri, scope, code_challenge, nonce, expires_at, COALESCE(auth_time, created_at)
+	`
+	code := AuthorizationCode{Hash: codeHash}
+	err = s.db.QueryRowContext(ctx, query, codeHash).Scan(&code.ClientID, &code.UserID, &code.RedirectURI, &code.Scope, &code.CodeChallenge, &code.Nonce, &code.ExpiresAt, &code.AuthTime)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrAuthorizationCodeInvalid
+	}
+	if err != nil {
+		logs.Error("Error consuming authorization code", zap.Error(err))
+		return nil, err
+	}
+	return &code, nil
+}
+
+// SaveConsent adds scopes to what the user has granted the client.
+func (s *oauthStorage) SaveConsent(ctx context.Context, userID, clientID string, scopes []string) error {
+	logs, err := logger.NewLogger()
+	if err != nil {
+		return err
+	}
+	query := `
+		INSERT INTO oauth_consents (user_id, client_id, scopes)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (user_id, client_id) DO UPDATE
+		SET
+			scopes = ARRAY(SELECT DISTINCT unnest(oauth_consents.scopes || EXCLUDED.scopes) ORDER BY 1),
+			updated_at = now()
+	`
+	_, err = s.db.ExecContext(ctx, query, userID, clientID, pq.Array(scopes))
+	if err != nil {
+		logs.Error("Error saving oauth consent", zap.Error(err))
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,188 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"strings"
+
+	pb "blog-auth/genproto/userservice"
+	logger "blog-auth/internal/logger"
+
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrPasskeyUserNotFound = errors.New("user not found")
+	ErrWebAuthnSession     = errors.New("passkey session is invalid or expired")
+)
+
+// PasskeyUser adapts a user and their registered credentials to
+// webauthn.User.
+type PasskeyUser struct {
+	User        *pb.UserModel
+	Credentials []webauthn.Credential
+	// SecondFactor holds the ids of credentials the user allowed as a second
+	// factor after a password login.
+	SecondFactor map[string]bool
+}
+
+func (u *PasskeyUser) WebAuthnID() []byte {
+	return []byte(u.User.Id)
+}
+
+func (u *PasskeyUser) WebAuthnName() string {
+	return u.User.Email
+}
+
+func (u *PasskeyUser) WebAuthnDisplayName() string {
+	return strings.TrimSpace(u.User.Fname + " " + u.User.Lname)
+}
+
+func (u *PasskeyUser) WebAuthnCredentials() []webauthn.Credential {
+	return u.Credentials
+}
+
+type PasskeyStorage interface {
+	GetPasskeyUser(ctx context.Context, userID string) (*PasskeyUser, error)
+	CreatePasskey(ctx context.Context, userID, name string, cred *webauthn.Credential, secondFactor bool) (string, error)
+	UpdatePasskey(ctx context.Context, cred *webauthn.Credential) error
+	SaveWebAuthnSession(ctx context.Context, userID, kind string, session *webauthn.SessionData) (string, error)
+	TakeWebAuthnSession(ctx context.Context, id, kind string) (*webauthn.SessionData, string, error)
+}
+
+type passkeyStorage struct {
+	db *sql.DB
+}
+
+func NewPasskeyStorage(db *sql.DB) PasskeyStorage {
+	return &passkeyStorage{db: db}
+}
+
+func (s *passkeyStorage) GetPasskeyUser(ctx context.Context, userID string) (*PasskeyUser, error) {
+	logs, err := logger.NewLogger()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := uuid.Parse(userID); err != nil {
+		return nil, ErrPasskeyUserNotFound
+	}
+	user := pb.UserModel{}
+	query := `select id, name, lastname, email, role, email_verified, created_at, updated_at from users where id = $1 and deleted_at = 0`
+	err = s.db.QueryRowContext(ctx, query, userID).Scan(&user.Id, &user.Fname, &user.Lname, &user.Email, &user.Role, &user.EmailVerified, &user.CreatedAt, &user.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrPasskeyUserNotFound
+	}
+	if err != nil {
+		logs.Error("Error getting user", zap.Error(err))
+		return nil, err
+	}
+
+	rows, err := s.db.QueryContext(ctx, `SELECT id, credential, second_factor FROM webauthn_credentials WHERE user_id = $1`, userID)
+	if err != nil {
+		logs.Error("Error getting passkeys", zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	res := PasskeyUser{User: &user, SecondFactor: map[string]bool{}}
+	for rows.Next() {
+		var id string
+		var data []byte
+		var secondFactor bool
+		if err = rows.Scan(&id, &data, &secondFactor); err != nil {
+			return nil, err
+		}
+		cred := webauthn.Credential{}
+		if err = json.Unmarshal(data, &cred); err != nil {
+			return nil, err
+		}
+		res.Credentials = append(res.Credentials, cred)
+		if secondFactor {
+			res.SecondFactor[string(cred.ID)] = true
+		}
+	}
+	return &res, rows.Err()
+}
+
+func (s *passkeyStorage) CreatePasskey(ctx context.Context, userID, name string, cred *webauthn.Credential, secondFactor bool) (string, error) {
+	logs, err := logger.NewLogger()
+	if err != nil {
+		return "", err
+	}
+	data, err := json.Marshal(cred)
+	if err != nil {
+		return "", err
+	}
+	id := uuid.NewString()
+	query := `
+		INSERT INTO webauthn_credentials (
+			id, user_id, credential_id, credential, sign_count, name, second_factor
+		) VALUES (
+			$1, $2, $3, $4, $5, $6, $7
+		)`
+	_, err = s.db.ExecContext(ctx, query, id, userID, cred.ID, data, cred.Authenticator.SignCount, name, secondFactor)
+	if err != nil {
+		logs.Error("Error creating passkey", zap.Error(err))
+		return "", err
+	}
+	return id, nil
+}
+
+// UpdatePasskey stores the sign count and flags reported by the authenticator
+// on the last successful assertion.
+func (s *passkeyStorage) UpdatePasskey(ctx context.Context, cred *webauthn.Credential) error {
+	data, err := json.Marshal(cred)
+	if err != nil {
+		return err
+	}
+	query := `UPDATE webauthn_credentials SET credential = $2, sign_count = $3, last_used_at = now() WHERE credential_id = $1`
+	_, err = s.db.ExecContext(ctx, query, cred.ID, data, cred.Authenticator.SignCount)
+	return err
+}
+
+// SaveWebAuthnSession keeps the challenge of a registration or login ceremony
+// until the client finishes it. userID is empty for discoverable logins.
+func (s *passkeyStorage) SaveWebAuthnSession(ctx context.Context, userID, kind string, session *webauthn.SessionData) (string, error) {
+	data, err := json.Marshal(session)
+	if err != nil {
+		return "", err
+	}
+	var uid interface{}
+	if userID != "" {
+		uid = userID
+	}
+	id := uuid.NewString()
+	query := `INSERT INTO webauthn_sessions (id, user_id, kind, data, expires_at) VALUES ($1, $2, $3, $4, $5)`
+	_, err = s.db.ExecContext(ctx, query, id, uid, kind, data, session.Expires)
+	if err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+// TakeWebAuthnSession deletes and returns a session so every challenge can be
+// answered only once.
+func (s *passkeyStorage) TakeWebAuthnSession(ctx context.Context, id, kind string) (*webauthn.SessionData, string, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, "", ErrWebAuthnSession
+	}
+	var data []byte
+	var userID sql.NullString
+	query := `DELETE FROM webauthn_sessions WHERE id = $1 AND kind = $2 AND expires_at > now() RETURNING user_id, data`
+	err := s.db.QueryRowContext(ctx, query, id, kind).Scan(&userID, &data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, "", ErrWebAuthnSession
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	session := webauthn.SessionData{}
+	if err = json.Unmarshal(data, &session); err != nil {
+		return nil, "", err
+	}
+	return &session, userID.String, nil
+}
@@ -0,0 +1,141 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"blog-auth/internal/config"
+	logger "blog-auth/internal/logger"
+	"blog-auth/internal/password"
+	"blog-auth/internal/token"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// forgotPasswordMessage is returned whether or not the email belongs to an
+// account, so the endpoint cannot be used to enumerate users.
+const forgotPasswordMessage = "If an account with this email exists, a password reset code has been sent"
+
+// passwordResetTimeout bounds the background work of a reset request.
+const passwordResetTimeout = 30 * time.Second
+
+var ErrPasswordResetInvalid = errors.New("password reset token is invalid or expired")
+
+// requestPasswordReset starts the reset of the account with the given email
+// in the background and returns at once, so the response time does not reveal
+// whether the account exists. Unknown emails are silently ignored.
+func requestPasswordReset(ctx context.Context, db *sql.DB, cfg config.Config, email string) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), passwordResetTimeout)
+	go func() {
+		defer cancel()
+		if err := createPasswordReset(ctx, db, cfg, email); err != nil {
+			if logs, lerr := logger.NewLogger(); lerr == nil {
+				logs.Error("Error requesting password reset", zap.Error(err))
+			}
+		}
+	}()
+}
+
+// createPasswordReset stores a single-use reset token for the account with
+// the given email and queues it for mailing.
+func createPasswordReset(ctx context.Context, db *sql.DB, cfg config.Config, email string) error {
+	logs, err := logger.NewLogger()
+	if err != nil {
+		return err
+	}
+
+	var userID, name string
+	query := `SELECT id, name FROM users WHERE email = $1 AND deleted_at = 0`
+	err = db.QueryRowContext(ctx, query, email).Scan(&userID, &name)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		logs.Error("Error getting user", zap.Error(err))
+		return err
+	}
+
+	plain, hash, err := token.NewOpaqueToken()
+	if err != nil {
+		return err
+	}
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query = `INSERT INTO password_resets (id, user_id, token_hash, expires_at) VALUES ($1, $2, $3, $4)`
+	_, err = tx.ExecContext(ctx, query, uuid.NewString(), userID, hash, time.Now().Add(cfg.RESETTOKENTTL))
+	if err != nil {
+		logs.Error("Error creating password reset", zap.Error(err))
+		return err
+	}
+	err = enqueueCode(ctx, tx, email, name, "Your password reset code", plain)
+	if err != nil {
+		logs.Error("Error queueing password reset email", zap.Error(err))
+		return err
+	}
+	return tx.Commit()
+}
+
+// resetPassword consumes a reset token, sets the new password and revokes
+// every session and refresh token of the user. It returns the revoked session
+// ids, whose access tokens the caller must deny until they expire.
+func resetPassword(ctx context.Context, db *sql.DB, hasher *password.Hasher, tokenHash, newPassword string) ([]string, error) {
+	logs, err := logger.NewLogger()
+	if err != nil {
+		return nil, err
+	}
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var userID string
+	query := `
+		SELECT
+			user_id
+		FROM
+			password_resets
+		WHERE
+			token_hash = $1 AND used_at IS NULL AND expires_at > now()
+		FOR UPDATE
+	`
+	err = tx.QueryRowContext(ctx, query, tokenHash).Scan(&userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrPasswordResetInvalid
+	}
+	if err != nil {
+		logs.Error("Error getting password reset", zap.Error(err))
+		return nil, err
+	}
+
+	hashpass, err := hasher.Hash(newPassword)
+	if err != nil {
+		return nil, err
+	}
+	_, err = tx.ExecContext(ctx, `UPDATE users SET password = $1, updated_at = now() WHERE id = $2`, hashpass, userID)
+	if err != nil {
+		logs.Error("Error updating password", zap.Error(err))
+		return nil, err
+	}
+	_, err = tx.ExecContext(ctx, `UPDATE password_resets SET used_at = now() WHERE user_id = $1 AND used_at IS NULL`, userID)
+	if err != nil {
+		logs.Error("Error consuming password reset", zap.Error(err))
+		return nil, err
+	}
+	query = `UPDATE sessions SET revoked_at = now() WHERE user_id = $1 AND revoked_at IS NULL RETURNING id`
+	ids, err := revokeSessions(ctx, tx, userID, query, []interface{}{userID}, true, "password reset")
+	if err != nil {
+		return nil, err
+	}
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
@@ -0,0 +1,371 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	pb "blog-auth/genproto/userservice"
+	logger "blog-auth/internal/logger"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrRoleExists         = errors.New("role already exists")
+	ErrRoleNotFound       = errors.New("role not found")
+	ErrPermissionExists   = errors.New("permission already exists")
+	ErrPermissionNotFound = errors.New("permission not found")
+)
+
+// UserAccess is what a user is allowed to do: the built-in role from the users
+// table plus the roles assigned through user_roles and their permissions.
+type UserAccess struct {
+	Role        string
+	Roles       []string
+	Permissions []string
+}
+
+// Has reports whether the user holds permission. admin and super-admin hold
+// every permission.
+func (a *UserAccess) Has(permission string) bool {
+	if a.Role == "admin" || a.Role == "super-admin" {
+		return true
+	}
+	for _, p := range a.Permissions {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+type PermissionStorage interface {
+	CreateRole(ctx context.Context, req *pb.CreateRoleReq) (*pb.CreateRoleRes, error)
+	DeleteRole(ctx context.Context, req *pb.DeleteRoleReq) (*pb.DeleteRoleRes, error)
+	ListRoles(ctx context.Context, req *pb.ListRolesReq) (*pb.ListRolesRes, error)
+	CreatePermission(ctx context.Context, req *pb.CreatePermissionReq) (*pb.CreatePermissionRes, error)
+	ListPermissions(ctx context.Context, req *pb.ListPermissionsReq) (*pb.ListPermissionsRes, error)
+	GrantPermission(ctx context.Context, req *pb.GrantPermissionReq) (*pb.GrantPermissionRes, error)
+	RevokePermission(ctx context.Context, req *pb.RevokePermissionReq) (*pb.RevokePermissionRes, error)
+	AssignRole(ctx context.Context, req *pb.AssignRoleReq) (*pb.AssignRoleRes, error)
+	UnassignRole(ctx context.Context, req *pb.UnassignRoleReq) (*pb.UnassignRoleRes, error)
+	UserAccess(ctx context.Context, userID string) (*UserAccess, error)
+}
+
+type permissionStorage struct {
+	db *sql.DB
+}
+
+func NewPermissionStorage(db *sql.DB) PermissionStorage {
+	return &permissionStorage{db: db}
+}
+
+func (s *permissionStorage) CreateRole(ctx context.Context, req *pb.CreateRoleReq) (*pb.CreateRoleRes, error) {
+	logs, err := logger.NewLogger()
+	if err != nil {
+		return nil, err
+	}
+	role := pb.Role{}
+	query := `
+		INSERT INTO roles (id, name, description) VALUES ($1, $2, $3)
+		ON CONFLICT (name) DO NOTHING
+		RETURNING id, name, description, created_at
+	`
+	err = s.db.QueryRowContext(ctx, query, uuid.NewString(), req.Name, req.Description).Scan(&role.Id, &role.Name, &role.Description, &role.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrRoleExists
+	}
+	if err != nil {
+		logs.Error("Error creating role", zap.Error(err))
+		return nil, err
+	}
+	return &pb.CreateRoleRes{Role: &role}, nil
+}
+
+func (s *permissionStorage) DeleteRole(ctx context.Context, req *pb.DeleteRoleReq) (*pb.DeleteRoleRes, error) {
+	logs, err := logger.NewLogger()
+	if err != nil {
+		return nil, err
+	}
+	res, err := s.db.ExecContext(ctx, `DELETE FROM roles WHERE name = $1`, req.Name)
+	if err != nil {
+		logs.Error("Error deleting role", zap.Error(err))
+		return nil, err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return nil, ErrRoleNotFound
+	}
+	return &pb.DeleteRoleRes{Message: "Role deleted"}, nil
+}
+
+func (s *permissionStorage) ListRoles(ctx context.Context, req *pb.ListRolesReq) (*pb.ListRolesRes, error) {
+	logs, err := logger.NewLogger()
+	if err != nil {
+		return nil, err
+	}
+	query := `
+		SELECT
+			r.id,
+			r.name,
+			r.description,
+			r.created_at,
+			COALESCE(array_agg(p.name ORDER BY p.name) FILTER (WHERE p.name IS NOT NULL), '{}')
+		FROM
+			roles r
+		LEFT JOIN
+			role_permissions rp ON rp.role_id = r.id
+		LEFT JOIN
+			permissions p ON p.id = rp.permission_id
+		GROUP BY
+			r.id
+		ORDER BY
+			r.name
+	`
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		logs.Error("Error listing roles", zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	res := pb.ListRolesRes{}
+	for rows.Next() {
+		role := pb.Role{}
+		err = rows.Scan(&role.Id, &role.Name, &role.Description, &role.CreatedAt, pq.Array(&role.Permissions))
+		if err != nil {
+			logs.Error("Error scanning role", zap.Error(err))
+			return nil, err
+		}
+		res.Roles = append(res.Roles, &role)
+	}
+	return &res, rows.Err()
+}
+
+func (s *permissionStorage) CreatePermission(ctx context.Context, req *pb.CreatePermissionReq) (*pb.CreatePermissionRes, error) {
+	logs, err := logger.NewLogger()
+	if err != nil {
+		return nil, err
+	}
+	permission := pb.Permission{}
+	query := `
+		INSERT INTO permissions (id, name, description) VALUES ($1, $2, $3)
+		ON CONFLICT (name) DO NOTHING
+		RETURNING id, name, description, created_at
+	`
+	err = s.db.QueryRowContext(ctx, query, uuid.NewString(), req.Name, req.Description).Scan(&permission.Id, &permission.Name, &permission.Description, &permission.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrPermissionExists
+	}
+	if err != nil {
+		logs.Error("Error creating permission", zap.Error(err))
+		return nil, err
+	}
+	return &pb.CreatePermissionRes{Permission: &permission}, nil
+}
+
+func (s *permissionStorage) ListPermissions(ctx context.Context, req *pb.ListPermissionsReq) (*pb.ListPermissionsRes, error) {
+	logs, err := logger.NewLogger()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := s.db.QueryContext(ctx, `SELECT id, name, description, created_at FROM permissions ORDER BY name`)
+	if err != nil {
+		logs.Error("Error listing permissions", zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	res := pb.ListPermissionsRes{}
+	for rows.Next() {
+		permission := pb.Permission{}
+		if err = rows.Scan(&permission.Id, &permission.Name, &permission.Description, &permission.CreatedAt); err != nil {
+			logs.Error("Error scanning permission", zap.Error(err))
+			return nil, err
+		}
+		res.Permissions = append(res.Permissions, &permission)
+	}
+	return &res, rows.Err()
+}
+
+// roleAndPermission resolves both names, so callers can tell which one is
+// missing.
+func (s *permissionStorage) roleAndPermission(ctx context.Context, role, permission string) (string, string, error) {
+	var roleID, permissionID sql.NullString
+	query := `SELECT (SELECT id FROM roles WHERE name = $1), (SELECT id FROM permissions WHERE name = $2)`
+	if err := s.db.QueryRowContext(ctx, query, role, permission).Scan(&roleID, &permissionID); err != nil {
+		return "", "", err
+	}
+	if !roleID.Valid {
+		return "", "", ErrRoleNotFound
+	}
+	if !permissionID.Valid {
+		return "", "", ErrPermissionNotFound
+	}
+	return roleID.String, permissionID.String, nil
+}
+
+func (s *permissionStorage) GrantPermission(ctx context.Context, req *pb.GrantPermissionReq) (*pb.GrantPermissionRes, error) {
+	logs, err := logger.NewLogger()
+	if err != nil {
+		return nil, err
+	}
+	roleID, permissionID, err := s.roleAndPermission(ctx, req.Role, req.Permission)
+	if err != nil {
+		return nil, err
+	}
+	query := `INSERT INTO role_permissions (role_id, permission_id) VALUES ($1, $2) ON CONFLICT DO NOTHING`
+	if _, err = s.db.ExecContext(ctx, query, roleID, permissionID); err != nil {
+		logs.Error("Error granting permission", zap.Error(err))
+		return nil, err
+	}
+	return &pb.GrantPermissionRes{Message: "Permission granted"}, nil
+}
+
+func (s *permissionStorage) RevokePermission(ctx context.Context, req *pb.RevokePermissionReq) (*pb.RevokePermissionRes, error) {
+	logs, err := logger.NewLogger()
+	if err != nil {
+		return nil, err
+	}
+	roleID, permissionID, err := s.roleAndPermission(ctx, req.Role, req.Permission)
+	if err != nil {
+		return nil, err
+	}
+	query := `DELETE FROM role_permissions WHERE role_id = $1 AND permission_id = $2`
+	if _, err = s.db.ExecContext(ctx, query, roleID, permissionID); err != nil {
+		logs.Error("Error revoking permission", zap.Error(err))
+		return nil, err
+	}
+	return &pb.RevokePermissionRes{Message: "Permission revoked"}, nil
+}
+
+// roleForUser resolves a role name and checks that the user exists.
+func roleForUser(ctx context.Context, tx *sql.Tx, userID, role string) (string, error) {
+	if _, err := uuid.Parse(userID); err != nil {
+		return "", ErrUserNotFound
+	}
+	var roleID sql.NullString
+	var userExists bool
+	query := `
+		SELECT
+			(SELECT id FROM roles WHERE name = $2),
+			EXISTS(SELECT 1 FROM users WHERE id = $1 AND deleted_at = 0)
+	`
+	if err := tx.QueryRowContext(ctx, query, userID, role).Scan(&roleID, &userExists); err != nil {
+		return "", err
+	}
+	if !userExists {
+		return "", ErrUserNotFound
+	}
+	if !roleID.Valid {
+		return "", ErrRoleNotFound
+	}
+	return roleID.String, nil
+}
+
+func (s *permissionStorage) AssignRole(ctx context.Context, req *pb.AssignRoleReq) (*pb.AssignRoleRes, error) {
+	logs, err := logger.NewLogger()
+	if err != nil {
+		return nil, err
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	roleID, err := roleForUser(ctx, tx, req.UserId, req.Role)
+	if err != nil {
+		return nil, err
+	}
+	res, err := tx.ExecContext(ctx, `INSERT INTO user_roles (user_id, role_id) VALUES ($1, $2) ON CONFLICT DO NOTHING`, req.UserId, roleID)
+	if err != nil {
+		logs.Error("Error assigning role", zap.Error(err))
+		return nil, err
+	}
+	if n, _ := res.RowsAffected(); n > 0 {
+		if err = writeAudit(ctx, tx, req.UserId, eventRoleAssigned, req.Role); err != nil {
+			return nil, err
+		}
+	}
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+	return &pb.AssignRoleRes{Message: "Role assigned"}, nil
+}
+
+func (s *permissionStorage) UnassignRole(ctx context.Context, req *pb.UnassignRoleReq) (*pb.UnassignRoleRes, error) {
+	logs, err := logger.NewLogger()
+	if err != nil {
+		return nil, err
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	roleID, err := roleForUser(ctx, tx, req.UserId, req.Role)
+	if err != nil {
+		return nil, err
+	}
+	res, err := tx.ExecContext(ctx, `DELETE FROM user_roles WHERE user_id = $1 AND role_id = $2`, req.UserId, roleID)
+	if err != nil {
+		logs.Error("Error unassigning role", zap.Error(err))
+		return nil, err
+	}
+	if n, _ := res.RowsAffected(); n > 0 {
+		if err = writeAudit(ctx, tx, req.UserId, eventRoleUnassigned, req.Role); err != nil {
+			return nil, err
+		}
+	}
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+	return &pb.UnassignRoleRes{Message: "Role unassigned"}, nil
+}
+
+// UserAccess loads the roles and effective permissions of a user.
+func (s *permissionStorage) UserAccess(ctx context.Context, userID string) (*UserAccess, error) {
+	logs, err := logger.NewLogger()
+	if err != nil {
+		return nil, err
+	}
+	if _, err = uuid.Parse(userID); err != nil {
+		return nil, ErrUserNotFound
+	}
+	access := UserAccess{}
+	query := `
+		SELECT
+			u.role,
+			ARRAY(
+				SELECT r.name
+				FROM user_roles ur JOIN roles r ON r.id = ur.role_id
+				WHERE ur.user_id = u.id
+				ORDER BY r.name
+			),
+			ARRAY(
+				SELECT DISTINCT p.name
+				FROM user_roles ur
+				JOIN role_permissions rp ON rp.role_id = ur.role_id
+				JOIN permissions p ON p.id = rp.permission_id
+				WHERE ur.user_id = u.id
+				ORDER BY p.name
+			)
+		FROM
+			users u
+		WHERE
+			u.id = $1 AND u.deleted_at = 0
+	`
+	err = s.db.QueryRowContext(ctx, query, userID).Scan(&access.Role, pq.Array(&access.Roles), pq.Array(&access.Permissions))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		logs.Error("Error getting user access", zap.Error(err))
+		return nil, err
+	}
+	return &access, nil
+}
@@ -0,0 +1,182 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	pb "blog-auth/genproto/userservice"
+	logger "blog-auth/internal/logger"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"go.uber.org/zap"
+)
+
+// lastUsedInterval is how stale last_used_at may get before a use of the
+// token writes it again.
+const lastUsedInterval = time.Minute
+
+var (
+	ErrPersonalTokenNotFound = errors.New("personal access token not found")
+	ErrPersonalTokenInvalid  = errors.New("personal access token is invalid, expired or revoked")
+)
+
+// PersonalTokenOwner is the user a personal access token acts for, with the
+// scopes it is limited to.
+type PersonalTokenOwner struct {
+	TokenID       string
+	UserID        string
+	Role          string
+	EmailVerified bool
+	Scopes        []string
+	ExpiresAt     *time.Time
+	CreatedAt     time.Time
+}
+
+type PersonalTokenStorage interface {
+	CreatePersonalToken(ctx context.Context, userID string, pat *pb.PersonalAccessToken, tokenHash string, expiresAt *time.Time) error
+	ListPersonalTokens(ctx context.Context, userID string) ([]*pb.PersonalAccessToken, error)
+	RevokePersonalToken(ctx context.Context, userID, tokenID string) error
+	UsePersonalToken(ctx context.Context, tokenHash string) (*PersonalTokenOwner, error)
+}
+
+type personalTokenStorage struct {
+	db *sql.DB
+}
+
+func NewPersonalTokenStorage(db *sql.DB) PersonalTokenStorage {
+	return &personalTokenStorage{db: db}
+}
+
+func (s *personalTokenStorage) CreatePersonalToken(ctx context.Context, userID string, pat *pb.PersonalAccessToken, tokenHash string, expiresAt *time.Time) error {
+	logs, err := logger.NewLogger()
+	if err != nil {
+		return err
+	}
+	pat.Id = uuid.NewString()
+	query := `
+		INSERT INTO personal_access_tokens (id, user_id, name, token_hash, scopes, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING created_at, expires_at
+	`
+	var expires sql.NullString
+	err = s.db.QueryRowContext(ctx, query, pat.Id, userID, pat.Name, tokenHash, pq.Array(pat.Scopes), expiresAt).Scan(&pat.CreatedAt, &expires)
+	if err != nil {
+		logs.Error("Error creating personal access token", zap.Error(err))
+		return err
+	}
+	pat.ExpiresAt = expires.String
+	return nil
+}
+
+// ListPersonalTokens returns the user's tokens that are neither revoked nor
+// expired, newest first.
+func (s *personalTokenStorage) ListPersonalTokens(ctx context.Context, userID string) ([]*pb.PersonalAccessToken, error) {
+	logs, err := logger.NewLogger()
+	if err != nil {
+		return nil, err
+	}
+	query := `
+		SELECT
+			id,
+			name,
+			scopes,
+			expires_at,
+			last_used_at,
+			created_at
+		FROM
+			personal_access_tokens
+		WHERE
+			user_id = $1
+			AND revoked_at IS NULL
+			AND (expires_at IS NULL OR expires_at > now())
+		ORDER BY
+			created_at DESC
+	`
+	rows, err := s.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		logs.Error("Error listing personal access tokens", zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	var pats []*pb.PersonalAccessToken
+	for rows.Next() {
+		pat := pb.PersonalAccessToken{}
+		var expires, lastUsed sql.NullString
+		err = rows.Scan(&pat.Id, &pat.Name, pq.Array(&pat.Scopes), &expires, &lastUsed, &pat.CreatedAt)
+		if err != nil {
+			logs.Error("Error scanning personal access token", zap.Error(err))
+			return nil, err
+		}
+		pat.ExpiresAt, pat.LastUsedAt = expires.String, lastUsed.String
+		pats = append(pats, &pat)
+	}
+	return pats, rows.Err()
+}
+
+func (s *personalTokenStorage) RevokePersonalToken(ctx context.Context, userID, tokenID string) error {
+	logs, err := logger.NewLogger()
+	if err != nil {
+		return err
+	}
+	if _, err = uuid.Parse(tokenID); err != nil {
+		return ErrPersonalTokenNotFound
+	}
+	query := `UPDATE personal_access_tokens SET revoked_at = now() WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL`
+	res, err := s.db.ExecContext(ctx, query, tokenID, userID)
+	if err != nil {
+		logs.Error("Error revoking personal access token", zap.Error(err))
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrPersonalTokenNotFound
+	}
+	return nil
+}
+
+// UsePersonalToken looks up a usable token of an active user and records
+// that it was used. last_used_at is only written once per lastUsedInterval, so
+// a busy script does not update the row on every request.
+func (s *personalTokenStorage) UsePersonalToken(ctx context.Context, tokenHash string) (*PersonalTokenOwner, error) {
+	logs, err := logger.NewLogger()
+	if err != nil {
+		return nil, err
+	}
+	query := `
+		WITH token AS (
+			SELECT
+				p.id,
+				u.id AS user_id,
+				u.role,
+				u.email_verified,
+				p.scopes,
+				p.expires_at,
+				p.created_at
+			FROM
+				personal_access_tokens p
+				JOIN users u ON u.id = p.user_id
+			WHERE
+				p.token_hash = $1
+				AND p.revoked_at IS NULL
+				AND (p.expires_at IS NULL OR p.expires_at > now())
+				AND u.deleted_at = 0
+		), touched AS (
+			UPDATE personal_access_tokens SET last_used_at = now()
+			WHERE id IN (SELECT id FROM token) AND (last_used_at IS NULL OR last_used_at < $2)
+		)
+		SELECT id, user_id, role, email_verified, scopes, expires_at, created_at FROM token
+	`
+	owner := PersonalTokenOwner{}
+	err = s.db.QueryRowContext(ctx, query, tokenHash, time.Now().Add(-lastUsedInterval)).Scan(&owner.TokenID, &owner.UserID, &owner.Role, &owner.EmailVerified, pq.Array(&owner.Scopes), &owner.ExpiresAt, &owner.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrPersonalTokenInvalid
+	}
+	if err != nil {
+		logs.Error("Error using personal access token", zap.Error(err))
+		return nil, err
+	}
+	return &owner, nil
+}
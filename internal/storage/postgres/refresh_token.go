@@ -0,0 +1,204 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	pb "blog-auth/genproto/userservice"
+	logger "blog-auth/internal/logger"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrRefreshTokenInvalid = errors.New("refresh token is invalid or expired")
+	ErrRefreshTokenReused  = errors.New("refresh token was already used")
+)
+
+// Grant is what a session's tokens were issued for. ClientID and Scope are
+// empty for first-party logins, which get the user's full permissions.
+type Grant struct {
+	SessionID string
+	ClientID  string
+	Scope     string
+}
+
+type RefreshTokenStorage interface {
+	CreateRefreshToken(ctx context.Context, userID, familyID, tokenHash string, expiresAt time.Time) error
+	RotateRefreshToken(ctx context.Context, tokenHash, newTokenHash, clientID, ip string, expiresAt time.Time) (*pb.UserModel, Grant, error)
+	RevokeRefreshToken(ctx context.Context, tokenHash string) (string, error)
+}
+
+type refreshTokenStorage struct {
+	db *sql.DB
+}
+
+func NewRefreshTokenStorage(db *sql.DB) RefreshTokenStorage {
+	return &refreshTokenStorage{db: db}
+}
+
+func (s *refreshTokenStorage) CreateRefreshToken(ctx context.Context, userID, familyID, tokenHash string, expiresAt time.Time) error {
+	logs, err := logger.NewLogger()
+	if err != nil {
+		return err
+	}
+	query := `INSERT INTO refresh_tokens (id, user_id, family_id, token_hash, expires_at) VALUES ($1, $2, $3, $4, $5)`
+	_, err = s.db.ExecContext(ctx, query, uuid.NewString(), userID, familyID, tokenHash, expiresAt)
+	if err != nil {
+		logs.Error("Error creating refresh token", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// RotateRefreshToken exchanges the token identified by tokenHash for a new one
+// in the same family and returns the user and what the session was granted.
+// The token must have been issued to clientID, which is empty for first-party
+// logins. Presenting a token that has already been rotated revokes the whole
+// family, since it means the token leaked.
+func (s *refreshTokenStorage) RotateRefreshToken(ctx context.Context, tokenHash, newTokenHash, clientID, ip string, expiresAt time.Time) (*pb.UserModel, Grant, error) {
+	logs, err := logger.NewLogger()
+	if err != nil {
+		return nil, Grant{}, err
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, Grant{}, err
+	}
+	defer tx.Rollback()
+
+	query := `
+		SELECT
+			r.id,
+			r.user_id,
+			r.family_id,
+			r.expires_at,
+			r.rotated_at IS NOT NULL,
+			r.revoked_at IS NOT NULL,
+			COALESCE(s.client_id, ''),
+			COALESCE(s.scope, '')
+		FROM
+			refresh_tokens r
+		LEFT JOIN
+			sessions s ON s.id = r.family_id
+		WHERE
+			r.token_hash = $1
+		FOR UPDATE OF r
+	`
+	var (
+		id, userID, familyID string
+		expires              time.Time
+		rotated, revoked     bool
+		grant                Grant
+	)
+	err = tx.QueryRowContext(ctx, query, tokenHash).Scan(&id, &userID, &familyID, &expires, &rotated, &revoked, &grant.ClientID, &grant.Scope)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, Grant{}, ErrRefreshTokenInvalid
+	}
+	if err != nil {
+		logs.Error("Error getting refresh token", zap.Error(err))
+		return nil, Grant{}, err
+	}
+
+	if rotated && !revoked {
+		_, err = tx.ExecContext(ctx, `UPDATE refresh_tokens SET revoked_at = now() WHERE family_id = $1 AND revoked_at IS NULL`, familyID)
+		if err != nil {
+			logs.Error("Error revoking refresh token family", zap.Error(err))
+			return nil, Grant{}, err
+		}
+		err = writeAudit(ctx, tx, userID, eventRefreshTokenReused, fmt.Sprintf("family_id=%s token_id=%s", familyID, id))
+		if err != nil {
+			logs.Error("Error writing audit log", zap.Error(err))
+			return nil, Grant{}, err
+		}
+		if err = tx.Commit(); err != nil {
+			return nil, Grant{}, err
+		}
+		logs.Warn("Refresh token reuse detected, family revoked", zap.String("user_id", userID), zap.String("family_id", familyID))
+		return nil, Grant{}, ErrRefreshTokenReused
+	}
+	if revoked || time.Now().After(expires) || grant.ClientID != clientID {
+		return nil, Grant{}, ErrRefreshTokenInvalid
+	}
+
+	user := pb.UserModel{}
+	query = `SELECT id, role, email_verified FROM users WHERE id = $1 AND deleted_at = 0`
+	err = tx.QueryRowContext(ctx, query, userID).Scan(&user.Id, &user.Role, &user.EmailVerified)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, Grant{}, ErrRefreshTokenInvalid
+	}
+	if err != nil {
+		logs.Error("Error getting user", zap.Error(err))
+		return nil, Grant{}, err
+	}
+
+	_, err = tx.ExecContext(ctx, `UPDATE refresh_tokens SET rotated_at = now() WHERE id = $1`, id)
+	if err != nil {
+		logs.Error("Error rotating refresh token", zap.Error(err))
+		return nil, Grant{}, err
+	}
+	query = `INSERT INTO refresh_tokens (id, user_id, family_id, token_hash, expires_at) VALUES ($1, $2, $3, $4, $5)`
+	_, err = tx.ExecContext(ctx, query, uuid.NewString(), userID, familyID, newTokenHash, expiresAt)
+	if err != nil {
+		logs.Error("Error creating refresh token", zap.Error(err))
+		return nil, Grant{}, err
+	}
+	_, err = tx.ExecContext(ctx, `UPDATE sessions SET last_seen_at = now(), ip = $2, expires_at = GREATEST(expires_at, $3) WHERE id = $1`, familyID, ip, expiresAt)
+	if err != nil {
+		logs.Error("Error updating session", zap.Error(err))
+		return nil, Grant{}, err
+	}
+	if err = tx.Commit(); err != nil {
+		return nil, Grant{}, err
+	}
+	grant.SessionID = familyID
+	return &user, grant, nil
+}
+
+// RevokeRefreshToken ends the session the token belongs to: every token of its
+// family is revoked, along with the session record. It returns the session id.
+func (s *refreshTokenStorage) RevokeRefreshToken(ctx context.Context, tokenHash string) (string, error) {
+	logs, err := logger.NewLogger()
+	if err != nil {
+		return "", err
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+
+	var userID, familyID string
+	err = tx.QueryRowContext(ctx, `SELECT user_id, family_id FROM refresh_tokens WHERE token_hash = $1`, tokenHash).Scan(&userID, &familyID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", ErrRefreshTokenInvalid
+	}
+	if err != nil {
+		logs.Error("Error getting refresh token", zap.Error(err))
+		return "", err
+	}
+	_, err = tx.ExecContext(ctx, `UPDATE refresh_tokens SET revoked_at = now() WHERE family_id = $1 AND revoked_at IS NULL`, familyID)
+	if err != nil {
+		logs.Error("Error revoking refresh token family", zap.Error(err))
+		return "", err
+	}
+	res, err := tx.ExecContext(ctx, `UPDATE sessions SET revoked_at = now() WHERE id = $1 AND revoked_at IS NULL`, familyID)
+	if err != nil {
+		logs.Error("Error revoking session", zap.Error(err))
+		return "", err
+	}
+	if n, _ := res.RowsAffected(); n > 0 {
+		if err = writeAudit(ctx, tx, userID, eventSessionRevoked, "session revoked with refresh token"); err != nil {
+			logs.Error("Error writing audit log", zap.Error(err))
+			return "", err
+		}
+	}
+	if err = tx.Commit(); err != nil {
+		return "", err
+	}
+	return familyID, nil
+}
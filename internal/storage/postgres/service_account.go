@@ -0,0 +1,164 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	pb "blog-auth/genproto/userservice"
+	logger "blog-auth/internal/logger"
+
+	"github.com/lib/pq"
+	"go.uber.org/zap"
+)
+
+var ErrServiceAccountNotFound = errors.New("service account not found")
+
+const (
+	AuthMethodClientSecret  = "client_secret"
+	AuthMethodPrivateKeyJWT = "private_key_jwt"
+)
+
+// ServiceAccountCredential is what a service account authenticates with:
+// either the hash of its secret or its PEM encoded public key.
+type ServiceAccountCredential struct {
+	SecretHash string
+	PublicKey  string
+}
+
+type ServiceAccountStorage interface {
+	CreateServiceAccount(ctx context.Context, account *pb.ServiceAccount, credential ServiceAccountCredential) error
+	GetServiceAccount(ctx context.Context, clientID string) (*pb.ServiceAccount, ServiceAccountCredential, error)
+	ListServiceAccounts(ctx context.Context) ([]*pb.ServiceAccount, error)
+	DeleteServiceAccount(ctx context.Context, clientID string) error
+}
+
+type serviceAccountStorage struct {
+	db *sql.DB
+}
+
+func NewServiceAccountStorage(db *sql.DB) ServiceAccountStorage {
+	return &serviceAccountStorage{db: db}
+}
+
+func (s *serviceAccountStorage) CreateServiceAccount(ctx context.Context, account *pb.ServiceAccount, credential ServiceAccountCredential) error {
+	logs, err := logger.NewLogger()
+	if err != nil {
+		return err
+	}
+	var secretHash, publicKey interface{}
+	if credential.PublicKey != "" {
+		publicKey = credential.PublicKey
+	} else {
+		secretHash = credential.SecretHash
+	}
+	account.AuthMethod = authMethod(credential.PublicKey != "")
+	query := `
+		INSERT INTO service_accounts (id, name, secret_hash, public_key, scopes)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING created_at
+	`
+	err = s.db.QueryRowContext(ctx, query, account.ClientId, account.Name, secretHash, publicKey, pq.Array(account.Scopes)).Scan(&account.CreatedAt)
+	if err != nil {
+		logs.Error("Error creating service account", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+func (s *serviceAccountStorage) GetServiceAccount(ctx context.Context, clientID string) (*pb.ServiceAccount, ServiceAccountCredential, error) {
+	logs, err := logger.NewLogger()
+	if err != nil {
+		return nil, ServiceAccountCredential{}, err
+	}
+	query := `
+		SELECT
+			id,
+			name,
+			scopes,
+			COALESCE(secret_hash, ''),
+			COALESCE(public_key, ''),
+			created_at
+		FROM
+			service_accounts
+		WHERE
+			id = $1 AND deleted_at = 0
+	`
+	account := pb.ServiceAccount{}
+	credential := ServiceAccountCredential{}
+	err = s.db.QueryRowContext(ctx, query, clientID).Scan(&account.ClientId, &account.Name, pq.Array(&account.Scopes), &credential.SecretHash, &credential.PublicKey, &account.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ServiceAccountCredential{}, ErrServiceAccountNotFound
+	}
+	if err != nil {
+		logs.Error("Error getting service account", zap.Error(err))
+		return nil, ServiceAccountCredential{}, err
+	}
+	account.AuthMethod = authMethod(credential.PublicKey != "")
+	return &account, credential, nil
+}
+
+func (s *serviceAccountStorage) ListServiceAccounts(ctx context.Context) ([]*pb.ServiceAccount, error) {
+	logs, err := logger.NewLogger()
+	if err != nil {
+		return nil, err
+	}
+	query := `
+		SELECT
+			id,
+			name,
+			scopes,
+			public_key IS NOT NULL,
+			created_at
+		FROM
+			service_accounts
+		WHERE
+			deleted_at = 0
+		ORDER BY
+			created_at
+	`
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		logs.Error("Error listing service accounts", zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	var accounts []*pb.ServiceAccount
+	for rows.Next() {
+		account := pb.ServiceAccount{}
+		var hasKey bool
+		err = rows.Scan(&account.ClientId, &account.Name, pq.Array(&account.Scopes), &hasKey, &account.CreatedAt)
+		if err != nil {
+			logs.Error("Error scanning service account", zap.Error(err))
+			return nil, err
+		}
+		account.AuthMethod = authMethod(hasKey)
+		accounts = append(accounts, &account)
+	}
+	return accounts, rows.Err()
+}
+
+func (s *serviceAccountStorage) DeleteServiceAccount(ctx context.Context, clientID string) error {
+	logs, err := logger.NewLogger()
+	if err != nil {
+		return err
+	}
+	query := `UPDATE service_accounts SET deleted_at = date_part('epoch', now())::bigint WHERE id = $1 AND deleted_at = 0`
+	res, err := s.db.ExecContext(ctx, query, clientID)
+	if err != nil {
+		logs.Error("Error deleting service account", zap.Error(err))
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrServiceAccountNotFound
+	}
+	return nil
+}
+
+func authMethod(hasKey bool) string {
+	if hasKey {
+		return AuthMethodPrivateKeyJWT
+	}
+	return AuthMethodClientSecret
+}
@@ -0,0 +1,306 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	pb "blog-auth/genproto/userservice"
+	logger "blog-auth/internal/logger"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrSessionNotFound   = errors.New("session not found")
+	ErrSignInCodeInvalid = errors.New("sign-in code is invalid or expired")
+)
+
+// Device describes the client a session was started from. ClientID and Scope
+// are set when an OAuth client started it.
+type Device struct {
+	Name      string
+	UserAgent string
+	IP        string
+	ClientID  string
+	Scope     string
+}
+
+// SessionStorage keeps one record per login. A session id doubles as the
+// family id of its refresh tokens, so revoking a session revokes them too. A
+// session expires with its last token, or its cookie on the HTTP pages.
+type SessionStorage interface {
+	CreateSession(ctx context.Context, userID string, device Device, expiresAt time.Time) (string, error)
+	ListSessions(ctx context.Context, userID string) ([]*pb.Session, error)
+	RevokeSession(ctx context.Context, userID, sessionID string) error
+	RevokeOtherSessions(ctx context.Context, userID, keepID string) ([]string, error)
+	RevokeUserSessions(ctx context.Context, userID string) ([]string, error)
+	SessionUser(ctx context.Context, sessionID string) (string, time.Time, error)
+	CreateSignInCode(ctx context.Context, sessionID, codeHash, handoffHash string, expiresAt time.Time) error
+	ConsumeSignInCode(ctx context.Context, codeHash, handoffHash string) (string, time.Time, error)
+}
+
+type sessionStorage struct {
+	db *sql.DB
+}
+
+func NewSessionStorage(db *sql.DB) SessionStorage {
+	return &sessionStorage{db: db}
+}
+
+func (s *sessionStorage) CreateSession(ctx context.Context, userID string, device Device, expiresAt time.Time) (string, error) {
+	logs, err := logger.NewLogger()
+	if err != nil {
+		return "", err
+	}
+	if len(device.Name) > 100 {
+		device.Name = device.Name[:100]
+	}
+	id := uuid.NewString()
+	query := `INSERT INTO sessions (id, user_id, device_name, user_agent, ip, client_id, scope, expires_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+	_, err = s.db.ExecContext(ctx, query, id, userID, device.Name, device.UserAgent, device.IP, device.ClientID, device.Scope, expiresAt)
+	if err != nil {
+		logs.Error("Error creating session", zap.Error(err))
+		return "", err
+	}
+	return id, nil
+}
+
+// ListSessions returns the user's sessions that are neither revoked nor
+// expired, with or without a refresh token, most recently used first.
+func (s *sessionStorage) ListSessions(ctx context.Context, userID string) ([]*pb.Session, error) {
+	logs, err := logger.NewLogger()
+	if err != nil {
+		return nil, err
+	}
+	query := `
+		SELECT
+			s.id,
+			s.device_name,
+			s.user_agent,
+			s.ip,
+			s.created_at,
+			s.last_seen_at,
+			s.client_id,
+			s.scope
+		FROM
+			sessions s
+		WHERE
+			s.user_id = $1
+			AND s.revoked_at IS NULL
+			AND s.expires_at > now()
+		ORDER BY
+			s.last_seen_at DESC
+	`
+	rows, err := s.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		logs.Error("Error listing sessions", zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sessions []*pb.Session
+	for rows.Next() {
+		session := pb.Session{}
+		err = rows.Scan(&session.Id, &session.DeviceName, &session.UserAgent, &session.Ip, &session.CreatedAt, &session.LastSeenAt, &session.ClientId, &session.Scope)
+		if err != nil {
+			logs.Error("Error scanning session", zap.Error(err))
+			return nil, err
+		}
+		sessions = append(sessions, &session)
+	}
+	return sessions, rows.Err()
+}
+
+// SessionUser marks a live session as used and returns its user and when
+// they signed in. A revoked, expired or unknown session is
+// ErrSessionNotFound.
+func (s *sessionStorage) SessionUser(ctx context.Context, sessionID string) (string, time.Time, error) {
+	logs, err := logger.NewLogger()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	if _, err := uuid.Parse(sessionID); err != nil {
+		return "", time.Time{}, ErrSessionNotFound
+	}
+	var userID string
+	var createdAt time.Time
+	query := `UPDATE sessions SET last_seen_at = now() WHERE id = $1 AND revoked_at IS NULL AND expires_at > now() RETURNING user_id, created_at`
+	err = s.db.QueryRowContext(ctx, query, sessionID).Scan(&userID, &createdAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", time.Time{}, ErrSessionNotFound
+	}
+	if err != nil {
+		logs.Error("Error getting session", zap.Error(err))
+		return "", time.Time{}, err
+	}
+	return userID, createdAt, nil
+}
+
+// CreateSignInCode stores a single-use code that hands a live session over to
+// the browser holding the handoff.
+func (s *sessionStorage) CreateSignInCode(ctx context.Context, sessionID, codeHash, handoffHash string, expiresAt time.Time) error {
+	logs, err := logger.NewLogger()
+	if err != nil {
+		return err
+	}
+	if _, err := uuid.Parse(sessionID); err != nil {
+		return ErrSessionNotFound
+	}
+	query := `
+		INSERT INTO sign_in_codes (code_hash, session_id, handoff_hash, expires_at)
+		SELECT $1, id, $3, $4 FROM sessions WHERE id = $2 AND revoked_at IS NULL AND expires_at > now()
+	`
+	res, err := s.db.ExecContext(ctx, query, codeHash, sessionID, handoffHash, expiresAt)
+	if err != nil {
+		logs.Error("Error creating sign-in code", zap.Error(err))
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return ErrSessionNotFound
+	}
+	return nil
+}
+
+// ConsumeSignInCode uses up a code created for the same handoff and returns
+// its session and when the session expires. A used, expired or unknown code,
+// or one of a session that ended since, is ErrSignInCodeInvalid.
+func (s *sessionStorage) ConsumeSignInCode(ctx context.Context, codeHash, handoffHash string) (string, time.Time, error) {
+	logs, err := logger.NewLogger()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	var sessionID string
+	var expiresAt time.Time
+	query := `
+		WITH code AS (
+			UPDATE sign_in_codes SET used_at = now()
+			WHERE code_hash = $1 AND handoff_hash = $2 AND used_at IS NULL AND expires_at > now()
+			RETURNING session_id
+		)
+		SELECT
+			s.id,
+			s.expires_at
+		FROM
+			sessions s
+			JOIN code c ON c.session_id = s.id
+		WHERE
+			s.revoked_at IS NULL AND s.expires_at > now()
+	`
+	err = s.db.QueryRowContext(ctx, query, codeHash, handoffHash).Scan(&sessionID, &expiresAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", time.Time{}, ErrSignInCodeInvalid
+	}
+	if err != nil {
+		logs.Error("Error consuming sign-in code", zap.Error(err))
+		return "", time.Time{}, err
+	}
+	return sessionID, expiresAt, nil
+}
+
+func (s *sessionStorage) RevokeSession(ctx context.Context, userID, sessionID string) error {
+	if _, err := uuid.Parse(sessionID); err != nil {
+		return ErrSessionNotFound
+	}
+	query := `UPDATE sessions SET revoked_at = now() WHERE user_id = $1 AND id = $2 AND revoked_at IS NULL RETURNING id`
+	ids, err := s.revoke(ctx, userID, query, []interface{}{userID, sessionID}, false, "session revoked by user")
+	if err != nil {
+		return err
+	}
+	if len(ids) == 0 {
+		return ErrSessionNotFound
+	}
+	return nil
+}
+
+func (s *sessionStorage) RevokeOtherSessions(ctx context.Context, userID, keepID string) ([]string, error) {
+	query := `UPDATE sessions SET revoked_at = now() WHERE user_id = $1 AND id <> $2 AND revoked_at IS NULL RETURNING id`
+	return s.revoke(ctx, userID, query, []interface{}{userID, keepID}, false, "other sessions revoked by user")
+}
+
+// RevokeUserSessions ends every session of the user, including refresh token
+// families that predate session records.
+func (s *sessionStorage) RevokeUserSessions(ctx context.Context, userID string) ([]string, error) {
+	if _, err := uuid.Parse(userID); err != nil {
+		return nil, ErrUserNotFound
+	}
+	var exists bool
+	err := s.db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM users WHERE id = $1 AND deleted_at = 0)`, userID).Scan(&exists)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrUserNotFound
+	}
+	query := `UPDATE sessions SET revoked_at = now() WHERE user_id = $1 AND revoked_at IS NULL RETURNING id`
+	return s.revoke(ctx, userID, query, []interface{}{userID}, true, "all sessions revoked by admin")
+}
+
+// revoke runs query, which marks sessions as revoked and returns their ids,
+// and revokes the refresh tokens of those sessions. With allTokens every
+// refresh token of the user is revoked instead. It returns the revoked ids.
+func (s *sessionStorage) revoke(ctx context.Context, userID, query string, args []interface{}, allTokens bool, details string) ([]string, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	ids, err := revokeSessions(ctx, tx, userID, query, args, allTokens, details)
+	if err != nil {
+		return nil, err
+	}
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// revokeSessions does the work of revoke inside tx, so other changes that must
+// log the user out can commit together with it.
+func revokeSessions(ctx context.Context, tx *sql.Tx, userID, query string, args []interface{}, allTokens bool, details string) ([]string, error) {
+	logs, err := logger.NewLogger()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		logs.Error("Error revoking sessions", zap.Error(err))
+		return nil, err
+	}
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err = rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if allTokens {
+		_, err = tx.ExecContext(ctx, `UPDATE refresh_tokens SET revoked_at = now() WHERE user_id = $1 AND revoked_at IS NULL`, userID)
+	} else {
+		_, err = tx.ExecContext(ctx, `UPDATE refresh_tokens SET revoked_at = now() WHERE family_id = ANY($1) AND revoked_at IS NULL`, pq.Array(ids))
+	}
+	if err != nil {
+		logs.Error("Error revoking refresh tokens", zap.Error(err))
+		return nil, err
+	}
+	if len(ids) > 0 || allTokens {
+		err = writeAudit(ctx, tx, userID, eventSessionRevoked, fmt.Sprintf("%s: %d session(s)", details, len(ids)))
+		if err != nil {
+			logs.Error("Error writing audit log", zap.Error(err))
+			return nil, err
+		}
+	}
+	return ids, nil
+}
@@ -0,0 +1,178 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	logger "blog-auth/internal/logger"
+	"blog-auth/internal/token"
+
+	"go.uber.org/zap"
+)
+
+// SigningKeyStorage keeps the asymmetric keys access tokens are signed with.
+// Every replica loads the same set, so rotation happens once for all of them.
+// Private keys are encrypted with a key-encryption key from the config.
+type SigningKeyStorage interface {
+	ListSigningKeys(ctx context.Context) ([]token.SigningKey, error)
+	AddSigningKey(ctx context.Context, key token.SigningKey) (bool, error)
+	ReplaceSigningKeys(ctx context.Context, key token.SigningKey, expireOthersAt time.Time) error
+}
+
+type signingKeyStorage struct {
+	db  *sql.DB
+	kek string
+}
+
+func NewSigningKeyStorage(db *sql.DB, kek string) SigningKeyStorage {
+	return &signingKeyStorage{db: db, kek: kek}
+}
+
+// ListSigningKeys returns the keys that have not expired yet. Keys stored in
+// plaintext before they were encrypted are sealed on the way.
+func (s *signingKeyStorage) ListSigningKeys(ctx context.Context) ([]token.SigningKey, error) {
+	logs, err := logger.NewLogger()
+	if err != nil {
+		return nil, err
+	}
+	query := `
+		SELECT
+			kid,
+			algorithm,
+			private_key,
+			activates_at,
+			retires_at,
+			expires_at
+		FROM
+			signing_keys
+		WHERE
+			expires_at > now()
+		ORDER BY
+			activates_at
+	`
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		logs.Error("Error listing signing keys", zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys, legacy []token.SigningKey
+	for rows.Next() {
+		var (
+			key     token.SigningKey
+			private string
+		)
+		err = rows.Scan(&key.ID, &key.Algorithm, &private, &key.ActivatesAt, &key.RetiresAt, &key.ExpiresAt)
+		if err != nil {
+			logs.Error("Error scanning signing key", zap.Error(err))
+			return nil, err
+		}
+		var plaintext bool
+		key.Private, plaintext, err = token.OpenPrivateKey(s.kek, key.ID, private)
+		if err != nil {
+			logs.Error("Error opening signing key", zap.String("kid", key.ID), zap.Error(err))
+			continue
+		}
+		if plaintext {
+			legacy = append(legacy, key)
+		}
+		keys = append(keys, key)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	for _, key := range legacy {
+		if err := s.sealSigningKey(ctx, key); err != nil {
+			logs.Error("Error encrypting signing key", zap.String("kid", key.ID), zap.Error(err))
+		}
+	}
+	return keys, nil
+}
+
+// sealSigningKey encrypts a key that is still stored in plaintext.
+func (s *signingKeyStorage) sealSigningKey(ctx context.Context, key token.SigningKey) error {
+	sealed, err := token.SealPrivateKey(s.kek, key.ID, key.Private)
+	if err != nil {
+		return err
+	}
+	query := `UPDATE signing_keys SET private_key = $2 WHERE kid = $1 AND private_key NOT LIKE 'v1:%'`
+	_, err = s.db.ExecContext(ctx, query, key.ID, sealed)
+	return err
+}
+
+// AddSigningKey stores key unless another replica already added a key of the
+// same algorithm that activates at the same time or later. It reports whether
+// key was stored.
+func (s *signingKeyStorage) AddSigningKey(ctx context.Context, key token.SigningKey) (bool, error) {
+	logs, err := logger.NewLogger()
+	if err != nil {
+		return false, err
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	if _, err = tx.ExecContext(ctx, `LOCK TABLE signing_keys IN SHARE ROW EXCLUSIVE MODE`); err != nil {
+		return false, err
+	}
+	var exists bool
+	query := `SELECT EXISTS (SELECT 1 FROM signing_keys WHERE algorithm = $1 AND activates_at >= $2 AND expires_at > now())`
+	if err = tx.QueryRowContext(ctx, query, key.Algorithm, key.ActivatesAt).Scan(&exists); err != nil {
+		logs.Error("Error checking signing keys", zap.Error(err))
+		return false, err
+	}
+	if exists {
+		return false, nil
+	}
+	if err = insertSigningKey(ctx, tx, s.kek, key); err != nil {
+		logs.Error("Error creating signing key", zap.Error(err))
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// ReplaceSigningKeys makes key the only one that signs from its activation on.
+// Other keys stop signing then and stop verifying at expireOthersAt.
+func (s *signingKeyStorage) ReplaceSigningKeys(ctx context.Context, key token.SigningKey, expireOthersAt time.Time) error {
+	logs, err := logger.NewLogger()
+	if err != nil {
+		return err
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+		UPDATE signing_keys
+		SET
+			retires_at = LEAST(retires_at, $1),
+			expires_at = LEAST(expires_at, $2)
+		WHERE
+			expires_at > now()
+	`
+	if _, err = tx.ExecContext(ctx, query, key.ActivatesAt, expireOthersAt); err != nil {
+		logs.Error("Error retiring signing keys", zap.Error(err))
+		return err
+	}
+	if err = insertSigningKey(ctx, tx, s.kek, key); err != nil {
+		logs.Error("Error creating signing key", zap.Error(err))
+		return err
+	}
+	return tx.Commit()
+}
+
+func insertSigningKey(ctx context.Context, tx *sql.Tx, kek string, key token.SigningKey) error {
+	private, err := token.SealPrivateKey(kek, key.ID, key.Private)
+	if err != nil {
+		return err
+	}
+	query := `INSERT INTO signing_keys (kid, algorithm, private_key, activates_at, retires_at, expires_at) VALUES ($1, $2, $3, $4, $5, $6)`
+	_, err = tx.ExecContext(ctx, query, key.ID, key.Algorithm, private, key.ActivatesAt, key.RetiresAt, key.ExpiresAt)
+	return err
+}
@@ -0,0 +1,79 @@
+package token
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// maxAssertionLifetime bounds how far in the future a client assertion may
+// expire, which also bounds how long its jti has to be remembered.
+const maxAssertionLifetime = 5 * time.Minute
+
+// ParsePublicKey decodes a PEM encoded RSA or Ed25519 public key.
+func ParsePublicKey(encoded string) (crypto.PublicKey, error) {
+	block, _ := pem.Decode([]byte(encoded))
+	if block == nil {
+		return nil, errors.New("invalid public key PEM")
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	switch key := key.(type) {
+	case *rsa.PublicKey:
+		if key.N.BitLen() < rsaKeyBits {
+			return nil, fmt.Errorf("rsa keys must have at least %d bits", rsaKeyBits)
+		}
+		return key, nil
+	case ed25519.PublicKey:
+		return key, nil
+	}
+	return nil, errors.New("public key must be RSA or Ed25519")
+}
+
+// ParseClientAssertion verifies a private_key_jwt client assertion (RFC 7523)
+// signed by the client with key: it must be issued by and about clientID,
+// addressed to audience, short-lived and carry a jti. The caller must reject
+// a jti it has seen before.
+func ParseClientAssertion(raw string, key crypto.PublicKey, clientID, audience string) (*jwt.RegisteredClaims, error) {
+	method := AlgEdDSA
+	if _, ok := key.(*rsa.PublicKey); ok {
+		method = AlgRS256
+	}
+	claims := &jwt.RegisteredClaims{}
+	_, err := jwt.ParseWithClaims(raw, claims, func(*jwt.Token) (interface{}, error) { return key, nil },
+		jwt.WithValidMethods([]string{method}),
+		jwt.WithIssuer(clientID),
+		jwt.WithSubject(clientID),
+		jwt.WithAudience(audience),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("invalid client assertion: %w", err)
+	}
+	if claims.ID == "" {
+		return nil, errors.New("invalid client assertion: missing jti")
+	}
+	if claims.ExpiresAt.Time.After(time.Now().Add(maxAssertionLifetime)) {
+		return nil, errors.New("invalid client assertion: expires too far in the future")
+	}
+	return claims, nil
+}
+
+// AssertionIssuer returns the unverified issuer of a client assertion, which
+// names the client when the request has no client_id.
+func AssertionIssuer(raw string) string {
+	claims := &jwt.RegisteredClaims{}
+	if _, _, err := jwt.NewParser().ParseUnverified(raw, claims); err != nil {
+		return ""
+	}
+	return claims.Issuer
+}
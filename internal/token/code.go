@@ -0,0 +1,60 @@
+package token
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// userCodeAlphabet has no vowels, so user codes spell no words, and no
+// characters that are easy to mistake for each other.
+const userCodeAlphabet = "BCDFGHJKLMNPQRSTVWXZ"
+
+// NewVerificationCode returns a random 6-digit numeric code.
+func NewVerificationCode() (string, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(1000000))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%06d", n.Int64()), nil
+}
+
+// HashVerificationCode keys the hash with a server secret: a plain hash of a
+// 6-digit code could be brute-forced offline from a database dump.
+func HashVerificationCode(key, code string) string {
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write([]byte(code))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// NewUserCode returns a random device flow user code such as "WDJB-MJHT", short
+// enough to type on another device.
+func NewUserCode() (string, error) {
+	code := make([]byte, 8)
+	for i := range code {
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(len(userCodeAlphabet))))
+		if err != nil {
+			return "", err
+		}
+		code[i] = userCodeAlphabet[n.Int64()]
+	}
+	return string(code[:4]) + "-" + string(code[4:]), nil
+}
+
+// NormalizeUserCode uppercases a user code as typed and restores its dash.
+func NormalizeUserCode(code string) string {
+	code = strings.Map(func(r rune) rune {
+		if r == '-' || r == ' ' {
+			return -1
+		}
+		return r
+	}, strings.ToUpper(code))
+	if len(code) != 8 {
+		return code
+	}
+	return code[:4] + "-" + code[4:]
+}
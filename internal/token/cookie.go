@@ -0,0 +1,43 @@
+package token
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SessionCookie returns the value of a browser cookie that resumes the
+// session until expires. It is signed with a subkey of TOKENKEY, so a session id cannot
+// be guessed into a cookie; revocation is checked against the session.
+func (m *Manager) SessionCookie(sessionID string, expires time.Time) string {
+	payload := sessionID + "." + strconv.FormatInt(expires.Unix(), 10)
+	return payload + "." + m.signCookie(payload)
+}
+
+// ParseSessionCookie checks the signature and expiry of a cookie made by
+// SessionCookie and returns its session id.
+func (m *Manager) ParseSessionCookie(value string) (string, bool) {
+	i := strings.LastIndex(value, ".")
+	if i < 0 {
+		return "", false
+	}
+	payload, sig := value[:i], value[i+1:]
+	if !hmac.Equal([]byte(sig), []byte(m.signCookie(payload))) {
+		return "", false
+	}
+	sessionID, exp, ok := strings.Cut(payload, ".")
+	expires, err := strconv.ParseInt(exp, 10, 64)
+	if !ok || err != nil || !time.Now().Before(time.Unix(expires, 0)) {
+		return "", false
+	}
+	return sessionID, true
+}
+
+func (m *Manager) signCookie(payload string) string {
+	mac := hmac.New(sha256.New, m.cookieKey)
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
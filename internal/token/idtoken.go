@@ -0,0 +1,51 @@
+package token
+
+import (
+	"errors"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// ErrSymmetricIDToken is returned when asked for an ID token while access
+// tokens are signed with the shared secret, which relying parties cannot
+// verify.
+var ErrSymmetricIDToken = errors.New("id tokens need an asymmetric signing algorithm")
+
+// UserInfo holds the OpenID Connect standard claims released for a user.
+// Only claims covered by the granted scopes are set.
+type UserInfo struct {
+	Name          string `json:"name,omitempty"`
+	GivenName     string `json:"given_name,omitempty"`
+	FamilyName    string `json:"family_name,omitempty"`
+	Picture       string `json:"picture,omitempty"`
+	Email         string `json:"email,omitempty"`
+	EmailVerified *bool  `json:"email_verified,omitempty"`
+}
+
+// IDClaims is the payload of an OpenID Connect ID token.
+type IDClaims struct {
+	Nonce    string           `json:"nonce,omitempty"`
+	AuthTime *jwt.NumericDate `json:"auth_time,omitempty"`
+	UserInfo
+	jwt.RegisteredClaims
+}
+
+// GenerateIDToken signs an ID token issued by issuer for the user to the
+// client. It expires together with the access token issued alongside it.
+func (m *Manager) GenerateIDToken(issuer, userID, clientID string, claims IDClaims) (string, error) {
+	if m.Algorithm() == AlgHS256 {
+		return "", ErrSymmetricIDToken
+	}
+	now := time.Now()
+	claims.RegisteredClaims = jwt.RegisteredClaims{
+		ID:        uuid.NewString(),
+		Issuer:    issuer,
+		Subject:   userID,
+		Audience:  jwt.ClaimStrings{clientID},
+		IssuedAt:  jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(now.Add(m.accessTTL)),
+	}
+	return m.sign(claims)
+}
@@ -0,0 +1,196 @@
+package token
+
+import (
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	AlgHS256 = "HS256"
+	AlgRS256 = "RS256"
+	AlgEdDSA = "EdDSA"
+
+	rsaKeyBits = 2048
+)
+
+// SigningKey is an asymmetric key identified by its kid. A key is published
+// from the moment it exists, signs between ActivatesAt and RetiresAt, and
+// still verifies until ExpiresAt so tokens it signed can run out.
+type SigningKey struct {
+	ID          string
+	Algorithm   string
+	Private     crypto.Signer
+	ActivatesAt time.Time
+	RetiresAt   time.Time
+	ExpiresAt   time.Time
+}
+
+// GenerateSigningKey creates a new key for alg with a random kid.
+func GenerateSigningKey(alg string) (SigningKey, error) {
+	var (
+		private crypto.Signer
+		err     error
+	)
+	switch alg {
+	case AlgRS256:
+		private, err = rsa.GenerateKey(rand.Reader, rsaKeyBits)
+	case AlgEdDSA:
+		_, private, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		return SigningKey{}, fmt.Errorf("unsupported signing algorithm %q", alg)
+	}
+	if err != nil {
+		return SigningKey{}, err
+	}
+	kid, _, err := NewOpaqueToken()
+	if err != nil {
+		return SigningKey{}, err
+	}
+	return SigningKey{ID: kid[:16], Algorithm: alg, Private: private}, nil
+}
+
+// MarshalPrivateKey encodes a private key as PKCS #8 PEM for storage.
+func MarshalPrivateKey(key crypto.Signer) (string, error) {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return "", err
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})), nil
+}
+
+// ParsePrivateKey decodes a key written by MarshalPrivateKey.
+func ParsePrivateKey(encoded string) (crypto.Signer, error) {
+	block, _ := pem.Decode([]byte(encoded))
+	if block == nil {
+		return nil, errors.New("invalid private key PEM")
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, errors.New("private key cannot sign")
+	}
+	return signer, nil
+}
+
+// sealedKeyPrefix marks private keys encrypted by SealPrivateKey. Keys stored
+// before encryption are bare PEM.
+const sealedKeyPrefix = "v1:"
+
+func keyCipher(kek string) (cipher.AEAD, error) {
+	sum := sha256.Sum256([]byte(kek))
+	block, err := aes.NewCipher(sum[:])
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// SealPrivateKey encrypts a private key with AES-256-GCM under kek for
+// storage, so read access to the database does not let anyone sign tokens.
+// The kid is bound to the ciphertext, which therefore cannot be moved to
+// another key's row.
+func SealPrivateKey(kek, kid string, key crypto.Signer) (string, error) {
+	encoded, err := MarshalPrivateKey(key)
+	if err != nil {
+		return "", err
+	}
+	gcm, err := keyCipher(kek)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := gcm.Seal(nonce, nonce, []byte(encoded), []byte(kid))
+	return sealedKeyPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// OpenPrivateKey decrypts a key written by SealPrivateKey. A key stored as bare
+// PEM before encryption is parsed as it is and reported as legacy, so the
+// caller can seal it.
+func OpenPrivateKey(kek, kid, stored string) (crypto.Signer, bool, error) {
+	if !strings.HasPrefix(stored, sealedKeyPrefix) {
+		key, err := ParsePrivateKey(stored)
+		return key, true, err
+	}
+	data, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(stored, sealedKeyPrefix))
+	if err != nil {
+		return nil, false, err
+	}
+	gcm, err := keyCipher(kek)
+	if err != nil {
+		return nil, false, err
+	}
+	if len(data) < gcm.NonceSize() {
+		return nil, false, errors.New("sealed private key too short")
+	}
+	encoded, err := gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], []byte(kid))
+	if err != nil {
+		return nil, false, err
+	}
+	key, err := ParsePrivateKey(string(encoded))
+	return key, false, err
+}
+
+func signingMethod(alg string) jwt.SigningMethod {
+	switch alg {
+	case AlgRS256:
+		return jwt.SigningMethodRS256
+	case AlgEdDSA:
+		return jwt.SigningMethodEdDSA
+	}
+	return nil
+}
+
+// JWK is the public half of a signing key in RFC 7517 form.
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+// JWKS is the document verifiers fetch to check access tokens.
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+func publicJWK(key SigningKey) (JWK, bool) {
+	jwk := JWK{Kid: key.ID, Use: "sig", Alg: key.Algorithm}
+	switch pub := key.Private.Public().(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+	case ed25519.PublicKey:
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+	default:
+		return JWK{}, false
+	}
+	return jwk, true
+}
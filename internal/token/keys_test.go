@@ -0,0 +1,120 @@
+package token
+
+import (
+	"context"
+	"crypto/ed25519"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestSetKeys(t *testing.T) {
+	m := newTestManager(AlgRS256)
+	old := activeKey(t, AlgRS256)
+	old.ActivatesAt = time.Now().Add(-time.Hour)
+	next := activeKey(t, AlgRS256)
+	pending := activeKey(t, AlgRS256)
+	pending.ActivatesAt = time.Now().Add(time.Hour)
+	expired := activeKey(t, AlgRS256)
+	expired.ExpiresAt = time.Now().Add(-time.Minute)
+	m.SetKeys([]SigningKey{old, next, pending, expired})
+
+	raw, err := m.GenerateAccessToken(Claims{UserID: "user-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, _, err := jwt.NewParser().ParseUnverified(raw, &Claims{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if kid := parsed.Header["kid"]; kid != next.ID {
+		t.Errorf("signed with %v, want the last activated key %s", kid, next.ID)
+	}
+
+	kids := map[string]bool{}
+	for _, k := range m.JWKS().Keys {
+		kids[k.Kid] = true
+	}
+	if !kids[old.ID] || !kids[next.ID] || !kids[pending.ID] || kids[expired.ID] {
+		t.Errorf("JWKS kids = %v", kids)
+	}
+}
+
+func TestSetKeysWithoutActiveKey(t *testing.T) {
+	m := newTestManager(AlgRS256)
+	pending := activeKey(t, AlgRS256)
+	pending.ActivatesAt = time.Now().Add(time.Hour)
+	m.SetKeys([]SigningKey{pending})
+	if _, err := m.GenerateAccessToken(Claims{UserID: "user-1"}); err == nil {
+		t.Error("GenerateAccessToken signed without an active key")
+	}
+}
+
+func TestReloadOnUnknownKid(t *testing.T) {
+	key := activeKey(t, AlgRS256)
+	signer := newTestManager(AlgRS256)
+	signer.SetKeys([]SigningKey{key})
+	raw, err := signer.GenerateAccessToken(Claims{UserID: "user-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A replica that has not refreshed since the key was created.
+	verifier := newTestManager(AlgRS256)
+	reloads := 0
+	verifier.SetReloader(func(ctx context.Context) error {
+		reloads++
+		verifier.SetKeys([]SigningKey{key})
+		return nil
+	}, time.Hour)
+	if _, err := verifier.ParseAccessToken(raw); err != nil {
+		t.Fatalf("ParseAccessToken: %v", err)
+	}
+
+	other := newTestManager(AlgRS256)
+	other.SetKeys([]SigningKey{activeKey(t, AlgRS256)})
+	unknown, err := other.GenerateAccessToken(Claims{UserID: "user-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := verifier.ParseAccessToken(unknown); err == nil {
+		t.Error("token of an unknown key verified")
+	}
+	if reloads != 1 {
+		t.Errorf("reloaded %d times, want 1 within the interval", reloads)
+	}
+}
+
+func TestSealPrivateKey(t *testing.T) {
+	key := activeKey(t, AlgEdDSA)
+	sealed, err := SealPrivateKey("kek", key.ID, key.Private)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(sealed, "PRIVATE KEY") {
+		t.Fatalf("sealed key is plaintext PEM: %q", sealed)
+	}
+	opened, legacy, err := OpenPrivateKey("kek", key.ID, sealed)
+	if err != nil || legacy {
+		t.Fatalf("OpenPrivateKey = %v, %v", legacy, err)
+	}
+	if !key.Private.Public().(ed25519.PublicKey).Equal(opened.Public()) {
+		t.Error("opened a different key")
+	}
+	if _, _, err := OpenPrivateKey("other", key.ID, sealed); err == nil {
+		t.Error("opened with another key-encryption key")
+	}
+	if _, _, err := OpenPrivateKey("kek", "other-kid", sealed); err == nil {
+		t.Error("opened under another kid")
+	}
+
+	plain, err := MarshalPrivateKey(key.Private)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, legacy, err := OpenPrivateKey("kek", key.ID, plain); !legacy || err != nil {
+		t.Errorf("plaintext key: OpenPrivateKey = %v, %v", legacy, err)
+	}
+}
@@ -0,0 +1,24 @@
+package token
+
+import "strings"
+
+// PersonalTokenPrefix starts every personal access token, so secret scanners
+// can recognize leaked ones and the server can tell them from JWTs.
+const PersonalTokenPrefix = "bpat_"
+
+// NewPersonalAccessToken returns a random personal access token and its
+// SHA-256 hash. Only the hash is stored.
+func NewPersonalAccessToken() (string, string, error) {
+	plain, _, err := NewOpaqueToken()
+	if err != nil {
+		return "", "", err
+	}
+	plain = PersonalTokenPrefix + plain
+	return plain, HashOpaqueToken(plain), nil
+}
+
+// IsPersonalAccessToken reports whether raw looks like a personal access
+// token.
+func IsPersonalAccessToken(raw string) bool {
+	return strings.HasPrefix(raw, PersonalTokenPrefix)
+}
@@ -0,0 +1,48 @@
+package token
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNewPersonalAccessToken(t *testing.T) {
+	plain, hash, err := NewPersonalAccessToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(plain, PersonalTokenPrefix) || !IsPersonalAccessToken(plain) {
+		t.Errorf("token %q does not carry the prefix", plain)
+	}
+	if hash != HashOpaqueToken(plain) {
+		t.Error("hash is not the hash of the whole token")
+	}
+	if strings.Contains(hash, strings.TrimPrefix(plain, PersonalTokenPrefix)) {
+		t.Error("hash contains the token")
+	}
+	other, otherHash, err := NewPersonalAccessToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other == plain || otherHash == hash {
+		t.Error("two tokens are the same")
+	}
+}
+
+func TestIsPersonalAccessToken(t *testing.T) {
+	access := newTestManager(AlgHS256)
+	jwt, err := access.GenerateAccessToken(Claims{UserID: "user-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for raw, want := range map[string]bool{
+		PersonalTokenPrefix + "abc": true,
+		jwt:                         false,
+		"":                          false,
+		"bpat":                      false,
+		"BPAT_abc":                  false,
+	} {
+		if got := IsPersonalAccessToken(raw); got != want {
+			t.Errorf("IsPersonalAccessToken(%q) = %v, want %v", raw, got, want)
+		}
+	}
+}
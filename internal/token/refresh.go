@@ -0,0 +1,42 @@
+package token
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"time"
+)
+
+// RefreshTTL returns how long newly issued refresh tokens stay valid.
+func (m *Manager) RefreshTTL() time.Duration {
+	return m.refreshTTL
+}
+
+// NewRefreshToken returns an opaque refresh token for the client together with
+// the hash that is stored server-side.
+func NewRefreshToken() (string, string, error) {
+	return NewOpaqueToken()
+}
+
+// HashRefreshToken returns the value refresh tokens are looked up by.
+func HashRefreshToken(plain string) string {
+	return HashOpaqueToken(plain)
+}
+
+// NewOpaqueToken returns a random URL-safe token and its SHA-256 hash. Only the
+// hash should ever be persisted.
+func NewOpaqueToken() (string, string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", err
+	}
+	plain := base64.RawURLEncoding.EncodeToString(buf)
+	return plain, HashOpaqueToken(plain), nil
+}
+
+// HashOpaqueToken returns the hex-encoded SHA-256 of an opaque token.
+func HashOpaqueToken(plain string) string {
+	sum := sha256.Sum256([]byte(plain))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,25 @@
+package token
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// Purposes TOKENKEY is used for. Each gets its own subkey, so a key that
+// leaks or is misused for one purpose does not serve another.
+const (
+	KeyAccessToken      = "access-token"
+	KeySessionCookie    = "session-cookie"
+	KeyVerificationCode = "verification-code"
+	KeyMagicLink        = "magic-link"
+	KeyTOTPSecret       = "totp-secret"
+	KeySigningKeys      = "signing-keys"
+)
+
+// Subkey derives the key for purpose from the server secret.
+func Subkey(secret, purpose string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("blog-auth subkey:" + purpose))
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,226 @@
+package token
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"blog-auth/internal/config"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+const issuer = "blog-auth"
+
+// Claims is the payload of an access token issued by this service.
+type Claims struct {
+	UserID        string   `json:"user_id"`
+	Role          string   `json:"role"`
+	EmailVerified bool     `json:"email_verified"`
+	Permissions   []string `json:"permissions,omitempty"`
+	SessionID     string   `json:"sid,omitempty"`
+	// ClientID and Scope are set on tokens issued to OAuth clients, which may
+	// only do what the granted scopes allow.
+	ClientID string `json:"client_id,omitempty"`
+	Scope    string `json:"scope,omitempty"`
+	// Service is set on tokens of service accounts, machines that act for
+	// themselves. Their UserID and ClientID are the account's client id.
+	Service bool `json:"service,omitempty"`
+	// PersonalToken is set on the claims of a personal access token, which
+	// are looked up rather than signed. Like OAuth tokens they only allow
+	// what Scope does.
+	PersonalToken bool `json:"-"`
+	jwt.RegisteredClaims
+}
+
+// Manager signs and verifies access tokens. With TOKENALGO=HS256 tokens are
+// signed with a subkey of the shared TOKENKEY secret; otherwise with the active key of the
+// set installed by SetKeys, and verifiers only need the public JWKS.
+type Manager struct {
+	key        []byte
+	cookieKey  []byte
+	algorithm  string
+	accessTTL  time.Duration
+	refreshTTL time.Duration
+
+	mu      sync.RWMutex
+	signing *SigningKey
+	keys    map[string]SigningKey
+
+	reloadMu       sync.Mutex
+	reload         func(ctx context.Context) error
+	reloadInterval time.Duration
+	reloaded       time.Time
+}
+
+// reloadTimeout bounds a key set reload triggered by a token being verified.
+const reloadTimeout = 5 * time.Second
+
+func NewManager(cfg config.Config) *Manager {
+	return &Manager{
+		key:        []byte(Subkey(cfg.TOKENKEY, KeyAccessToken)),
+		cookieKey:  []byte(Subkey(cfg.TOKENKEY, KeySessionCookie)),
+		algorithm:  cfg.TOKENALGO,
+		accessTTL:  cfg.ACCESSTOKENTTL,
+		refreshTTL: cfg.REFRESHTOKENTTL,
+		keys:       map[string]SigningKey{},
+	}
+}
+
+// Algorithm returns the algorithm new access tokens are signed with.
+func (m *Manager) Algorithm() string {
+	if m.algorithm == "" {
+		return AlgHS256
+	}
+	return m.algorithm
+}
+
+// SetKeys replaces the key set. Expired keys are dropped and the active key
+// that was activated last becomes the signing key.
+func (m *Manager) SetKeys(keys []SigningKey) {
+	now := time.Now()
+	set := map[string]SigningKey{}
+	var signing *SigningKey
+	for i := range keys {
+		key := keys[i]
+		if !now.Before(key.ExpiresAt) || signingMethod(key.Algorithm) == nil {
+			continue
+		}
+		set[key.ID] = key
+		active := !now.Before(key.ActivatesAt) && now.Before(key.RetiresAt)
+		if active && key.Algorithm == m.Algorithm() && (signing == nil || key.ActivatesAt.After(signing.ActivatesAt)) {
+			signing = &key
+		}
+	}
+	m.mu.Lock()
+	m.keys = set
+	m.signing = signing
+	m.mu.Unlock()
+}
+
+// JWKS returns the public keys tokens may currently be verified with,
+// including keys that are published ahead of their activation.
+func (m *Manager) JWKS() JWKS {
+	now := time.Now()
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	jwks := JWKS{Keys: []JWK{}}
+	for _, key := range m.keys {
+		if !now.Before(key.ExpiresAt) {
+			continue
+		}
+		if jwk, ok := publicJWK(key); ok {
+			jwks.Keys = append(jwks.Keys, jwk)
+		}
+	}
+	return jwks
+}
+
+// SetReloader makes verification call reload, at most once per interval, when
+// a token names a kid the manager does not know. A key another replica has
+// just created then verifies before the next scheduled refresh.
+func (m *Manager) SetReloader(reload func(ctx context.Context) error, interval time.Duration) {
+	m.reloadMu.Lock()
+	defer m.reloadMu.Unlock()
+	m.reload = reload
+	m.reloadInterval = interval
+}
+
+// reloadKeys runs the reloader unless it ran within its interval. It reports
+// whether the key set was reloaded.
+func (m *Manager) reloadKeys() bool {
+	m.reloadMu.Lock()
+	if m.reload == nil || time.Since(m.reloaded) < m.reloadInterval {
+		m.reloadMu.Unlock()
+		return false
+	}
+	m.reloaded = time.Now()
+	reload := m.reload
+	m.reloadMu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), reloadTimeout)
+	defer cancel()
+	return reload(ctx) == nil
+}
+
+// AccessTTL returns how long newly issued access tokens stay valid.
+func (m *Manager) AccessTTL() time.Duration {
+	return m.accessTTL
+}
+
+// GenerateAccessToken signs a short-lived access token carrying claims; the
+// registered claims are filled in here. Permissions are a snapshot;
+// CheckPermission has the current ones.
+func (m *Manager) GenerateAccessToken(claims Claims) (string, error) {
+	now := time.Now()
+	claims.RegisteredClaims = jwt.RegisteredClaims{
+		ID:        uuid.NewString(),
+		Issuer:    issuer,
+		Subject:   claims.UserID,
+		IssuedAt:  jwt.NewNumericDate(now),
+		NotBefore: jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(now.Add(m.accessTTL)),
+	}
+	if m.Algorithm() == AlgHS256 {
+		return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(m.key)
+	}
+	return m.sign(claims)
+}
+
+// sign signs claims with the active asymmetric key.
+func (m *Manager) sign(claims jwt.Claims) (string, error) {
+	m.mu.RLock()
+	signing := m.signing
+	m.mu.RUnlock()
+	if signing == nil {
+		return "", errors.New("no active signing key")
+	}
+	t := jwt.NewWithClaims(signingMethod(signing.Algorithm), claims)
+	t.Header["kid"] = signing.ID
+	return t.SignedString(signing.Private)
+}
+
+// ParseAccessToken verifies the signature and expiry of an access token and
+// returns its claims.
+func (m *Manager) ParseAccessToken(tokenStr string) (*Claims, error) {
+	claims := &Claims{}
+	_, err := jwt.ParseWithClaims(tokenStr, claims, m.verificationKey,
+		jwt.WithValidMethods([]string{m.Algorithm()}),
+		jwt.WithIssuer(issuer),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("invalid access token: %w", err)
+	}
+	if claims.UserID == "" {
+		return nil, errors.New("invalid access token: missing user id")
+	}
+	return claims, nil
+}
+
+// verificationKey picks the key a token names in its kid header. Keys are
+// only trusted for the algorithm they were generated for.
+func (m *Manager) verificationKey(t *jwt.Token) (interface{}, error) {
+	if m.Algorithm() == AlgHS256 {
+		return m.key, nil
+	}
+	kid, _ := t.Header["kid"].(string)
+	m.mu.RLock()
+	key, ok := m.keys[kid]
+	m.mu.RUnlock()
+	if !ok && m.reloadKeys() {
+		m.mu.RLock()
+		key, ok = m.keys[kid]
+		m.mu.RUnlock()
+	}
+	if !ok || !time.Now().Before(key.ExpiresAt) {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	if t.Method.Alg() != key.Algorithm {
+		return nil, errors.New("signing algorithm does not match key")
+	}
+	return key.Private.Public(), nil
+}
@@ -0,0 +1,186 @@
+package token
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"blog-auth/internal/config"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func newTestManager(alg string) *Manager {
+	return NewManager(config.Config{
+		TOKENKEY:        "test-key",
+		TOKENALGO:       alg,
+		ACCESSTOKENTTL:  15 * time.Minute,
+		REFRESHTOKENTTL: 24 * time.Hour,
+	})
+}
+
+func activeKey(t *testing.T, alg string) SigningKey {
+	t.Helper()
+	key, err := GenerateSigningKey(alg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	key.ActivatesAt = now.Add(-time.Minute)
+	key.RetiresAt = now.Add(time.Hour)
+	key.ExpiresAt = now.Add(2 * time.Hour)
+	return key
+}
+
+func TestAccessTokenRoundTrip(t *testing.T) {
+	for _, alg := range []string{AlgHS256, AlgRS256, AlgEdDSA} {
+		t.Run(alg, func(t *testing.T) {
+			m := newTestManager(alg)
+			if alg != AlgHS256 {
+				m.SetKeys([]SigningKey{activeKey(t, alg)})
+			}
+			raw, err := m.GenerateAccessToken(Claims{UserID: "user-1", Role: "admin", SessionID: "session-1"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			claims, err := m.ParseAccessToken(raw)
+			if err != nil {
+				t.Fatalf("ParseAccessToken: %v", err)
+			}
+			if claims.UserID != "user-1" || claims.Role != "admin" || claims.SessionID != "session-1" || claims.Subject != "user-1" {
+				t.Errorf("claims = %+v", claims)
+			}
+		})
+	}
+}
+
+func TestParseAccessTokenRejects(t *testing.T) {
+	m := newTestManager(AlgHS256)
+	other := NewManager(config.Config{TOKENKEY: "other-key", ACCESSTOKENTTL: time.Minute})
+	forged, err := other.GenerateAccessToken(Claims{UserID: "user-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expired := Claims{UserID: "user-1", RegisteredClaims: jwt.RegisteredClaims{
+		Issuer:    issuer,
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(-time.Minute)),
+	}}
+	expiredRaw, err := jwt.NewWithClaims(jwt.SigningMethodHS256, expired).SignedString([]byte(Subkey("test-key", KeyAccessToken)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	noUser := Claims{RegisteredClaims: jwt.RegisteredClaims{
+		Issuer:    issuer,
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+	}}
+	noUserRaw, err := jwt.NewWithClaims(jwt.SigningMethodHS256, noUser).SignedString([]byte(Subkey("test-key", KeyAccessToken)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	unsigned, err := jwt.NewWithClaims(jwt.SigningMethodNone, Claims{UserID: "user-1"}).SignedString(jwt.UnsafeAllowNoneSignatureType)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, raw := range map[string]string{
+		"wrong key":  forged,
+		"expired":    expiredRaw,
+		"no user id": noUserRaw,
+		"alg none":   unsigned,
+		"garbage":    "not-a-token",
+	} {
+		if _, err := m.ParseAccessToken(raw); err == nil {
+			t.Errorf("%s: ParseAccessToken accepted the token", name)
+		}
+	}
+}
+
+func TestMagicLinkToken(t *testing.T) {
+	plain, hash, err := NewMagicLinkToken("test-key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, ok := VerifyMagicLinkToken("test-key", plain)
+	if !ok || got != hash {
+		t.Errorf("VerifyMagicLinkToken = %q, %v; want %q, true", got, ok, hash)
+	}
+	if _, ok := VerifyMagicLinkToken("other-key", plain); ok {
+		t.Error("token verified with another key")
+	}
+	nonce, _, _ := strings.Cut(plain, ".")
+	if _, ok := VerifyMagicLinkToken("test-key", nonce+".forged"); ok {
+		t.Error("token with a forged signature verified")
+	}
+}
+
+func TestPersonalAccessToken(t *testing.T) {
+	plain, hash, err := NewPersonalAccessToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsPersonalAccessToken(plain) || hash != HashOpaqueToken(plain) {
+		t.Errorf("NewPersonalAccessToken = %q, %q", plain, hash)
+	}
+	if IsPersonalAccessToken("eyJhbGciOiJIUzI1NiJ9.e30.sig") {
+		t.Error("a JWT was taken for a personal access token")
+	}
+}
+
+func TestUserCode(t *testing.T) {
+	code, err := NewUserCode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(code) != 9 || code[4] != '-' {
+		t.Errorf("NewUserCode = %q", code)
+	}
+	if got := NormalizeUserCode(strings.ToLower(strings.ReplaceAll(code, "-", " "))); got != code {
+		t.Errorf("NormalizeUserCode = %q, want %q", got, code)
+	}
+}
+
+func TestHashVerificationCode(t *testing.T) {
+	if HashVerificationCode("key-1", "123456") == HashVerificationCode("key-2", "123456") {
+		t.Error("verification code hash does not depend on the key")
+	}
+}
+
+func TestSubkey(t *testing.T) {
+	seen := map[string]string{}
+	for _, purpose := range []string{KeyAccessToken, KeySessionCookie, KeyVerificationCode, KeyMagicLink, KeyTOTPSecret} {
+		key := Subkey("test-key", purpose)
+		if key == "test-key" || seen[key] != "" {
+			t.Errorf("subkey of %s repeats %q", purpose, seen[key])
+		}
+		seen[key] = purpose
+	}
+	if Subkey("test-key", KeyMagicLink) != Subkey("test-key", KeyMagicLink) {
+		t.Error("Subkey is not deterministic")
+	}
+	if Subkey("other-key", KeyMagicLink) == Subkey("test-key", KeyMagicLink) {
+		t.Error("Subkey does not depend on the secret")
+	}
+}
+
+func TestSessionCookie(t *testing.T) {
+	m := newTestManager(AlgHS256)
+	value := m.SessionCookie("session-1", time.Now().Add(time.Hour))
+	if id, ok := m.ParseSessionCookie(value); !ok || id != "session-1" {
+		t.Errorf("ParseSessionCookie = %q, %v", id, ok)
+	}
+
+	expired := m.SessionCookie("session-1", time.Now().Add(-time.Second))
+	other := NewManager(config.Config{TOKENKEY: "other-key"}).SessionCookie("session-1", time.Now().Add(time.Hour))
+	for name, value := range map[string]string{
+		"expired":     expired,
+		"other key":   other,
+		"other id":    strings.Replace(value, "session-1", "session-2", 1),
+		"no dot":      "session-1",
+		"empty":       "",
+		"bad expires": "session-1.soon." + m.signCookie("session-1.soon"),
+	} {
+		if _, ok := m.ParseSessionCookie(value); ok {
+			t.Errorf("%s: cookie accepted", name)
+		}
+	}
+}
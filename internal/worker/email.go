@@ -0,0 +1,143 @@
+package worker
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+
+	"blog-auth/internal/config"
+	"blog-auth/internal/helper"
+	logger "blog-auth/internal/logger"
+	"blog-auth/internal/storage/postgres"
+
+	"go.uber.org/zap"
+)
+
+const (
+	emailBatchSize = 10
+	emailLease     = 2 * time.Minute
+	maxBackoff     = time.Hour
+	purgeInterval  = time.Hour
+)
+
+// EmailWorker delivers queued emails with a pool of senders. Failed sends are
+// retried with exponential backoff until MAILATTEMPTS is reached, after which
+// the email is dead-lettered for an admin to inspect. Sent and dead emails
+// are deleted once MAILRETENTION has passed.
+type EmailWorker struct {
+	queue       postgres.EmailQueueStorage
+	mailer      helper.Mailer
+	workers     int
+	poll        time.Duration
+	maxAttempts int
+	backoff     time.Duration
+	retention   time.Duration
+}
+
+func NewEmailWorker(queue postgres.EmailQueueStorage, mailer helper.Mailer, cfg config.Config) *EmailWorker {
+	w := &EmailWorker{
+		queue:       queue,
+		mailer:      mailer,
+		workers:     cfg.MAILWORKERS,
+		poll:        cfg.MAILPOLL,
+		maxAttempts: cfg.MAILATTEMPTS,
+		backoff:     cfg.MAILBACKOFF,
+		retention:   cfg.MAILRETENTION,
+	}
+	if w.workers < 1 {
+		w.workers = 1
+	}
+	if w.poll <= 0 {
+		w.poll = 5 * time.Second
+	}
+	return w
+}
+
+// Run polls the queue until ctx is cancelled.
+func (w *EmailWorker) Run(ctx context.Context) {
+	logs, err := logger.NewLogger()
+	if err != nil {
+		return
+	}
+	jobs := make(chan postgres.QueuedEmail)
+	var wg sync.WaitGroup
+	for i := 0; i < w.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for email := range jobs {
+				w.deliver(ctx, email)
+			}
+		}()
+	}
+
+	ticker := time.NewTicker(w.poll)
+	defer ticker.Stop()
+	var purged time.Time
+	for {
+		if w.retention > 0 && time.Since(purged) >= purgeInterval {
+			purged = time.Now()
+			if _, err := w.queue.PurgeEmails(ctx, purged.Add(-w.retention)); err != nil {
+				logs.Error("Error purging old emails", zap.Error(err))
+			}
+		}
+		emails, err := w.queue.ClaimEmails(ctx, emailBatchSize, emailLease)
+		if err != nil {
+			logs.Error("Error claiming queued emails", zap.Error(err))
+		}
+		for _, email := range emails {
+			select {
+			case jobs <- email:
+			case <-ctx.Done():
+			}
+		}
+		// Keep draining while the queue is full, otherwise wait for the next tick.
+		if len(emails) == emailBatchSize && ctx.Err() == nil {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			close(jobs)
+			wg.Wait()
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (w *EmailWorker) deliver(ctx context.Context, email postgres.QueuedEmail) {
+	logs, err := logger.NewLogger()
+	if err != nil {
+		return
+	}
+	sendCtx, cancel := context.WithTimeout(ctx, time.Minute)
+	err = w.mailer.Send(sendCtx, email.Message)
+	cancel()
+	if err == nil {
+		if err = w.queue.MarkEmailSent(ctx, email.ID); err != nil {
+			logs.Error("Error marking email sent", zap.String("id", email.ID), zap.Error(err))
+		}
+		return
+	}
+
+	dead := email.Attempts >= w.maxAttempts
+	next := time.Now().Add(w.nextBackoff(email.Attempts))
+	logs.Warn("Error sending queued email", zap.String("id", email.ID), zap.Int("attempts", email.Attempts), zap.Bool("dead", dead), zap.Error(err))
+	if err = w.queue.MarkEmailFailed(ctx, email.ID, err, next, dead); err != nil {
+		logs.Error("Error marking email failed", zap.String("id", email.ID), zap.Error(err))
+	}
+}
+
+// nextBackoff doubles the base delay for every attempt, capped at maxBackoff,
+// with up to 20% jitter so failed emails do not retry in lockstep.
+func (w *EmailWorker) nextBackoff(attempts int) time.Duration {
+	d := w.backoff
+	for i := 1; i < attempts && d < maxBackoff; i++ {
+		d *= 2
+	}
+	if d > maxBackoff {
+		d = maxBackoff
+	}
+	return d + time.Duration(rand.Int63n(int64(d)/5+1))
+}
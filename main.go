@@ -1,14 +1,26 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"blog-auth/internal/config"
+	"blog-auth/internal/helper"
+	"blog-auth/internal/interceptor"
+	"blog-auth/internal/ratelimit"
+	"blog-auth/internal/revocation"
 	"net"
+	"net/http"
 
+	"blog-auth/api"
 	pb "blog-auth/genproto/userservice"
+	"blog-auth/internal/authz"
+	"blog-auth/internal/idp"
+	"blog-auth/internal/keys"
 	logger "blog-auth/internal/logger"
 	"blog-auth/internal/service"
 	"blog-auth/internal/storage/postgres"
+	"blog-auth/internal/token"
+	"blog-auth/internal/worker"
 	"google.golang.org/grpc"
 )
 
@@ -19,6 +31,10 @@ func main() {
 		logs.Error("Error while initializing logger")
 		return
 	}
+	if err := cfg.Validate(); err != nil {
+		logs.Error(fmt.Sprintf("Invalid configuration: %v", err))
+		return
+	}
 	db, err := postgres.ConnectPostgres()
 	if err != nil {
 		logs.Error("Error while initializing postgres connection")
@@ -33,16 +49,93 @@ func main() {
 	defer listener.Close()
 	logs.Info(fmt.Sprintf("Server start on port: %d", cfg.AUTHPORT))
 
-	userStorage := postgres.NewUserStorage(db)
-	userService := service.NewUserService(userStorage)
+	mailer, err := helper.NewMailer(cfg)
+	if err != nil {
+		logs.Error("Error while initializing mailer")
+		return
+	}
 
-	adminStorage := postgres.NewAdminStorage(db)
-	adminService := service.NewAdminService(adminStorage)
+	emailWorker := worker.NewEmailWorker(postgres.NewEmailQueueStorage(db), mailer, cfg)
+	go emailWorker.Run(context.Background())
 
-	s := grpc.NewServer()
+	userStorage := postgres.NewUserStorage(db, cfg)
+	tokens := token.NewManager(cfg)
+	rotator := keys.NewRotator(postgres.NewSigningKeyStorage(db, token.Subkey(cfg.TOKENKEY, token.KeySigningKeys)), tokens, cfg)
+	if err := rotator.Refresh(context.Background()); err != nil {
+		logs.Error("Error while loading signing keys")
+		return
+	}
+	go rotator.Run(context.Background())
+	denylist, err := revocation.NewDenylist(cfg)
+	if err != nil {
+		logs.Error("Error while initializing token denylist")
+		return
+	}
+	refreshStorage := postgres.NewRefreshTokenStorage(db)
+	sessionStorage := postgres.NewSessionStorage(db)
+	mfaStorage := postgres.NewMFAStorage(db, cfg)
+	passkeyStorage := postgres.NewPasskeyStorage(db)
+	web, err := service.NewWebAuthn(cfg)
+	if err != nil {
+		logs.Error("Error while initializing webauthn")
+		return
+	}
+	magicLinkStorage := postgres.NewMagicLinkStorage(db, cfg)
+	throttleStorage := postgres.NewLoginThrottleStorage(db, cfg)
+	permissionStorage := postgres.NewPermissionStorage(db)
+	engine, err := authz.New(cfg, db)
+	if err != nil {
+		logs.Error("Error while loading authorization policy")
+		return
+	}
+	go engine.Watch(context.Background(), cfg.AUTHZRELOAD)
+	personalTokenStorage := postgres.NewPersonalTokenStorage(db)
+	identityStorage := postgres.NewIdentityStorage(db, cfg)
+	providers, err := idp.New(cfg)
+	if err != nil {
+		logs.Error("Error while loading identity providers")
+		return
+	}
+	userService := service.NewUserService(userStorage, refreshStorage, sessionStorage, mfaStorage, passkeyStorage, web, magicLinkStorage, throttleStorage, permissionStorage, engine, denylist, tokens, personalTokenStorage, identityStorage, providers)
+
+	adminStorage := postgres.NewAdminStorage(db, cfg)
+	oauthStorage := postgres.NewOAuthStorage(db)
+	serviceAccountStorage := postgres.NewServiceAccountStorage(db)
+	oauthService := service.NewOAuthService(userService, oauthStorage, serviceAccountStorage, cfg)
+	adminService := service.NewAdminService(adminStorage, permissionStorage, sessionStorage, denylist, tokens, rotator, oauthStorage, serviceAccountStorage)
+
+	limiter, err := ratelimit.NewLimiter(cfg)
+	if err != nil {
+		logs.Error("Error while initializing rate limiter")
+		return
+	}
+	rules, err := ratelimit.ParseRules(cfg.RATELIMITS, cfg.RATEDEFAULT)
+	if err != nil {
+		logs.Error("Error while parsing rate limits")
+		return
+	}
+
+	proxies, err := interceptor.ParseTrustedProxies(cfg.TRUSTEDPROXIES)
+	if err != nil {
+		logs.Error("Error while parsing trusted proxies")
+		return
+	}
+
+	s := grpc.NewServer(grpc.ChainUnaryInterceptor(
+		interceptor.ClientAddress(proxies),
+		interceptor.RateLimit(limiter, rules, tokens),
+		interceptor.Auth(tokens, denylist, userService, interceptor.Policies),
+	))
 	pb.RegisterUserServiceServer(s, userService)
 	pb.RegisterAdminServiceServer(s, adminService)
 
+	go func() {
+		addr := fmt.Sprintf("%s:%d", cfg.AUTHHOST, cfg.HTTPPORT)
+		if err := http.ListenAndServe(addr, api.NewRouter(tokens, userService, oauthService, proxies, limiter, rules)); err != nil {
+			logs.Error("Error while serving http")
+		}
+	}()
+
 	if err := s.Serve(listener); err != nil {
 		logs.Error("Error while initializing server")
 	}